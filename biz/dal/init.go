@@ -7,6 +7,7 @@ import (
 
 // Init init dal
 func init() {
-	mysql.Init() // mysql init
+	mysql.Init()    // mysql init
+	mysql.Migrate() // 本服务自有数据表
 	query.SetDefault(mysql.DB)
 }
@@ -0,0 +1,40 @@
+package mysql
+
+import (
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/biz/model/gorm_gen"
+)
+
+// Migrate 创建或更新本服务自有的数据表（pre_files 等表由 DooTask 维护，不在此处迁移）
+func Migrate() {
+	err := DB.AutoMigrate(
+		&entity.FileBlob{},
+		&entity.FileQuota{},
+		&entity.FileMigration{},
+		&entity.FileReplica{},
+		&entity.FileTier{},
+		&entity.FileJob{},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// CreateFulltextIndex 为 pre_file_contents.text 创建 ngram 全文索引，用于中日韩文本搜索，已存在时返回 false；
+// 该表由 DooTask 维护且可能很大，不在启动时迁移，由 storagectl fulltext 显式执行；没有索引时搜索使用 LIKE 匹配
+func CreateFulltextIndex() (bool, error) {
+	var count int64
+	err := DB.Raw("SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?",
+		gorm_gen.TableNameFileContent, "ft_text").Scan(&count).Error
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	err = DB.Exec("ALTER TABLE " + gorm_gen.TableNameFileContent + " ADD FULLTEXT INDEX ft_text (text) WITH PARSER ngram").Error
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -16,44 +16,74 @@ import (
 )
 
 var (
-	Q           = new(Query)
-	File        *file
-	FileContent *fileContent
-	File_User   *file_User
+	Q             = new(Query)
+	File          *file
+	FileBlob      *fileBlob
+	FileContent   *fileContent
+	FileJob       *fileJob
+	FileMigration *fileMigration
+	FileQuota     *fileQuota
+	FileReplica   *fileReplica
+	FileTier      *fileTier
+	File_User     *file_User
 )
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	*Q = *Use(db, opts...)
 	File = &Q.File
+	FileBlob = &Q.FileBlob
 	FileContent = &Q.FileContent
+	FileJob = &Q.FileJob
+	FileMigration = &Q.FileMigration
+	FileQuota = &Q.FileQuota
+	FileReplica = &Q.FileReplica
+	FileTier = &Q.FileTier
 	File_User = &Q.File_User
 }
 
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
-		db:          db,
-		File:        newFile(db, opts...),
-		FileContent: newFileContent(db, opts...),
-		File_User:   newFile_User(db, opts...),
+		db:            db,
+		File:          newFile(db, opts...),
+		FileBlob:      newFileBlob(db, opts...),
+		FileContent:   newFileContent(db, opts...),
+		FileJob:       newFileJob(db, opts...),
+		FileMigration: newFileMigration(db, opts...),
+		FileQuota:     newFileQuota(db, opts...),
+		FileReplica:   newFileReplica(db, opts...),
+		FileTier:      newFileTier(db, opts...),
+		File_User:     newFile_User(db, opts...),
 	}
 }
 
 type Query struct {
 	db *gorm.DB
 
-	File        file
-	FileContent fileContent
-	File_User   file_User
+	File          file
+	FileBlob      fileBlob
+	FileContent   fileContent
+	FileJob       fileJob
+	FileMigration fileMigration
+	FileQuota     fileQuota
+	FileReplica   fileReplica
+	FileTier      fileTier
+	File_User     file_User
 }
 
 func (q *Query) Available() bool { return q.db != nil }
 
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
-		db:          db,
-		File:        q.File.clone(db),
-		FileContent: q.FileContent.clone(db),
-		File_User:   q.File_User.clone(db),
+		db:            db,
+		File:          q.File.clone(db),
+		FileBlob:      q.FileBlob.clone(db),
+		FileContent:   q.FileContent.clone(db),
+		FileJob:       q.FileJob.clone(db),
+		FileMigration: q.FileMigration.clone(db),
+		FileQuota:     q.FileQuota.clone(db),
+		FileReplica:   q.FileReplica.clone(db),
+		FileTier:      q.FileTier.clone(db),
+		File_User:     q.File_User.clone(db),
 	}
 }
 
@@ -67,24 +97,42 @@ func (q *Query) WriteDB() *Query {
 
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
-		db:          db,
-		File:        q.File.replaceDB(db),
-		FileContent: q.FileContent.replaceDB(db),
-		File_User:   q.File_User.replaceDB(db),
+		db:            db,
+		File:          q.File.replaceDB(db),
+		FileBlob:      q.FileBlob.replaceDB(db),
+		FileContent:   q.FileContent.replaceDB(db),
+		FileJob:       q.FileJob.replaceDB(db),
+		FileMigration: q.FileMigration.replaceDB(db),
+		FileQuota:     q.FileQuota.replaceDB(db),
+		FileReplica:   q.FileReplica.replaceDB(db),
+		FileTier:      q.FileTier.replaceDB(db),
+		File_User:     q.File_User.replaceDB(db),
 	}
 }
 
 type queryCtx struct {
-	File        IFileDo
-	FileContent IFileContentDo
-	File_User   IFile_UserDo
+	File          IFileDo
+	FileBlob      IFileBlobDo
+	FileContent   IFileContentDo
+	FileJob       IFileJobDo
+	FileMigration IFileMigrationDo
+	FileQuota     IFileQuotaDo
+	FileReplica   IFileReplicaDo
+	FileTier      IFileTierDo
+	File_User     IFile_UserDo
 }
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
-		File:        q.File.WithContext(ctx),
-		FileContent: q.FileContent.WithContext(ctx),
-		File_User:   q.File_User.WithContext(ctx),
+		File:          q.File.WithContext(ctx),
+		FileBlob:      q.FileBlob.WithContext(ctx),
+		FileContent:   q.FileContent.WithContext(ctx),
+		FileJob:       q.FileJob.WithContext(ctx),
+		FileMigration: q.FileMigration.WithContext(ctx),
+		FileQuota:     q.FileQuota.WithContext(ctx),
+		FileReplica:   q.FileReplica.WithContext(ctx),
+		FileTier:      q.FileTier.WithContext(ctx),
+		File_User:     q.File_User.WithContext(ctx),
 	}
 }
 
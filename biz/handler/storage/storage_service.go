@@ -0,0 +1,971 @@
+// Code generated by hertz generator.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/common"
+	"github.com/cloudisk/biz/model/entity"
+	storage "github.com/cloudisk/biz/model/storage"
+	"github.com/cloudisk/biz/service"
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// Upload .
+// @router /api/file/content/upload [POST]
+func Upload(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.UploadReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+	form, err := c.MultipartForm()
+	if err != nil {
+		return
+	}
+	file := form.File["files"][0]
+	pid, _ := strconv.Atoi(req.GetPid())
+	cover, _ := strconv.ParseBool(req.GetCover())
+
+	webkitRelativePath := ""
+	if paths, exists := form.Value["webkitRelativePath"]; exists && len(paths) > 0 {
+		webkitRelativePath = paths[0]
+	}
+	log.Printf("webkitRelativePath: %s", webkitRelativePath)
+
+	// 客户端可提供 SHA-256 或 MD5，服务端据此校验上传内容
+	hash := req.GetHash()
+	if hashes, exists := form.Value["hash"]; hash == "" && exists && len(hashes) > 0 {
+		hash = hashes[0]
+	}
+
+	log.Printf("开始上传文件: %s", file.Filename)
+
+	item, err := service.Upload(user, pid, webkitRelativePath, cover, *file, hash)
+	if err != nil {
+		log.Printf("文件上传失败: %s, 错误: %v", file.Filename, err)
+		resp := new(storage.UploadResp)
+		resp.Ret = 0
+		resp.Msg = "文件上传失败: " + err.Error()
+		c.JSON(uploadErrorStatus(err), resp)
+		return
+	}
+
+	log.Printf("文件上传成功: %s", file.Filename)
+
+	resp := new(storage.UploadResp)
+	resp.Data = append(resp.Data, item)
+	resp.Ret = 1
+	resp.Msg = file.Filename + " 上传成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// OfficeUpload .
+// @router /api/file/content/office [GET、POST]
+func OfficeUpload(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.OfficeUploadReq
+
+	fileId := c.Query("id")
+	if id, err := strconv.Atoi(fileId); err == nil {
+		req.Id = int32(id)
+	}
+	if len(c.Request.Body()) > 0 {
+		if err = c.BindAndValidate(&req); err != nil {
+			c.String(consts.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+
+	switch req.GetStatus() {
+	case 2: // 文档已保存；保存成功后才应答，OnlyOffice 收到成功应答后会丢弃其副本，下载地址也会过期
+		err = service.OfficeUpload(user, int(req.GetId()), int(req.GetStatus()),
+			req.GetKey(), req.GetUrl())
+		if err != nil {
+			c.String(requestErrorStatus(err), err.Error())
+			return
+		}
+	}
+
+	resp := new(storage.OfficeUploadResp)
+	resp.Error = 0
+	c.JSON(consts.StatusOK, resp)
+}
+
+// Save .
+// @router /api/file/content/save [POST]
+func Save(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.SaveReq
+
+	// 获取并打印原始请求体
+	rawBody := c.Request.Body()
+
+	// 手动解析请求体
+	var rawReq struct {
+		ID      int    `json:"id"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(rawBody, &rawReq); err != nil {
+		log.Printf("Error parsing raw request: %v", err)
+		c.JSON(consts.StatusBadRequest, &storage.SaveResp{
+			Ret: 0,
+			Msg: "无效的请求格式",
+		})
+		return
+	}
+
+	// 手动设置请求参数
+	req.Id = int32(rawReq.ID)
+	req.Content = rawReq.Content
+
+	log.Printf("Parsed request: id=%d, content=%s", req.Id, req.Content)
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+
+	// 保存文件内容
+	fileContent, err := service.SaveContent(user, int64(req.Id), req.Content)
+	if err != nil {
+		log.Printf("Save content error: %v", err)
+		c.JSON(uploadErrorStatus(err), &storage.SaveResp{
+			Ret: 0,
+			Msg: err.Error(),
+		})
+		return
+	}
+	resp := new(storage.SaveResp)
+	resp.Ret = 1
+	resp.Msg = "保存成功"
+	resp.Data = []*common.FileContent{fileContent}
+
+	c.JSON(consts.StatusOK, resp)
+
+}
+
+// Download .
+// @router /api/file/content/download [GET]
+func Download(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.DownloadReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+
+	// 保存为离线文件由后台任务执行，通过 /api/file/content/job 查询进度
+	job, err := service.EnqueueDownload(user, int64(req.FileId))
+	if err != nil {
+		resp := new(storage.DownloadResp)
+		resp.Ret = 0
+		resp.Msg = err.Error()
+		c.JSON(consts.StatusBadRequest, resp)
+		return
+	}
+
+	resp := new(storage.DownloadResp)
+	resp.Ret = 1
+	resp.Msg = "已提交保存任务"
+	resp.JobID = job.ID
+
+	c.JSON(consts.StatusAccepted, resp)
+}
+
+// Remove .
+// @router /api/file/content/remove [DELETE]
+func Remove(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.RemoveReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+	fileID := req.FileId
+	log.Printf("开始删除本地文件, ID: %d", fileID)
+
+	err = service.DeleteLocalFileWithUser(user, fileID)
+	if err != nil {
+		log.Printf("删除文件失败, ID: %d, 错误: %v", fileID, err)
+		resp := new(storage.RemoveResp)
+		resp.Ret = 0
+		resp.Msg = "删除失败: " + err.Error()
+		c.JSON(consts.StatusInternalServerError, resp)
+		return
+	}
+
+	log.Printf("本地文件删除成功, ID: %d", fileID)
+
+	resp := new(storage.RemoveResp)
+	resp.Ret = 1
+	resp.Msg = "删除成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// Downloading .
+// @router /api/file/content/downloading [GET]
+func Downloading(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.DownloadReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	fileID := req.FileId
+	file, err := query.Q.File.Where(query.File.ID.Eq(int64(fileID))).First()
+	if err != nil {
+		c.String(consts.StatusBadRequest, "文件不存在")
+		return
+	}
+
+	fileName := file.Name
+	if file.Ext != "" {
+		fileName = fileName + "." + file.Ext
+	}
+	meta := service.GetContentMeta(file)
+	fullPath := meta.Key
+
+	log.Printf("开始下载文件: %s, ID: %d", fullPath, fileID)
+
+	offset, length, err := service.ParseByteRange(string(c.GetHeader("Range")), meta.Size)
+	if err != nil {
+		c.Header("Content-Range", fmt.Sprintf("bytes */%d", meta.Size))
+		c.String(consts.StatusRequestedRangeNotSatisfiable, err.Error())
+		return
+	}
+	// 客户端接受内容的压缩方式时不解压，以 Content-Encoding 返回；范围请求按原内容计算、本地已缓存时读取缓存的原内容
+	encoded := length < 0 && !service.IsCached(meta) && service.AcceptsEncoding(string(c.GetHeader("Accept-Encoding")), meta.Encoding)
+	var fileData []byte
+	if encoded {
+		fileData, err = service.ReadEncodedContent(meta)
+	} else {
+		fileData, err = service.ReadContentRange(meta, offset, length)
+	}
+	if errors.Is(err, service.ErrObjectRestoring) {
+		log.Printf("文件已归档，等待恢复: %s", fullPath)
+		c.String(consts.StatusAccepted, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("下载文件失败: %s, 错误: %v", fullPath, err)
+		c.String(consts.StatusInternalServerError, "下载文件失败: "+err.Error())
+		return
+	}
+
+	log.Printf("文件下载成功: %s, ID: %d", fullPath, fileID)
+
+	contentType := meta.ContentType(fileName)
+	setContentHeaders(c, fileName, contentType, req.GetInline())
+	if meta.Encoding != "" {
+		c.Header("Vary", "Accept-Encoding")
+	}
+	if encoded {
+		// 校验值按原内容计算，与压缩后的响应体不符，不返回
+		c.Header("Content-Encoding", meta.Encoding)
+		c.Data(consts.StatusOK, contentType, fileData)
+		return
+	}
+	setDigestHeaders(c, meta)
+	c.Header("Accept-Ranges", "bytes")
+	if length >= 0 {
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+int64(len(fileData))-1, meta.Size))
+		c.Data(consts.StatusPartialContent, contentType, fileData)
+		return
+	}
+	c.Data(consts.StatusOK, contentType, fileData)
+}
+
+// IoUpload .
+// @router /api/file/content/io_upload [POST]
+func IoUpload(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.IoUploadReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+	fileID := req.GetFileId()
+	file, err := query.Q.File.Where(query.File.ID.Eq(int64(fileID))).First()
+	if err != nil {
+		c.String(consts.StatusBadRequest, "文件不存在")
+		return
+	}
+
+	cover, _ := strconv.ParseBool(req.GetCover())
+	webkitRelativePath := req.GetWebkitRelativePath()
+
+	resp := new(storage.IoUploadResp)
+	fileName := file.Name + "." + file.Ext
+
+	// 上传由后台任务执行，通过 /api/file/content/job 查询进度
+	job, err := service.EnqueueIoUpload(user, int(fileID), webkitRelativePath, cover, fileName, req.GetHash())
+	if err != nil {
+		log.Printf("提交上传任务失败: %s, 错误: %v", fileName, err)
+		resp.Ret = 0
+		resp.Msg = "文件上传失败: " + err.Error()
+		c.JSON(requestErrorStatus(err), resp)
+		return
+	}
+
+	resp.Ret = 1
+	resp.Msg = fileName + " 已提交上传任务"
+	resp.JobID = job.ID
+
+	c.JSON(consts.StatusAccepted, resp)
+}
+
+// DownloadingOffice .
+// @router /api/file/content/downloading_office [GET]
+func DownloadingOffice(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.DownloadOfficeReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	ossFileName := req.Key
+	log.Printf("开始下载文件: %s", ossFileName)
+
+	meta := service.GetBlobMeta(ossFileName)
+	fileData, err := service.ReadContent(meta)
+	if errors.Is(err, service.ErrObjectRestoring) {
+		log.Printf("文件已归档，等待恢复: %s", ossFileName)
+		c.String(consts.StatusAccepted, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("下载文件失败: %s, 错误: %v", ossFileName, err)
+		c.String(consts.StatusInternalServerError, "下载文件失败: "+err.Error())
+		return
+	}
+
+	log.Printf("文件下载成功: %s", ossFileName)
+
+	contentType := meta.ContentType(ossFileName)
+	setDigestHeaders(c, meta)
+	setContentHeaders(c, ossFileName, contentType, false)
+	c.Data(consts.StatusOK, contentType, fileData)
+}
+
+// Status .
+// @router /api/file/content/status [GET]
+func Status(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.StatusReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		log.Printf("Bind and validate error: %v", err)
+		resp := new(storage.StatusResp)
+		resp.Ret = 1
+		resp.Msg = "success"
+		resp.Data = make([]*storage.FileStatus, 0)
+		c.JSON(consts.StatusOK, resp)
+		return
+	}
+
+	resp := new(storage.StatusResp)
+	resp.Ret = 1
+	resp.Msg = "success"
+	resp.Data = make([]*storage.FileStatus, 0)
+
+	// 获取文件内容
+	log.Printf("开始查询文件状态: %v", req.FileIds)
+	fileContents, err := query.Q.FileContent.
+		Where(query.FileContent.Fid.In(service.SliceInt32ToInt64(req.FileIds)...)).
+		Order(query.FileContent.UpdatedAt.Desc()).
+		Find()
+	if err != nil {
+		log.Printf("Query error: %v", err)
+		c.JSON(consts.StatusOK, resp)
+		return
+	}
+
+	// 处理每个文件的内容
+	processedIDs := make(map[int32]bool)
+	for _, fileContent := range fileContents {
+		fileID := int32(fileContent.Fid)
+		// 如果已经处理过这个ID，跳过
+		if processedIDs[fileID] {
+			continue
+		}
+		processedIDs[fileID] = true
+
+		fileStatus := &storage.FileStatus{
+			ID:     fileID,
+			Status: "none", // 默认状态
+		}
+
+		var contentMap map[string]interface{}
+		if err := json.Unmarshal([]byte(fileContent.Content), &contentMap); err != nil {
+			log.Printf("JSON unmarshal error for FileId %d: %v", fileStatus.ID, err)
+			resp.Data = append(resp.Data, fileStatus)
+			continue
+		}
+
+		// 检查cloud_url字段
+		if cloudURL, hasCloudURL := contentMap["cloud_url"].(string); hasCloudURL && cloudURL != "" {
+			fileStatus.Status = "cloud"
+
+			// 检查url字段
+			if url, hasURL := contentMap["url"].(string); hasURL {
+				url = strings.ReplaceAll(url, "\\/", "/")
+				if url != "" {
+					fileStatus.Status = "cloud_confirmed"
+				}
+			}
+		}
+		if service.IsRestoring(fileContent) {
+			fileStatus.Status = "restoring"
+		}
+
+		resp.Data = append(resp.Data, fileStatus)
+	}
+
+	log.Printf("查询文件状态完成: %+v", resp)
+	c.JSON(consts.StatusOK, resp)
+}
+
+// InstantUpload .
+// @router /api/file/content/instant_upload [POST]
+func InstantUpload(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.InstantUploadReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+	pid, _ := strconv.Atoi(req.GetPid())
+	cover, _ := strconv.ParseBool(req.GetCover())
+
+	log.Printf("开始秒传文件: %s, hash: %s", req.GetName(), req.GetHash())
+
+	resp := new(storage.InstantUploadResp)
+	item, err := service.InstantUpload(user, pid, req.GetWebkitRelativePath(), cover, req.GetName(), req.GetHash(), req.GetSize())
+	if err != nil {
+		resp.Ret = 0
+		resp.Msg = err.Error()
+		if errors.Is(err, service.ErrBlobNotFound) {
+			// 未命中不是错误，客户端改为普通上传
+			c.JSON(consts.StatusOK, resp)
+			return
+		}
+		log.Printf("文件秒传失败: %s, 错误: %v", req.GetName(), err)
+		resp.Msg = "文件上传失败: " + err.Error()
+		c.JSON(uploadErrorStatus(err), resp)
+		return
+	}
+
+	log.Printf("文件秒传成功: %s", req.GetName())
+
+	resp.Data = append(resp.Data, item)
+	resp.Ret = 1
+	resp.Msg = req.GetName() + " 上传成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// Delete .
+// @router /api/file/content/delete [DELETE]
+func Delete(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.DeleteReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+	fileID := req.FileId
+	log.Printf("开始删除文件, ID: %d", fileID)
+
+	err = service.DeleteFiles(user, int(fileID))
+	if err != nil {
+		log.Printf("删除文件失败, ID: %d, 错误: %v", fileID, err)
+		resp := new(storage.DeleteResp)
+		resp.Ret = 0
+		resp.Msg = "删除失败: " + err.Error()
+		c.JSON(consts.StatusInternalServerError, resp)
+		return
+	}
+
+	log.Printf("文件删除成功, ID: %d", fileID)
+
+	resp := new(storage.DeleteResp)
+	resp.Ret = 1
+	resp.Msg = "删除成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// setContentHeaders 输出下载的内容类型和 Content-Disposition；inline 仅对浏览器可安全预览的类型生效
+func setContentHeaders(c *app.RequestContext, fileName string, contentType string, inline bool) {
+	disposition := "attachment"
+	if inline && service.IsInlineType(contentType) {
+		disposition = "inline"
+	}
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": fileName}); value != "" {
+		disposition = value
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+}
+
+// setDigestHeaders 输出内容校验相关的响应头，客户端可据此校验下载结果
+func setDigestHeaders(c *app.RequestContext, meta service.ContentMeta) {
+	if etag := meta.ETag(); etag != "" {
+		c.Header("ETag", etag)
+	}
+	if digest := meta.Digest(); digest != "" {
+		c.Header("Digest", digest)
+	}
+}
+
+// Quota .
+// @router /api/file/content/quota [GET]
+func Quota(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.QuotaReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+
+	resp := new(storage.QuotaResp)
+	usages, err := service.GetQuotaUsage(user, int(req.GetFileId()))
+	if err != nil {
+		resp.Ret = 0
+		resp.Msg = "获取存储配额失败: " + err.Error()
+		c.JSON(consts.StatusInternalServerError, resp)
+		return
+	}
+
+	for _, usage := range usages {
+		resp.Data = append(resp.Data, newQuotaInfo(usage))
+	}
+	resp.Ret = 1
+	resp.Msg = "获取成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// SetQuota .
+// @router /api/file/content/quota [POST]
+func SetQuota(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.SetQuotaReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+
+	resp := new(storage.SetQuotaResp)
+	usage, err := service.SetQuota(user, req.GetUserid(), req.GetPshare(), req.GetLimit())
+	if err != nil {
+		resp.Ret = 0
+		resp.Msg = "设置存储配额失败: " + err.Error()
+		c.JSON(consts.StatusBadRequest, resp)
+		return
+	}
+
+	log.Printf("设置存储配额, userid: %d, pshare: %d, limit: %d", req.GetUserid(), req.GetPshare(), req.GetLimit())
+
+	resp.Data = newQuotaInfo(usage)
+	resp.Ret = 1
+	resp.Msg = "设置成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// Restore .
+// @router /api/file/content/restore [POST]
+func Restore(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.RestoreReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+	fileID := req.GetFileId()
+	log.Printf("开始恢复文件, ID: %d", fileID)
+
+	resp := new(storage.RestoreResp)
+	err = service.RestoreFiles(user, int(fileID))
+	if err != nil {
+		log.Printf("恢复文件失败, ID: %d, 错误: %v", fileID, err)
+		resp.Ret = 0
+		resp.Msg = "恢复失败: " + err.Error()
+		c.JSON(uploadErrorStatus(err), resp)
+		return
+	}
+
+	log.Printf("文件恢复成功, ID: %d", fileID)
+
+	resp.Ret = 1
+	resp.Msg = "恢复成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// uploadErrorStatus 写入失败时的状态码，配额不足时返回 507，违反上传策略时返回 400
+func uploadErrorStatus(err error) int {
+	if service.IsQuotaError(err) {
+		return consts.StatusInsufficientStorage
+	}
+	if service.IsPolicyError(err) {
+		return consts.StatusBadRequest
+	}
+	return consts.StatusInternalServerError
+}
+
+// requestErrorStatus 配额和上传策略错误按 uploadErrorStatus 返回，其他错误为请求错误
+func requestErrorStatus(err error) int {
+	if service.IsQuotaError(err) || service.IsPolicyError(err) {
+		return uploadErrorStatus(err)
+	}
+	return consts.StatusBadRequest
+}
+
+func newQuotaInfo(usage *service.QuotaUsage) *storage.QuotaInfo {
+	return &storage.QuotaInfo{
+		Userid:    usage.Userid,
+		Pshare:    usage.Pshare,
+		Used:      usage.Used,
+		Limit:     usage.Limit,
+		Available: usage.Available,
+	}
+}
+
+// Thumbnail .
+// @router /api/file/content/thumbnail [GET]
+func Thumbnail(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.ThumbnailReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+	fileID := req.GetFileId()
+	thumbnail, err := service.GetThumbnail(user, int64(fileID), int(req.GetSize()))
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.Header("ETag", thumbnail.ETag)
+	c.Header("Cache-Control", "private, max-age=86400")
+	if string(c.GetHeader("If-None-Match")) == thumbnail.ETag {
+		c.Status(consts.StatusNotModified)
+		return
+	}
+
+	data, err := thumbnail.Load()
+	if err != nil {
+		log.Printf("获取缩略图失败, ID: %d, 错误: %v", fileID, err)
+		if errors.Is(err, service.ErrThumbnailUnsupported) {
+			c.String(consts.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(consts.StatusInternalServerError, "获取缩略图失败: "+err.Error())
+		return
+	}
+
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(consts.StatusOK, thumbnail.ContentType, data)
+}
+
+// Search .
+// @router /api/file/search [GET]
+func Search(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.SearchReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+
+	resp := new(storage.SearchResp)
+	result, err := service.Search(user, service.SearchOptions{
+		Keyword:      req.GetKeyword(),
+		Type:         req.GetType(),
+		Ext:          req.GetExt(),
+		Userid:       req.GetUserid(),
+		Pid:          req.GetPid(),
+		CreatedStart: req.GetCreatedStart(),
+		CreatedEnd:   req.GetCreatedEnd(),
+		UpdatedStart: req.GetUpdatedStart(),
+		UpdatedEnd:   req.GetUpdatedEnd(),
+		Page:         int(req.GetPage()),
+		PageSize:     int(req.GetPageSize()),
+	})
+	if err != nil {
+		resp.Ret = 0
+		resp.Msg = err.Error()
+		c.JSON(consts.StatusBadRequest, resp)
+		return
+	}
+
+	resp.Data = &storage.SearchData{
+		Total:    result.Total,
+		Page:     int32(result.Page),
+		Pagesize: int32(result.PageSize),
+		List:     []*storage.SearchItem{},
+	}
+	for _, item := range result.List {
+		resp.Data.List = append(resp.Data.List, &storage.SearchItem{
+			File:    item.File,
+			Path:    item.Path,
+			Snippet: item.Snippet,
+		})
+	}
+	resp.Ret = 1
+	resp.Msg = "搜索成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// Export .
+// @router /api/file/content/export [GET、POST]
+func Export(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.ExportReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+
+	fileID := req.GetFileId()
+	if req.GetSave() {
+		// 保存为同一文件夹下的新文件
+		item, err := service.SaveExport(user, int64(fileID), req.GetFormat())
+		resp := new(storage.ExportResp)
+		if err != nil {
+			log.Printf("导出文件失败, ID: %d, 错误: %v", fileID, err)
+			resp.Ret = 0
+			resp.Msg = "导出文件失败: " + err.Error()
+			c.JSON(exportErrorStatus(err), resp)
+			return
+		}
+		resp.Data = append(resp.Data, item)
+		resp.Ret = 1
+		resp.Msg = item.FullName + " 导出成功"
+		c.JSON(consts.StatusOK, resp)
+		return
+	}
+
+	export, err := service.ExportFile(user, int64(fileID), req.GetFormat())
+	if err != nil {
+		log.Printf("导出文件失败, ID: %d, 错误: %v", fileID, err)
+		c.String(exportErrorStatus(err), "导出文件失败: "+err.Error())
+		return
+	}
+	// PNG 可内联显示，用于在任务评论中嵌入预览；HTML、SVG 始终作为附件下载
+	setContentHeaders(c, export.FileName, export.ContentType, req.GetInline())
+	c.Data(consts.StatusOK, export.ContentType, export.Data)
+}
+
+// exportErrorStatus 不支持的类型或格式返回 400，其余按上传错误处理
+func exportErrorStatus(err error) int {
+	if errors.Is(err, service.ErrExportUnsupported) {
+		return consts.StatusBadRequest
+	}
+	return uploadErrorStatus(err)
+}
+
+// ImportBucket .
+// @router /api/file/content/import [POST]
+func ImportBucket(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.ImportReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+
+	resp := new(storage.ImportResp)
+	result, err := service.ImportBucket(user, req.GetProfile(), req.GetPrefix(), req.GetUserid(), req.GetPid())
+	if err != nil {
+		resp.Ret = 0
+		resp.Msg = "导入失败: " + err.Error()
+		c.JSON(consts.StatusBadRequest, resp)
+		return
+	}
+
+	log.Printf("导入存储桶前缀: %s, userid: %d, pid: %d, 新建文件夹 %d 个, 文件 %d 个, 跳过 %d 个",
+		req.GetPrefix(), req.GetUserid(), req.GetPid(), result.Folders, result.Files, result.Skipped)
+
+	resp.Data = &storage.ImportInfo{
+		Folders: int64(result.Folders),
+		Files:   int64(result.Files),
+		Skipped: int64(result.Skipped),
+		Size:    result.Size,
+	}
+	resp.Ret = 1
+	resp.Msg = "导入成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// ListObjects .
+// @router /api/file/content/objects [GET]
+func ListObjects(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.ListObjectsReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+
+	resp := new(storage.ListObjectsResp)
+	objects, next, err := service.ListObjects(user, req.GetProfile(), req.GetPrefix(), req.GetMarker(), int(req.GetLimit()))
+	if err != nil {
+		resp.Ret = 0
+		resp.Msg = "列出对象失败: " + err.Error()
+		c.JSON(consts.StatusBadRequest, resp)
+		return
+	}
+
+	resp.Data = &storage.ListObjectsData{List: []*storage.ObjectItem{}, NextMarker: next}
+	for _, obj := range objects {
+		resp.Data.List = append(resp.Data.List, &storage.ObjectItem{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			Etag:         obj.ETag,
+			LastModified: obj.LastModified.Format("2006-01-02 15:04:05"),
+			StorageClass: obj.StorageClass,
+		})
+	}
+	resp.Ret = 1
+	resp.Msg = "获取成功"
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// Job .
+// @router /api/file/content/job [GET]
+func Job(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.JobReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+	resp := new(storage.JobResp)
+	job, err := service.GetJob(user, req.GetId())
+	if err != nil {
+		resp.Ret = 0
+		resp.Msg = err.Error()
+		c.JSON(consts.StatusNotFound, resp)
+		return
+	}
+	resp.Ret = 1
+	resp.Msg = "获取成功"
+	resp.Data = newJobInfo(job)
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+// CancelJob .
+// @router /api/file/content/job/cancel [POST]
+func CancelJob(ctx context.Context, c *app.RequestContext) {
+	var err error
+	var req storage.JobReq
+	err = c.BindAndValidate(&req)
+	if err != nil {
+		c.String(consts.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, _ := service.GetUserInfo(c.GetHeader("Token"))
+	resp := new(storage.JobResp)
+	job, err := service.CancelJob(user, req.GetId())
+	if err != nil {
+		resp.Ret = 0
+		resp.Msg = err.Error()
+		status := consts.StatusBadRequest
+		if errors.Is(err, service.ErrJobNotFound) {
+			status = consts.StatusNotFound
+		} else if errors.Is(err, service.ErrJobFinished) {
+			status = consts.StatusConflict
+		}
+		c.JSON(status, resp)
+		return
+	}
+	resp.Ret = 1
+	resp.Msg = "已取消"
+	resp.Data = newJobInfo(job)
+
+	c.JSON(consts.StatusOK, resp)
+}
+
+func newJobInfo(job *entity.FileJob) *storage.JobInfo {
+	return &storage.JobInfo{
+		ID:          job.ID,
+		Type:        job.Type,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		Error:       job.Error,
+		Result:      job.Result,
+		NextAt:      job.NextAt.Format("2006-01-02 15:04:05"),
+		CreatedAt:   job.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:   job.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"time"
+)
+
+const TableNameFileBlob = "pre_file_blobs"
+
+// FileBlob 按内容哈希去重的云存储对象，同一存储配置中相同内容按加密方式各保存一份，按引用计数回收
+type FileBlob struct {
+	ID          int64      `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
+	Profile     string     `gorm:"column:profile;size:32;not null;default:'';uniqueIndex:idx_blob_profile_hash_cipher,priority:1;comment:存储配置ID，为空时为默认存储" json:"profile"` // 存储配置ID，为空时为默认存储
+	Hash        string     `gorm:"column:hash;size:64;not null;uniqueIndex:idx_blob_profile_hash_cipher,priority:2;comment:内容SHA-256" json:"hash"`                        // 内容SHA-256
+	Size        int64      `gorm:"column:size;comment:大小(B)" json:"size"`                                                                                                 // 大小(B)
+	MD5         string     `gorm:"column:md5;size:32;comment:内容MD5" json:"md5"`                                                                                           // 内容MD5
+	CRC64       string     `gorm:"column:crc64;size:20;comment:内容CRC64-ECMA" json:"crc64"`                                                                                // 内容CRC64-ECMA
+	Mime        string     `gorm:"column:mime;size:127;comment:内容MIME类型" json:"mime"`                                                                                     // 内容MIME类型
+	ObjectKey   string     `gorm:"column:object_key;size:255;index:idx_blob_object_key;comment:云存储对象路径" json:"object_key"`                                                // 云存储对象路径
+	Encoding    string     `gorm:"column:encoding;size:16;comment:压缩方式，为空时未压缩" json:"encoding"`                                                                           // 压缩方式，为空时未压缩
+	EncodedSize int64      `gorm:"column:encoded_size;comment:压缩后的大小(B)" json:"encoded_size"`                                                                             // 压缩后的大小(B)
+	Cipher      string     `gorm:"column:cipher;size:16;not null;default:'';uniqueIndex:idx_blob_profile_hash_cipher,priority:3;comment:客户端加密方式，为空时未加密" json:"cipher"`    // 客户端加密方式，为空时未加密
+	KeyID       string     `gorm:"column:key_id;size:64;comment:加密数据密钥的主密钥ID" json:"key_id"`                                                                              // 加密数据密钥的主密钥ID
+	DataKey     string     `gorm:"column:data_key;size:255;comment:主密钥加密后的数据密钥(base64)" json:"data_key"`                                                                  // 主密钥加密后的数据密钥(base64)
+	Nonce       string     `gorm:"column:nonce;size:32;comment:分块加密的nonce前缀(base64)" json:"nonce"`                                                                        // 分块加密的nonce前缀(base64)
+	RefCount    int64      `gorm:"column:ref_count;comment:引用计数" json:"ref_count"`                                                                                        // 引用计数
+	ReleasedAt  *time.Time `gorm:"column:released_at;index:idx_blob_released_at;comment:引用计数降为0的时间，保留期从此时开始计算" json:"released_at"`                                        // 引用计数降为0的时间，保留期从此时开始计算
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (*FileBlob) TableName() string {
+	return TableNameFileBlob
+}
@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"time"
+)
+
+const TableNameFileJob = "pre_file_jobs"
+
+// FileJob 后台任务，如离线保存、上传、全文提取和迁移
+type FileJob struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
+	Type        string    `gorm:"column:type;size:32;not null;index;comment:任务类型" json:"type"`                                                  // 任务类型
+	Userid      int64     `gorm:"column:userid;index;comment:提交任务的会员ID，0为系统任务" json:"userid"`                                                   // 提交任务的会员ID，0为系统任务
+	Payload     string    `gorm:"column:payload;type:text;comment:任务参数(JSON)" json:"payload"`                                                   // 任务参数(JSON)
+	Status      string    `gorm:"column:status;size:16;index;comment:状态：pending 待执行，running 执行中，done 已完成，dead 重试耗尽，canceled 已取消" json:"status"` // 状态：pending 待执行，running 执行中，done 已完成，dead 重试耗尽，canceled 已取消
+	Attempts    int32     `gorm:"column:attempts;comment:已执行次数" json:"attempts"`                                                                // 已执行次数
+	MaxAttempts int32     `gorm:"column:max_attempts;comment:最大执行次数" json:"max_attempts"`                                                       // 最大执行次数
+	Error       string    `gorm:"column:error;size:1024;comment:最近一次失败原因" json:"error"`                                                         // 最近一次失败原因
+	Result      string    `gorm:"column:result;type:text;comment:执行结果(JSON)" json:"result"`                                                     // 执行结果(JSON)
+	NextAt      time.Time `gorm:"column:next_at;index;comment:下次执行时间，执行中为租约到期时间" json:"next_at"`                                                // 下次执行时间，执行中为租约到期时间
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (*FileJob) TableName() string {
+	return TableNameFileJob
+}
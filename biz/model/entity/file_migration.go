@@ -0,0 +1,26 @@
+package entity
+
+import (
+	"time"
+)
+
+const TableNameFileMigration = "pre_file_migrations"
+
+// FileMigration 云存储迁移中每个对象的进度，中断后据此从断点继续
+type FileMigration struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
+	Source    string    `gorm:"column:source;size:32;not null;uniqueIndex:idx_migration_object;comment:源存储" json:"source"`              // 源存储
+	Target    string    `gorm:"column:target;size:32;not null;uniqueIndex:idx_migration_object;comment:目标存储" json:"target"`             // 目标存储
+	ObjectKey string    `gorm:"column:object_key;size:255;not null;uniqueIndex:idx_migration_object;comment:云存储对象路径" json:"object_key"` // 云存储对象路径
+	Size      int64     `gorm:"column:size;comment:大小(B)" json:"size"`                                                                  // 大小(B)
+	Hash      string    `gorm:"column:hash;size:64;comment:内容SHA-256" json:"hash"`                                                      // 内容SHA-256
+	Status    string    `gorm:"column:status;size:16;index;comment:状态：done 已完成，failed 失败" json:"status"`                                // 状态：done 已完成，failed 失败
+	Error     string    `gorm:"column:error;size:1024;comment:失败原因" json:"error"`                                                       // 失败原因
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (*FileMigration) TableName() string {
+	return TableNameFileMigration
+}
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"time"
+)
+
+const TableNameFileQuota = "pre_file_quotas"
+
+// FileQuota 会员或共享文件夹的存储配额及已用空间
+type FileQuota struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
+	Userid    int64     `gorm:"column:userid;uniqueIndex:idx_quota_scope;comment:会员ID，共享文件夹配额为0" json:"userid"` // 会员ID，共享文件夹配额为0
+	Pshare    int64     `gorm:"column:pshare;uniqueIndex:idx_quota_scope;comment:共享根目录ID，会员配额为0" json:"pshare"` // 共享根目录ID，会员配额为0
+	LimitSize int64     `gorm:"column:limit_size;comment:配额上限(B)，0为不限制" json:"limit_size"`                      // 配额上限(B)，0为不限制
+	UsedSize  int64     `gorm:"column:used_size;comment:已用空间(B)" json:"used_size"`                              // 已用空间(B)
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (*FileQuota) TableName() string {
+	return TableNameFileQuota
+}
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+)
+
+const TableNameFileReplica = "pre_file_replicas"
+
+// FileReplica 对象到副本云存储的异步复制任务
+type FileReplica struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
+	ObjectKey string    `gorm:"column:object_key;size:255;not null;uniqueIndex:idx_replica_object;comment:云存储对象路径" json:"object_key"` // 云存储对象路径
+	Provider  string    `gorm:"column:provider;size:32;not null;uniqueIndex:idx_replica_object;comment:副本所在的云存储" json:"provider"`     // 副本所在的云存储
+	Status    string    `gorm:"column:status;size:16;index;comment:状态：pending 待复制，done 已完成，failed 失败" json:"status"`                  // 状态：pending 待复制，done 已完成，failed 失败
+	Attempts  int32     `gorm:"column:attempts;comment:已尝试次数" json:"attempts"`                                                        // 已尝试次数
+	Error     string    `gorm:"column:error;size:1024;comment:最近一次失败原因" json:"error"`                                                 // 最近一次失败原因
+	NextAt    time.Time `gorm:"column:next_at;index;comment:下次执行时间" json:"next_at"`                                                   // 下次执行时间
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (*FileReplica) TableName() string {
+	return TableNameFileReplica
+}
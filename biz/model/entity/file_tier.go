@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+)
+
+const TableNameFileTier = "pre_file_tiers"
+
+// FileTier 对象的存储类型、最近下载时间和归档恢复状态
+type FileTier struct {
+	ID              int64      `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
+	Profile         string     `gorm:"column:profile;size:64;not null;uniqueIndex:idx_tier_object;comment:对象所在的存储配置或云存储" json:"profile"`               // 对象所在的存储配置或云存储
+	ObjectKey       string     `gorm:"column:object_key;size:255;not null;uniqueIndex:idx_tier_object;comment:云存储对象路径" json:"object_key"`              // 云存储对象路径
+	StorageClass    string     `gorm:"column:storage_class;size:32;not null;default:standard;comment:存储类型：standard、ia、archive 等" json:"storage_class"` // 存储类型：standard、ia、archive 等
+	AccessedAt      time.Time  `gorm:"column:accessed_at;index;comment:最近一次下载时间" json:"accessed_at"`                                                   // 最近一次下载时间
+	RestoreStatus   string     `gorm:"column:restore_status;size:16;index;comment:归档恢复状态：restoring 恢复中，restored 已恢复" json:"restore_status"`            // 归档恢复状态：restoring 恢复中，restored 已恢复
+	RestoreExpireAt *time.Time `gorm:"column:restore_expire_at;comment:恢复副本的过期时间" json:"restore_expire_at"`                                            // 恢复副本的过期时间
+	CreatedAt       time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+// TableName 表名
+func (*FileTier) TableName() string {
+	return TableNameFileTier
+}
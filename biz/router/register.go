@@ -3,18 +3,13 @@
 package router
 
 import (
-	aliyun "github.com/cloudisk/biz/router/aliyun"
-	qiniu "github.com/cloudisk/biz/router/qiniu"
-	tencent "github.com/cloudisk/biz/router/tencent"
+	storage "github.com/cloudisk/biz/router/storage"
 	"github.com/cloudwego/hertz/pkg/app/server"
 )
 
 // GeneratedRegister registers routers generated by IDL.
 func GeneratedRegister(r *server.Hertz) {
 	//INSERT_POINT: DO NOT DELETE THIS LINE!
-	qiniu.Register(r)
+	storage.Register(r)
 
-	tencent.Register(r)
-
-	aliyun.Register(r)
 }
@@ -1,6 +1,6 @@
 // Code generated by hertz generator.
 
-package aliyun
+package storage
 
 import (
 	"github.com/cloudwego/hertz/pkg/app"
@@ -21,37 +21,52 @@ func _fileMw() []app.HandlerFunc {
 	return nil
 }
 
+func _searchMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
 func _contentMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
 
-func _officeuploadMw() []app.HandlerFunc {
+func _deleteMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
 
-func _saveMw() []app.HandlerFunc {
+func _downloadMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
 
-func _uploadMw() []app.HandlerFunc {
+func _downloadingMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
 
-func _downloadMw() []app.HandlerFunc {
+func _downloadingofficeMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
 
-func _removeMw() []app.HandlerFunc {
+func _exportMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
 
-func _downloadingMw() []app.HandlerFunc {
+func _export0Mw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _importbucketMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _instantuploadMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
@@ -61,12 +76,37 @@ func _iouploadMw() []app.HandlerFunc {
 	return nil
 }
 
+func _officeuploadMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
 func _officeupload0Mw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
 
-func _downloadingofficeMw() []app.HandlerFunc {
+func _quotaMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _setquotaMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _removeMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _restoreMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _saveMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
@@ -75,3 +115,33 @@ func _statusMw() []app.HandlerFunc {
 	// your code...
 	return nil
 }
+
+func _thumbnailMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _uploadMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _listobjectsMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _jobMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _job0Mw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
+
+func _canceljobMw() []app.HandlerFunc {
+	// your code...
+	return nil
+}
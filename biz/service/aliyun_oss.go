@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"mime/multipart"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -14,21 +15,47 @@ import (
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
 	sls "github.com/aliyun/aliyun-log-go-sdk"
-	"github.com/cloudisk/biz/dal/query"
 	"github.com/cloudisk/pkg/config"
 )
 
 // 实现了 Uploader 接口，支持阿里云 OSS 上传
-type OssUploader struct{}
+type OssUploader struct {
+	Region          string
+	Bucket          string
+	Endpoint        string // 为空时按 Region 使用默认的外网 Endpoint
+	AccessKeyId     string
+	AccessKeySecret string
+	SSE             ServerSideEncryption // 上传和复制时指定的服务端加密
+}
 
 // 日志查询服务
 type LogQueryService struct {
 	client sls.ClientInterface
 }
 
-// 返回 OssUploader 实例
+// 返回使用环境变量配置的 OssUploader 实例
 func NewOssUploader() *OssUploader {
-	return &OssUploader{}
+	return &OssUploader{
+		Region:          config.OssRegion,
+		Bucket:          config.OssBucket,
+		AccessKeyId:     config.OssAccessKeyId,
+		AccessKeySecret: config.OssAccessKeySecret,
+		SSE:             newSSE(config.OssSSE, config.OssSSEKeyID),
+	}
+}
+
+// client 按实例的配置创建 OSS 客户端
+func (u *OssUploader) client() *oss.Client {
+	cfg := oss.LoadDefaultConfig().
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			u.AccessKeyId,
+			u.AccessKeySecret,
+			"")).
+		WithRegion(u.Region).WithConnectTimeout(3 * time.Second).WithRetryMaxAttempts(3)
+	if u.Endpoint != "" {
+		cfg = cfg.WithEndpoint(u.Endpoint)
+	}
+	return oss.NewClient(cfg)
 }
 
 // 构造函数，返回 LogQueryService 实例
@@ -45,56 +72,17 @@ func NewLogQueryService() *LogQueryService {
 
 // Upload 实现 Uploader 接口中的 Upload 方法
 func (u *OssUploader) Upload(file multipart.File, objectName string, pid int64) (int64, error) {
-	// 获取文件的完整路径
-	fullPath := objectName
-	if strings.Contains(objectName, "/") {
-		// 如果已经包含路径分隔符，说明是从 webkitRelativePath 传入的，直接使用
-		fullPath = objectName
-	} else {
-		// 从 pid 开始构建文件夹路径
-		paths := []string{}
-		currentPid := pid
-
-		// 递归查找父文件夹
-		for currentPid > 0 {
-			parentFile, err := query.Q.File.Where(query.File.ID.Eq(currentPid)).First()
-			if err != nil {
-				log.Printf("找不到父文件夹，ID: %d, 错误: %v", currentPid, err)
-				break
-			}
-
-			if parentFile.Type == "folder" {
-				log.Printf("添加文件夹到路径: %s", parentFile.Name)
-				paths = append([]string{parentFile.Name}, paths...)
-			}
-
-			currentPid = parentFile.Pid
-		}
-
-		// 构建完整的文件路径
-		if len(paths) > 0 {
-			fullPath = strings.Join(paths, "/") + "/" + objectName
-			log.Printf("构建的完整文件路径: %s", fullPath)
-		} else {
-			log.Printf("没有找到父文件夹，使用原始文件名: %s", objectName)
-		}
-	}
-
-	cfg := oss.LoadDefaultConfig().
-		WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			config.OssAccessKeyId,
-			config.OssAccessKeySecret,
-			"")).
-		WithRegion(config.OssRegion).WithConnectTimeout(3 * time.Second).WithRetryMaxAttempts(3)
+	fullPath := uploadPath(objectName, pid)
 
-	client := oss.NewClient(cfg)
+	client := u.client()
 
 	// 创建上传请求
 	request := &oss.PutObjectRequest{
-		Bucket: oss.Ptr(config.OssBucket),
+		Bucket: oss.Ptr(u.Bucket),
 		Key:    oss.Ptr(fullPath),
 		Body:   file,
 	}
+	request.ServerSideEncryption, request.SSEKMSKeyId = u.SSE.ossHeaders()
 
 	log.Printf("开始上传文件到路径: %s", fullPath)
 
@@ -107,7 +95,7 @@ func (u *OssUploader) Upload(file multipart.File, objectName string, pid int64)
 
 	// 上传成功后，获取文件信息
 	objectInfo, err := client.HeadObject(context.TODO(), &oss.HeadObjectRequest{
-		Bucket: oss.Ptr(config.OssBucket),
+		Bucket: oss.Ptr(u.Bucket),
 		Key:    oss.Ptr(fullPath),
 	})
 	if err != nil {
@@ -120,43 +108,49 @@ func (u *OssUploader) Upload(file multipart.File, objectName string, pid int64)
 }
 
 func (u *OssUploader) ReaderUpload(file io.ReadCloser, objectName string) (int64, error) {
+	return u.PutObject(file, objectName, PutOptions{})
+}
+
+// PutObject 上传对象，opts 中的校验值交由 OSS 核对
+func (u *OssUploader) PutObject(file io.Reader, objectName string, opts PutOptions) (int64, error) {
 	log.Printf("开始上传文件到路径: %s", objectName)
 
 	// 检查必要的环境变量
-	if config.OssAccessKeyId == "" || config.OssAccessKeySecret == "" || config.OssRegion == "" || config.OssBucket == "" {
-		log.Printf("OSS 配置错误: AccessKeyId=%s, Region=%s, Bucket=%s", 
-			config.OssAccessKeyId, config.OssRegion, config.OssBucket)
+	if u.AccessKeyId == "" || u.AccessKeySecret == "" || u.Region == "" || u.Bucket == "" {
+		log.Printf("OSS 配置错误: AccessKeyId=%s, Region=%s, Bucket=%s",
+			u.AccessKeyId, u.Region, u.Bucket)
 		return 0, fmt.Errorf("OSS configuration is incomplete. Please check your environment variables")
 	}
 
-	cfg := oss.LoadDefaultConfig().
-		WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			config.OssAccessKeyId,
-			config.OssAccessKeySecret,
-			"")).
-		WithRegion(config.OssRegion).WithConnectTimeout(3 * time.Second).WithRetryMaxAttempts(3)
-
-	client := oss.NewClient(cfg)
+	client := u.client()
 
 	// 创建上传请求
 	request := &oss.PutObjectRequest{
-		Bucket: oss.Ptr(config.OssBucket),
+		Bucket: oss.Ptr(u.Bucket),
 		Key:    oss.Ptr(objectName),
 		Body:   file,
 	}
+	request.ServerSideEncryption, request.SSEKMSKeyId = u.SSE.ossHeaders()
+	if opts.ContentMD5 != "" {
+		request.ContentMD5 = oss.Ptr(opts.ContentMD5)
+	}
 
-	log.Printf("正在上传文件到 OSS: bucket=%s, key=%s", config.OssBucket, objectName)
+	log.Printf("正在上传文件到 OSS: bucket=%s, key=%s", u.Bucket, objectName)
 
 	// 上传文件
-	_, err := client.PutObject(context.TODO(), request)
+	result, err := client.PutObject(context.TODO(), request)
 	if err != nil {
 		log.Printf("文件上传失败: %v", err)
 		return 0, fmt.Errorf("failed to upload object: %v", err)
 	}
+	if opts.CRC64 != "" && result.HashCRC64 != nil && *result.HashCRC64 != opts.CRC64 {
+		log.Printf("CRC64 校验失败: local=%s, oss=%s", opts.CRC64, *result.HashCRC64)
+		return 0, fmt.Errorf("crc64 mismatch: local %s, oss %s", opts.CRC64, *result.HashCRC64)
+	}
 
 	// 上传成功后，获取文件信息
 	objectInfo, err := client.HeadObject(context.TODO(), &oss.HeadObjectRequest{
-		Bucket: oss.Ptr(config.OssBucket),
+		Bucket: oss.Ptr(u.Bucket),
 		Key:    oss.Ptr(objectName),
 	})
 	if err != nil {
@@ -168,6 +162,144 @@ func (u *OssUploader) ReaderUpload(file io.ReadCloser, objectName string) (int64
 	return objectInfo.ContentLength, nil
 }
 
+// GetObject 以流的方式读取对象内容，调用方负责关闭
+func (u *OssUploader) GetObject(objectName string) (io.ReadCloser, error) {
+	return u.getObject(&oss.GetObjectRequest{
+		Bucket: oss.Ptr(u.Bucket),
+		Key:    oss.Ptr(objectName),
+	})
+}
+
+// GetObjectRange 以流的方式读取对象的一段内容，调用方负责关闭
+func (u *OssUploader) GetObjectRange(objectName string, offset int64, length int64) (io.ReadCloser, error) {
+	return u.getObject(&oss.GetObjectRequest{
+		Bucket:        oss.Ptr(u.Bucket),
+		Key:           oss.Ptr(objectName),
+		Range:         oss.Ptr(httpRange(offset, length)),
+		RangeBehavior: oss.Ptr("standard"),
+	})
+}
+
+func (u *OssUploader) getObject(request *oss.GetObjectRequest) (io.ReadCloser, error) {
+	objectName := oss.ToString(request.Key)
+	output, err := u.client().GetObject(context.TODO(), request)
+	if err != nil {
+		var serr *oss.ServiceError
+		if errors.As(err, &serr) && serr.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, objectName)
+		}
+		if errors.As(err, &serr) && serr.Code == "InvalidObjectState" {
+			return nil, fmt.Errorf("%w: %s", ErrObjectArchived, objectName)
+		}
+		return nil, fmt.Errorf("failed to download object: %v", err)
+	}
+	return output.Body, nil
+}
+
+// Delete 实现 Uploader 接口中的 Delete 方法
+func (u *OssUploader) Delete(objectName string) error {
+	_, err := u.client().DeleteObject(context.TODO(), &oss.DeleteObjectRequest{
+		Bucket: oss.Ptr(u.Bucket),
+		Key:    oss.Ptr(objectName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %v", err)
+	}
+	return nil
+}
+
+// ossStorageClasses 统一的存储类型名称与 OSS 存储类型的对应关系
+var ossStorageClasses = map[string]oss.StorageClassType{
+	StorageStandard:    oss.StorageClassStandard,
+	StorageIA:          oss.StorageClassIA,
+	StorageArchive:     oss.StorageClassArchive,
+	StorageColdArchive: oss.StorageClassColdArchive,
+	StorageDeepArchive: oss.StorageClassDeepColdArchive,
+}
+
+// SetStorageClass 通过复制到自身转换对象的存储类型
+func (u *OssUploader) SetStorageClass(objectName string, class string) error {
+	storageClass, ok := ossStorageClasses[class]
+	if !ok {
+		return fmt.Errorf("OSS 不支持的存储类型: %s", class)
+	}
+	request := &oss.CopyObjectRequest{
+		Bucket:       oss.Ptr(u.Bucket),
+		Key:          oss.Ptr(objectName),
+		SourceBucket: oss.Ptr(u.Bucket),
+		SourceKey:    oss.Ptr(objectName),
+		StorageClass: storageClass,
+	}
+	request.ServerSideEncryption, request.SSEKMSKeyId = u.SSE.ossHeaders()
+	_, err := u.client().CopyObject(context.TODO(), request)
+	if err != nil {
+		return fmt.Errorf("failed to change storage class: %v", err)
+	}
+	return nil
+}
+
+// Restore 发起归档对象的恢复
+func (u *OssUploader) Restore(objectName string, days int) error {
+	_, err := u.client().RestoreObject(context.TODO(), &oss.RestoreObjectRequest{
+		Bucket:         oss.Ptr(u.Bucket),
+		Key:            oss.Ptr(objectName),
+		RestoreRequest: &oss.RestoreRequest{Days: int32(days)},
+	})
+	var serr *oss.ServiceError
+	if err != nil && !(errors.As(err, &serr) && serr.Code == "RestoreAlreadyInProgress") {
+		return fmt.Errorf("failed to restore object: %v", err)
+	}
+	return nil
+}
+
+// Restored 按 x-oss-restore 响应头判断恢复是否完成
+func (u *OssUploader) Restored(objectName string) (bool, error) {
+	result, err := u.client().HeadObject(context.TODO(), &oss.HeadObjectRequest{
+		Bucket: oss.Ptr(u.Bucket),
+		Key:    oss.Ptr(objectName),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve object info: %v", err)
+	}
+	return restoreDone(oss.ToString(result.Restore)), nil
+}
+
+// BucketEncryption 返回存储桶的默认加密，未配置时 Mode 为空
+func (u *OssUploader) BucketEncryption() (ServerSideEncryption, error) {
+	result, err := u.client().GetBucketEncryption(context.TODO(), &oss.GetBucketEncryptionRequest{
+		Bucket: oss.Ptr(u.Bucket),
+	})
+	var serr *oss.ServiceError
+	if errors.As(err, &serr) && serr.Code == "NoSuchServerSideEncryptionRule" {
+		return ServerSideEncryption{}, nil
+	}
+	if err != nil {
+		return ServerSideEncryption{}, fmt.Errorf("failed to get bucket encryption: %v", err)
+	}
+	if result.ServerSideEncryptionRule == nil || result.ServerSideEncryptionRule.ApplyServerSideEncryptionByDefault == nil {
+		return ServerSideEncryption{}, nil
+	}
+	rule := result.ServerSideEncryptionRule.ApplyServerSideEncryptionByDefault
+	return newSSE(oss.ToString(rule.SSEAlgorithm), oss.ToString(rule.KMSMasterKeyID)), nil
+}
+
+// ObjectEncryption 按 x-oss-server-side-encryption 响应头返回对象的加密方式
+func (u *OssUploader) ObjectEncryption(objectName string) (ServerSideEncryption, error) {
+	result, err := u.client().HeadObject(context.TODO(), &oss.HeadObjectRequest{
+		Bucket: oss.Ptr(u.Bucket),
+		Key:    oss.Ptr(objectName),
+	})
+	if err != nil {
+		return ServerSideEncryption{}, fmt.Errorf("failed to retrieve object info: %v", err)
+	}
+	return newSSE(oss.ToString(result.ServerSideEncryption), oss.ToString(result.SSEKMSKeyId)), nil
+}
+
+// restoreDone 解析 OSS、COS 的恢复状态响应头，例如 ongoing-request="false", expiry-date="..."
+func restoreDone(header string) bool {
+	return strings.Contains(header, `ongoing-request="false"`)
+}
+
 // DownloadFile 从阿里云OSS下载文件
 func DownloadFile(objectName string) ([]byte, error) {
 	bucketName := os.Getenv("OSS_BUCKET")
@@ -271,124 +403,57 @@ func DeleteFile(objectName string) error {
 	return nil
 }
 
-// ListFiles 从阿里云OSS获取文件列表
-func ListFiles() (any, error) {
-	bucketName := os.Getenv("OSS_BUCKET")
-	region := os.Getenv("OSS_REGION")
-
-	if bucketName == "" || region == "" {
-		return nil, errors.New("invalid parameters: bucket name and region are required")
-	}
-
-	cfg := oss.LoadDefaultConfig().
-		WithCredentialsProvider(credentials.NewEnvironmentVariableCredentialsProvider()).
-		WithRegion(region)
-
-	client := oss.NewClient(cfg)
-
-	request := &oss.ListObjectsV2Request{
-		Bucket: oss.Ptr(bucketName),
-	}
-
-	p := client.NewListObjectsV2Paginator(request)
-	// var fileInfos []model.FileInfo
-
-	// for p.HasNext() {
-	// 	page, err := p.NextPage(context.TODO())
-	// 	if err != nil {
-	// 		return nil, fmt.Errorf("failed to get objects list: %v", err)
-	// 	}
-
-	// 	// 收集每个对象的信息
-	// 	for _, obj := range page.Contents {
-	// 		fileInfos = append(fileInfos, model.FileInfo{
-	// 			Key:           oss.ToString(obj.Key),
-	// 			ContentLength: obj.Size,
-	// 			ETag:          oss.ToString(obj.ETag),
-	// 			LastModified:  oss.ToTime(obj.LastModified),
-	// 		})
-	// 	}
-	// }
-
-	return p, nil
+// ListFiles 列出阿里云OSS中 prefix 下的全部对象
+func ListFiles(prefix string) ([]ObjectInfo, error) {
+	return listAll(alioss, prefix)
 }
 
-func ListFilesV2(prefix, continuationToken string, maxKeys int) (any, string, error) {
-	bucketName := os.Getenv("OSS_BUCKET")
-	region := os.Getenv("OSS_REGION")
+// ListFilesV2 分页列出 prefix 下的对象，continuationToken 为上一页返回的令牌，没有下一页时返回空字符串
+func ListFilesV2(prefix, continuationToken string, maxKeys int) ([]ObjectInfo, string, error) {
+	return alioss.List(prefix, continuationToken, maxKeys)
+}
 
-	if bucketName == "" || region == "" {
+// List 实现 ObjectLister 接口
+func (u *OssUploader) List(prefix, marker string, limit int) ([]ObjectInfo, string, error) {
+	if u.Bucket == "" || u.Region == "" {
 		return nil, "", errors.New("invalid parameters: bucket name and region are required")
 	}
 
-	// 设置默认值
-	if prefix == "" {
-		prefix = "" // 默认不筛选文件前缀，列出所有对象
-	}
-	if maxKeys == 0 {
-		maxKeys = 1000 // 默认最多返回1000个文件
+	if limit == 0 {
+		limit = 1000 // 默认最多返回1000个文件
 	}
 
-	cfg := oss.LoadDefaultConfig().
-		WithCredentialsProvider(credentials.NewEnvironmentVariableCredentialsProvider()).
-		WithRegion(region)
-
-	client := oss.NewClient(cfg)
-
-	// 创建请求
 	request := &oss.ListObjectsV2Request{
-		Bucket:            oss.Ptr(bucketName),
-		Prefix:            oss.Ptr(prefix),
-		ContinuationToken: oss.Ptr(continuationToken),
-		MaxKeys:           int32(maxKeys),
+		Bucket:  oss.Ptr(u.Bucket),
+		Prefix:  oss.Ptr(prefix),
+		MaxKeys: int32(limit),
+	}
+	if marker != "" {
+		request.ContinuationToken = oss.Ptr(marker)
 	}
 
-	// 使用分页器
-	paginator := client.NewListObjectsV2Paginator(request)
-	// var fileInfos []model.FileInfo
-	var nextContinuationToken string
-	totalFiles := 0 // 用于控制返回文件数量
-
-	for paginator.HasNext() {
-		page, err := paginator.NextPage(context.TODO())
-		if err != nil {
-			return nil, "", fmt.Errorf("failed to get objects list: %v", err)
-		}
-
-		// 收集每个对象的信息
-		for _, obj := range page.Contents {
-			fmt.Print(obj)
-			// fileInfos = append(fileInfos, model.FileInfo{
-			// 	Key:           oss.ToString(obj.Key),
-			// 	ContentLength: obj.Size,
-			// 	ETag:          oss.ToString(obj.ETag),
-			// 	LastModified:  oss.ToTime(obj.LastModified),
-			// })
-			totalFiles++
-			// 如果已收集的文件数量达到了限制，则停止
-			if totalFiles >= maxKeys {
-				// 如果返回了 NextContinuationToken，使用它作为下一次查询的起点
-				if page.NextContinuationToken != nil {
-					nextContinuationToken = *page.NextContinuationToken
-				}
-				break
-			}
-		}
-
-		// 如果已经达到限制数量，则不再请求更多页面
-		if totalFiles >= maxKeys {
-			break
-		}
+	page, err := u.client().ListObjectsV2(context.TODO(), request)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get objects list: %v", err)
+	}
 
-		// 如果返回了 NextContinuationToken，使用它作为下一次查询的起点
-		if page.NextContinuationToken != nil {
-			nextContinuationToken = *page.NextContinuationToken
-		} else {
-			break
-		}
+	objects := make([]ObjectInfo, 0, len(page.Contents))
+	for _, obj := range page.Contents {
+		objects = append(objects, ObjectInfo{
+			Key:          oss.ToString(obj.Key),
+			Size:         obj.Size,
+			ETag:         strings.Trim(oss.ToString(obj.ETag), `"`),
+			LastModified: oss.ToTime(obj.LastModified),
+			StorageClass: storageClassName(oss.ToString(obj.StorageClass)),
+		})
 	}
 
-	return nil, nextContinuationToken, nil
+	// 如果返回了 NextContinuationToken，使用它作为下一次查询的起点
+	var nextContinuationToken string
+	if page.IsTruncated && page.NextContinuationToken != nil {
+		nextContinuationToken = *page.NextContinuationToken
+	}
+	return objects, nextContinuationToken, nil
 }
 
 // CopyFile 拷贝文件到目标存储空间
@@ -416,6 +481,7 @@ func CopyFile(srcBucket, srcObject, destBucket, destObject string) error {
 		SourceBucket: oss.Ptr(srcBucket),
 		SourceKey:    oss.Ptr(srcObject),
 	}
+	request.ServerSideEncryption, request.SSEKMSKeyId = alioss.SSE.ossHeaders()
 
 	_, err := client.CopyObject(context.TODO(), request)
 	if err != nil {
@@ -447,6 +513,7 @@ func RenameFile(srcObject, destObject string) error {
 		SourceKey:    oss.Ptr(srcObject),
 		SourceBucket: oss.Ptr(bucketName),
 	}
+	copyRequest.ServerSideEncryption, copyRequest.SSEKMSKeyId = alioss.SSE.ossHeaders()
 
 	// 执行 CopyObject 操作
 	_, err := client.CopyObject(context.TODO(), copyRequest)
@@ -0,0 +1,482 @@
+package service
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/pkg/config"
+	"github.com/gabriel-vasile/mimetype"
+	"gorm.io/gen"
+)
+
+var (
+	// ErrBlobNotFound 秒传时云端不存在相同内容
+	ErrBlobNotFound = errors.New("云端不存在相同内容的文件，请重新上传")
+	// ErrChecksumMismatch 上传内容与客户端提供的校验值不一致
+	ErrChecksumMismatch = errors.New("文件校验值不一致，上传内容可能已损坏")
+)
+
+// crc64Table 与 OSS/COS 一致的 CRC64-ECMA 表
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// blobMutex 串行化同一进程内的引用计数变更，跨进程的并发由 hash 唯一索引兜底
+var blobMutex sync.Mutex
+
+// blobKey 返回内容寻址对象在存储桶中的路径
+func blobKey(hash string) string {
+	return fmt.Sprintf("blobs/%s/%s", hash[:2], hash)
+}
+
+// spooledFile 暂存到本地的上传内容
+type spooledFile struct {
+	*os.File
+	Hash  string // SHA-256，十六进制
+	MD5   []byte
+	CRC64 uint64
+	Size  int64
+	Mime  string // 按文件头识别的 MIME 类型
+}
+
+// matches 判断客户端提供的校验值（SHA-256 或 MD5 的十六进制）是否与内容一致
+func (s *spooledFile) matches(expected string) bool {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	switch len(expected) {
+	case 0:
+		return true
+	case 64:
+		return expected == s.Hash
+	case 32:
+		return expected == hex.EncodeToString(s.MD5)
+	default:
+		return false
+	}
+}
+
+// Remove 关闭并删除临时文件
+func (s *spooledFile) Remove() {
+	s.File.Close()
+	os.Remove(s.File.Name())
+}
+
+// spool 将数据流写入临时文件，同时计算 SHA-256、MD5 和 CRC64
+func spool(r io.Reader) (*spooledFile, error) {
+	tempFile, err := os.CreateTemp("", "cloudisk-blob-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	sf := &spooledFile{File: tempFile}
+
+	h := sha256.New()
+	m := md5.New()
+	c := crc64.New(crc64Table)
+	size, err := io.Copy(io.MultiWriter(tempFile, h, m, c), r)
+	if err != nil {
+		sf.Remove()
+		return nil, fmt.Errorf("failed to read upload content: %w", err)
+	}
+	if _, err := tempFile.Seek(0, io.SeekStart); err != nil {
+		sf.Remove()
+		return nil, fmt.Errorf("failed to seek temp file: %w", err)
+	}
+
+	// 按文件头识别真实类型，不依赖扩展名
+	head := make([]byte, 3072)
+	n, _ := tempFile.ReadAt(head, 0)
+	sf.Mime = mimetype.Detect(head[:n]).String()
+
+	sf.Hash = hex.EncodeToString(h.Sum(nil))
+	sf.MD5 = m.Sum(nil)
+	sf.CRC64 = c.Sum64()
+	sf.Size = size
+	return sf, nil
+}
+
+// putBlob 上传内容到 profile 存储配置并登记引用；同一存储中相同内容已存在时只增加引用计数，不重复上传。
+// fileType 为 getFileType 返回的文件类型，决定是否压缩；expected 为客户端提供的 SHA-256 或 MD5，为空时不校验
+func putBlob(profile string, fileType string, r io.Reader, expected string) (*entity.FileBlob, error) {
+	sf, err := spool(r)
+	if err != nil {
+		return nil, err
+	}
+	defer sf.Remove()
+
+	if !sf.matches(expected) {
+		log.Printf("上传内容校验失败: expected=%s, sha256=%s", expected, sf.Hash)
+		return nil, ErrChecksumMismatch
+	}
+
+	encrypt := encryptFor(profile)
+	if blob, err := acquireBlob(profile, sf.Hash, sf.Size); err == nil {
+		log.Printf("内容已存在，跳过上传: %s", sf.Hash)
+		if blob.Mime == "" {
+			// 补全早期登记时未识别的类型
+			query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).Update(query.FileBlob.Mime, sf.Mime)
+			blob.Mime = sf.Mime
+		}
+		return blob, nil
+	} else if !errors.Is(err, ErrBlobNotFound) {
+		return nil, err
+	}
+
+	key, err := uploadKey(profile, sf.Hash, encrypt)
+	if err != nil {
+		return nil, err
+	}
+	uploader, err := profileStore(profile)
+	if err != nil {
+		return nil, err
+	}
+	// 先压缩后加密，云端校验值按上传的数据计算
+	object := sf
+	encoding := compressFor(fileType, sf.Size)
+	var encodedSize int64
+	if encoding != "" {
+		compressed, err := compressSpool(sf, encoding)
+		if err != nil {
+			return nil, err
+		}
+		defer compressed.Remove()
+		if compressed.Size < sf.Size {
+			object, encodedSize = compressed, compressed.Size
+		} else {
+			// 压缩后没有变小，按原内容存储
+			encoding = ""
+		}
+	}
+	var envelope *Envelope
+	if encrypt {
+		encrypted, e, err := encryptSpool(object)
+		if err != nil {
+			return nil, err
+		}
+		defer encrypted.Remove()
+		object, envelope = encrypted, e
+	}
+	size, err := uploader.PutObject(object.File, key, PutOptions{
+		ContentMD5: base64.StdEncoding.EncodeToString(object.MD5),
+		CRC64:      strconv.FormatUint(object.CRC64, 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if size != object.Size {
+		uploader.Delete(key)
+		return nil, fmt.Errorf("uploaded size mismatch: expected %d, got %d", object.Size, size)
+	}
+
+	blobMutex.Lock()
+	defer blobMutex.Unlock()
+
+	blob, err := query.Q.FileBlob.Where(query.FileBlob.Profile.Eq(profile), query.FileBlob.Hash.Eq(sf.Hash),
+		query.FileBlob.Cipher.Eq(blobCipher(encrypt))).First()
+	if err == nil {
+		// 上传期间已有相同内容登记，本次上传的对象不再使用
+		if blob.ObjectKey != key {
+			uploader.Delete(key)
+		}
+		return addBlobRef(blob)
+	}
+
+	blob = &entity.FileBlob{
+		Profile:   profile,
+		Hash:      sf.Hash,
+		Size:      sf.Size,
+		MD5:       hex.EncodeToString(sf.MD5),
+		CRC64:     strconv.FormatUint(sf.CRC64, 10),
+		Mime:      sf.Mime,
+		ObjectKey: key,
+		RefCount:  1,
+	}
+	if encoding != "" {
+		blob.Encoding = encoding
+		blob.EncodedSize = encodedSize
+	}
+	if envelope != nil {
+		blob.Cipher = encCipher
+		blob.KeyID = envelope.KeyID
+		blob.DataKey = envelope.DataKey
+		blob.Nonce = envelope.Nonce
+	}
+	if err := query.Q.FileBlob.Create(blob); err != nil {
+		if key != blobKey(sf.Hash) {
+			uploader.Delete(key)
+		}
+		return nil, fmt.Errorf("failed to create blob record: %w", err)
+	}
+	return blob, nil
+}
+
+// blobCipher 返回内容登记的加密方式，未加密时为空
+func blobCipher(encrypt bool) string {
+	if encrypt {
+		return encCipher
+	}
+	return ""
+}
+
+// uploadKey 返回新上传内容的对象路径。加密内容每次上传的密文不同，使用带随机后缀的路径，
+// 避免并发上传相同内容时互相覆盖；同一存储中已有其他加密方式的相同内容时也使用随机后缀
+func uploadKey(profile string, hash string, encrypt bool) (string, error) {
+	key := blobKey(hash)
+	if !encrypt {
+		count, err := query.Q.FileBlob.Where(query.FileBlob.Profile.Eq(profile), query.FileBlob.Hash.Eq(hash)).Count()
+		if err != nil {
+			return "", fmt.Errorf("failed to find blob: %w", err)
+		}
+		if count == 0 {
+			return key, nil
+		}
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate object key: %w", err)
+	}
+	return key + "-" + hex.EncodeToString(suffix), nil
+}
+
+// encryptSpool 以新的数据密钥加密暂存的内容，返回暂存的密文和加密信息
+func encryptSpool(sf *spooledFile) (*spooledFile, *Envelope, error) {
+	envelope, dataKey, err := newEnvelope()
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	prefix, _ := base64.StdEncoding.DecodeString(envelope.Nonce)
+	encrypted, err := spool(newEncryptReader(sf.File, aead, prefix))
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := sf.File.Seek(0, io.SeekStart); err != nil {
+		encrypted.Remove()
+		return nil, nil, fmt.Errorf("failed to seek temp file: %w", err)
+	}
+	return encrypted, envelope, nil
+}
+
+// acquireBlob 为 profile 存储中已存在的内容增加一次引用，只复用加密方式与存储配置当前要求一致的内容；
+// size 大于 0 时同时校验大小
+func acquireBlob(profile string, hash string, size int64) (*entity.FileBlob, error) {
+	blobMutex.Lock()
+	defer blobMutex.Unlock()
+
+	// 引用计数为 0 的内容仍在保留期内，可重新引用
+	blob, err := query.Q.FileBlob.Where(query.FileBlob.Profile.Eq(profile), query.FileBlob.Hash.Eq(hash),
+		query.FileBlob.Cipher.Eq(blobCipher(encryptFor(profile)))).First()
+	if err != nil {
+		return nil, ErrBlobNotFound
+	}
+	if size > 0 && blob.Size != size {
+		return nil, ErrBlobNotFound
+	}
+	return addBlobRef(blob)
+}
+
+// reacquireBlob 为恢复的文件内容重新引用其原对象
+func reacquireBlob(profile string, key string) (*entity.FileBlob, error) {
+	blobMutex.Lock()
+	defer blobMutex.Unlock()
+
+	blob, err := query.Q.FileBlob.Where(query.FileBlob.Profile.Eq(profile), query.FileBlob.ObjectKey.Eq(key)).First()
+	if err != nil {
+		return nil, ErrBlobNotFound
+	}
+	return addBlobRef(blob)
+}
+
+// addBlobRef 增加一次引用并清除释放时间，调用方需持有 blobMutex
+func addBlobRef(blob *entity.FileBlob) (*entity.FileBlob, error) {
+	if _, err := query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).
+		UpdateSimple(query.FileBlob.RefCount.Add(1), query.FileBlob.ReleasedAt.Null()); err != nil {
+		return nil, fmt.Errorf("failed to add blob reference: %w", err)
+	}
+	blob.RefCount++
+	blob.ReleasedAt = nil
+	return blob, nil
+}
+
+// releaseBlob 释放 profile 存储中 key 对象的一次引用，最后一个引用释放时删除云端对象；
+// 配置了保留期时只将引用计数置为 0，由 PurgeBlobs 到期清理，以便恢复已删除的文件。
+// 未登记的对象（早期按路径存储的内容）不处理
+func releaseBlob(profile string, key string) error {
+	if key == "" {
+		return nil
+	}
+
+	blobMutex.Lock()
+	defer blobMutex.Unlock()
+
+	blob, err := query.Q.FileBlob.Where(query.FileBlob.Profile.Eq(profile), query.FileBlob.ObjectKey.Eq(key)).First()
+	if err != nil {
+		return nil
+	}
+
+	if blob.RefCount > 1 {
+		_, err = query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).
+			UpdateSimple(query.FileBlob.RefCount.Sub(1))
+		return err
+	}
+	if blob.RefCount == 1 && config.BlobRetentionHours > 0 {
+		// 保留期从最后一个引用释放时开始计算
+		_, err = query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).
+			UpdateSimple(query.FileBlob.RefCount.Sub(1), query.FileBlob.ReleasedAt.Value(time.Now()))
+		return err
+	}
+	if blob.RefCount <= 0 {
+		return nil
+	}
+
+	return deleteBlob(blob)
+}
+
+// deleteBlob 删除云端对象及其登记记录，调用方需持有 blobMutex
+func deleteBlob(blob *entity.FileBlob) error {
+	store, err := profileStore(blob.Profile)
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(blob.ObjectKey); err != nil {
+		return fmt.Errorf("failed to delete blob object: %w", err)
+	}
+	if _, err := query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).Delete(); err != nil {
+		return fmt.Errorf("failed to delete blob record: %w", err)
+	}
+	if strings.HasPrefix(blob.Mime, "image/") {
+		deleteThumbnails(blob.Profile, blob.ObjectKey)
+	}
+	log.Printf("内容已无引用，删除云端对象: %s", blob.ObjectKey)
+	return nil
+}
+
+// PurgeBlobs 清理引用计数为 0 且释放时间超过保留期的内容，返回清理的数量
+func PurgeBlobs() (int, error) {
+	blobMutex.Lock()
+	defer blobMutex.Unlock()
+
+	before := time.Now().Add(-time.Duration(config.BlobRetentionHours) * time.Hour)
+	blobs, err := query.Q.FileBlob.Where(query.FileBlob.RefCount.Lte(0), query.FileBlob.ReleasedAt.Lt(before)).Find()
+	if err != nil {
+		return 0, fmt.Errorf("failed to find unreferenced blobs: %w", err)
+	}
+
+	purged := 0
+	for _, blob := range blobs {
+		if err := deleteBlob(blob); err != nil {
+			log.Printf("清理内容失败: %s, 错误: %v", blob.ObjectKey, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// setBlobContent 将内容寻址信息写入 file_contents 的 content 字段
+func setBlobContent(content map[string]interface{}, blob *entity.FileBlob) {
+	content["hash"] = blob.Hash
+	content["md5"] = blob.MD5
+	content["crc64"] = blob.CRC64
+	content["mime"] = blob.Mime
+	content["key"] = blob.ObjectKey
+	if blob.Profile != "" {
+		content["profile"] = blob.Profile
+	}
+	if blob.Encoding != "" {
+		content["encoding"] = blob.Encoding
+		content["encoded_size"] = blob.EncodedSize
+	} else {
+		delete(content, "encoding")
+		delete(content, "encoded_size")
+	}
+	if blob.Cipher != "" {
+		content["cipher"] = blob.Cipher
+		content["key_id"] = blob.KeyID
+		content["data_key"] = blob.DataKey
+		content["nonce"] = blob.Nonce
+	} else {
+		delete(content, "cipher")
+		delete(content, "key_id")
+		delete(content, "data_key")
+		delete(content, "nonce")
+	}
+}
+
+// VerifyResult 对象校验结果
+type VerifyResult struct {
+	Key      string
+	Expected string // 记录的 SHA-256
+	Actual   string // 重新计算的 SHA-256
+	Size     int64
+	Status   string // ok / mismatch / unknown / error
+	Err      error
+}
+
+// verifyObject 下载内容并重新计算 SHA-256（加密内容解密后计算），与记录值比对
+func verifyObject(meta ContentMeta) VerifyResult {
+	expected := meta.Hash
+	result := VerifyResult{Key: meta.Key, Expected: expected}
+
+	body, err := openContent(meta)
+	if err != nil {
+		result.Status = "error"
+		result.Err = err
+		return result
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	result.Size, err = io.Copy(h, body)
+	if err != nil {
+		result.Status = "error"
+		result.Err = err
+		return result
+	}
+	result.Actual = hex.EncodeToString(h.Sum(nil))
+
+	switch {
+	case expected == "":
+		result.Status = "unknown"
+	case expected == result.Actual:
+		result.Status = "ok"
+	default:
+		result.Status = "mismatch"
+	}
+	return result
+}
+
+// VerifyFile 重新计算文件最新内容的校验值
+func VerifyFile(fileID int64) (VerifyResult, error) {
+	file, err := query.Q.File.Where(query.File.ID.Eq(fileID)).First()
+	if err != nil {
+		return VerifyResult{}, fmt.Errorf("file not found: %v", err)
+	}
+	meta := GetContentMeta(file)
+	return verifyObject(meta), nil
+}
+
+// VerifyBlobs 逐个校验已登记的内容对象，每个对象只下载一次
+func VerifyBlobs(fn func(VerifyResult)) error {
+	var blobs []*entity.FileBlob
+	return query.Q.FileBlob.FindInBatches(&blobs, 100, func(tx gen.Dao, batch int) error {
+		for _, blob := range blobs {
+			fn(verifyObject(blobMeta(blob)))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
+)
+
+func Test_Spool(t *testing.T) {
+	sf, err := spool(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Remove()
+
+	if sf.Hash != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("unexpected hash %s", sf.Hash)
+	}
+	if sf.Size != 5 {
+		t.Errorf("unexpected size %d", sf.Size)
+	}
+	data, _ := io.ReadAll(sf)
+	if string(data) != "hello" {
+		t.Errorf("spooled content mismatch: %q", data)
+	}
+	if key := blobKey(sf.Hash); key != "blobs/2c/"+sf.Hash {
+		t.Errorf("unexpected blob key %s", key)
+	}
+}
+
+func Test_SpoolChecksums(t *testing.T) {
+	sf, err := spool(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Remove()
+
+	for _, expected := range []string{"", "5d41402abc4b2a76b9719d911017c592", "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824"} {
+		if !sf.matches(expected) {
+			t.Errorf("expected %q to match", expected)
+		}
+	}
+	if sf.matches("5d41402abc4b2a76b9719d911017c593") {
+		t.Error("unexpected match for wrong md5")
+	}
+
+	meta := ContentMeta{Hash: sf.Hash, MD5: "5d41402abc4b2a76b9719d911017c592"}
+	if digest := meta.Digest(); digest != "sha-256=LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=,md5=XUFAKrxLKna5cZ2REBfFkg==" {
+		t.Errorf("unexpected digest %s", digest)
+	}
+}
+
+func Test_SpoolMime(t *testing.T) {
+	sf, err := spool(strings.NewReader("%PDF-1.4\n%test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Remove()
+
+	if sf.Mime != "application/pdf" {
+		t.Errorf("unexpected mime %s", sf.Mime)
+	}
+	if !IsInlineType(sf.Mime) || IsInlineType("image/svg+xml") || IsInlineType("text/html; charset=utf-8") {
+		t.Errorf("unexpected inline type check")
+	}
+}
+
+// createBlobFile 上传内容并创建引用该内容的文件
+func createBlobFile(t *testing.T, profile string, userid int64, data string) (*gorm_gen.File, *entity.FileBlob) {
+	t.Helper()
+	blob, err := putBlob(profile, "txt", strings.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &gorm_gen.File{Type: "txt", Name: "note", Ext: "txt", Size: blob.Size, Userid: userid, CreatedID: userid}
+	if err := query.Q.File.Create(file); err != nil {
+		t.Fatal(err)
+	}
+	content := map[string]interface{}{"type": "txt", "ext": "txt"}
+	setBlobContent(content, blob)
+	raw, _ := json.Marshal(content)
+	if err := query.Q.FileContent.Create(&gorm_gen.FileContent{Fid: file.ID, Content: string(raw), Size: blob.Size, Userid: userid}); err != nil {
+		t.Fatal(err)
+	}
+	return file, blob
+}
+
+func Test_BlobRetention(t *testing.T) {
+	newTestDB(t)
+	store := useTestStore(t, "test")
+	retention := config.BlobRetentionHours
+	config.BlobRetentionHours = 24
+	defer func() { config.BlobRetentionHours = retention }()
+
+	user := &User{Userid: 1}
+	file, blob := createBlobFile(t, "test", 1, "retained content")
+	// 内容创建于保留期之前，保留期应从删除时开始计算
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).UpdateSimple(query.FileBlob.CreatedAt.Value(old), query.FileBlob.UpdatedAt.Value(old))
+
+	if err := DeleteFiles(user, int(file.ID)); err != nil {
+		t.Fatal(err)
+	}
+	released, _ := query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).First()
+	if released.RefCount != 0 || released.ReleasedAt == nil {
+		t.Fatalf("after delete: ref_count=%d released_at=%v", released.RefCount, released.ReleasedAt)
+	}
+	if purged, err := PurgeBlobs(); err != nil || purged != 0 {
+		t.Fatalf("PurgeBlobs() = %d, %v, want 0", purged, err)
+	}
+	if _, ok := store.objects[blob.ObjectKey]; !ok {
+		t.Fatal("object purged within retention")
+	}
+
+	if err := RestoreFiles(user, int(file.ID)); err != nil {
+		t.Fatal(err)
+	}
+	restored, _ := query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).First()
+	if restored.RefCount != 1 || restored.ReleasedAt != nil {
+		t.Fatalf("after restore: ref_count=%d released_at=%v", restored.RefCount, restored.ReleasedAt)
+	}
+	body, err := openContent(GetContentMeta(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "retained content" {
+		t.Errorf("restored content = %q", data)
+	}
+
+	// 释放超过保留期后被清理
+	if err := DeleteFiles(user, int(file.ID)); err != nil {
+		t.Fatal(err)
+	}
+	query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).UpdateSimple(query.FileBlob.ReleasedAt.Value(time.Now().Add(-25 * time.Hour)))
+	if purged, err := PurgeBlobs(); err != nil || purged != 1 {
+		t.Fatalf("PurgeBlobs() = %d, %v, want 1", purged, err)
+	}
+	if _, ok := store.objects[blob.ObjectKey]; ok {
+		t.Error("expired object not deleted")
+	}
+	if err := RestoreFiles(user, int(file.ID)); err == nil {
+		t.Error("restore after purge should fail")
+	}
+}
@@ -0,0 +1,435 @@
+package service
+
+import (
+	"container/list"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudisk/pkg/config"
+	"golang.org/x/sync/singleflight"
+)
+
+// cacheDirName LOCAL_DOWNLOAD_DIR 中存放下载缓存的子目录，离线文件直接存放在 LOCAL_DOWNLOAD_DIR 中
+const cacheDirName = ".cache"
+
+// errNotCacheable 内容超出缓存预算，直接从云存储读取
+var errNotCacheable = errors.New("内容超出本地缓存预算")
+
+// cacheEntry 本地缓存中的一个文件
+type cacheEntry struct {
+	key       string // 内容的 SHA-256，启动时发现的离线文件为空
+	path      string
+	size      int64
+	pinned    bool          // 离线文件，不被淘汰，由 Remove 删除
+	cacheable bool          // 取消离线后可转为下载缓存，加密内容的明文不保留
+	elem      *list.Element // 在 LRU 链表中的位置，离线文件为 nil
+}
+
+// localCache LOCAL_DOWNLOAD_DIR 中的本地缓存：离线文件和按 LRU 淘汰的下载缓存共用 LOCAL_CACHE_MAX_BYTES 的空间
+type localCache struct {
+	dir    string
+	budget int64
+	mu     sync.Mutex
+	lru    *list.List             // 下载缓存，最近使用的在前
+	byKey  map[string]*cacheEntry // 内容的 SHA-256 对应的任一文件
+	byPath map[string]*cacheEntry
+	used   int64
+	group  singleflight.Group
+}
+
+var (
+	downloadCache     *localCache
+	downloadCacheOnce sync.Once
+)
+
+// getLocalCache 返回 LOCAL_DOWNLOAD_DIR 的本地缓存，首次使用时登记目录中已有的文件，未配置目录时返回 nil
+func getLocalCache() *localCache {
+	downloadCacheOnce.Do(func() {
+		if config.LocalDownloadDir == "" {
+			return
+		}
+		cache, err := newLocalCache(config.LocalDownloadDir, config.LocalCacheMaxBytes)
+		if err != nil {
+			log.Printf("初始化本地缓存失败: %s, 错误: %v", config.LocalDownloadDir, err)
+			return
+		}
+		downloadCache = cache
+	})
+	return downloadCache
+}
+
+func newLocalCache(dir string, budget int64) (*localCache, error) {
+	c := &localCache{
+		dir:    dir,
+		budget: budget,
+		lru:    list.New(),
+		byKey:  map[string]*cacheEntry{},
+		byPath: map[string]*cacheEntry{},
+	}
+	if err := os.MkdirAll(filepath.Join(dir, cacheDirName), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %v", err)
+	}
+	if err := c.scan(); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.evict(nil)
+	c.mu.Unlock()
+	return c, nil
+}
+
+// scan 登记目录中已有的文件：子目录中的下载缓存按修改时间排列，其余文件为离线文件
+func (c *localCache) scan() error {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache dir: %v", err)
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		c.add(&cacheEntry{path: filepath.Join(c.dir, entry.Name()), size: info.Size(), pinned: true})
+	}
+
+	cacheDir := filepath.Join(c.dir, cacheDirName)
+	entries, err = os.ReadDir(cacheDir)
+	if err != nil {
+		return fmt.Errorf("failed to read cache dir: %v", err)
+	}
+	type cached struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	items := []cached{}
+	for _, entry := range entries {
+		path := filepath.Join(cacheDir, entry.Name())
+		if !isContentHash(entry.Name()) {
+			// 中断的填充留下的临时文件
+			os.Remove(path)
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		items = append(items, cached{name: entry.Name(), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].modTime.Before(items[j].modTime) })
+	for _, item := range items {
+		c.add(&cacheEntry{key: item.name, path: filepath.Join(cacheDir, item.name), size: item.size, cacheable: true})
+	}
+	return nil
+}
+
+// isContentHash 判断是否为十六进制的 SHA-256，用作缓存文件名
+func isContentHash(s string) bool {
+	if len(s) != 64 || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// add 登记文件，下载缓存放在 LRU 链表最前，调用方持有锁
+func (c *localCache) add(e *cacheEntry) {
+	if old, ok := c.byPath[e.path]; ok {
+		c.remove(old)
+	}
+	c.byPath[e.path] = e
+	if _, ok := c.byKey[e.key]; e.key != "" && !ok {
+		c.byKey[e.key] = e
+	}
+	if !e.pinned {
+		e.elem = c.lru.PushFront(e)
+	}
+	c.used += e.size
+}
+
+// remove 注销文件，不删除文件本身，调用方持有锁
+func (c *localCache) remove(e *cacheEntry) {
+	delete(c.byPath, e.path)
+	if e.key != "" && c.byKey[e.key] == e {
+		delete(c.byKey, e.key)
+		for _, other := range c.byPath {
+			if other.key == e.key {
+				c.byKey[e.key] = other
+				break
+			}
+		}
+	}
+	if e.elem != nil {
+		c.lru.Remove(e.elem)
+		e.elem = nil
+	}
+	c.used -= e.size
+}
+
+// evict 按 LRU 删除下载缓存直到占用不超过预算，keep 为刚填充的文件，不被淘汰；离线文件不被淘汰，调用方持有锁
+func (c *localCache) evict(keep *cacheEntry) {
+	for c.used > c.budget {
+		back := c.lru.Back()
+		if back == nil || back.Value.(*cacheEntry) == keep {
+			log.Printf("本地缓存占用超过预算: %d/%d", c.used, c.budget)
+			return
+		}
+		e := back.Value.(*cacheEntry)
+		c.remove(e)
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除本地缓存失败: %s, 错误: %v", e.path, err)
+		}
+	}
+}
+
+// lookup 返回内容的本地文件路径，并标记为最近使用
+func (c *localCache) lookup(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.byKey[key]
+	if !ok {
+		return "", false
+	}
+	if e.elem != nil {
+		c.lru.MoveToFront(e.elem)
+	}
+	return e.path, true
+}
+
+// fill 将 size 字节的内容填充到下载缓存并返回文件路径，同一内容的并发填充只读取一次
+func (c *localCache) fill(key string, size int64, open func() (io.ReadCloser, error)) (string, error) {
+	if path, ok := c.lookup(key); ok {
+		return path, nil
+	}
+	if c.budget <= 0 || size > c.budget {
+		return "", errNotCacheable
+	}
+	path, err, _ := c.group.Do(key, func() (interface{}, error) {
+		if path, ok := c.lookup(key); ok {
+			return path, nil
+		}
+		path := filepath.Join(c.dir, cacheDirName, key)
+		written, err := writeFile(path, open)
+		if err != nil {
+			return "", err
+		}
+		e := &cacheEntry{key: key, path: path, size: written, cacheable: true}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.add(e)
+		c.evict(e)
+		return path, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return path.(string), nil
+}
+
+// pin 将内容保存为离线文件 dest：已有下载缓存时直接转为离线文件，已有其他离线文件时复制，否则通过 open 读取
+func (c *localCache) pin(key string, cacheable bool, dest string, open func() (io.ReadCloser, error)) error {
+	_, err, _ := c.group.Do("pin:"+dest, func() (interface{}, error) {
+		return nil, c.doPin(key, cacheable, dest, open)
+	})
+	return err
+}
+
+func (c *localCache) doPin(key string, cacheable bool, dest string, open func() (io.ReadCloser, error)) error {
+	source := ""
+	c.mu.Lock()
+	if e, ok := c.byKey[key]; ok && key != "" {
+		switch {
+		case e.path == dest:
+			c.mu.Unlock()
+			return nil
+		case !e.pinned:
+			if err := os.Rename(e.path, dest); err == nil {
+				c.remove(e)
+				e.path, e.pinned = dest, true
+				c.add(e)
+				c.mu.Unlock()
+				return nil
+			}
+		default:
+			source = e.path
+		}
+	}
+	c.mu.Unlock()
+
+	if source != "" {
+		from := open
+		open = func() (io.ReadCloser, error) {
+			if f, err := os.Open(source); err == nil {
+				return f, nil
+			}
+			// 离线文件已被删除，改从云存储读取
+			return from()
+		}
+	}
+	written, err := writeFile(dest, open)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(&cacheEntry{key: key, path: dest, size: written, pinned: true, cacheable: cacheable})
+	c.evict(nil)
+	return nil
+}
+
+// unpin 取消离线文件 path：内容可缓存且没有其他副本时转为下载缓存，否则删除
+func (c *localCache) unpin(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.byPath[path]
+	if !ok {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete local file: %w", err)
+		}
+		return nil
+	}
+	c.remove(e)
+	if _, exists := c.byKey[e.key]; e.cacheable && e.key != "" && !exists && c.budget > 0 {
+		target := filepath.Join(c.dir, cacheDirName, e.key)
+		if err := os.Rename(path, target); err == nil {
+			e.path, e.pinned = target, false
+			c.add(e)
+			c.evict(nil)
+			return nil
+		}
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete local file: %w", err)
+	}
+	return nil
+}
+
+// writeFile 将 open 读取的内容写入临时文件后重命名为 path，返回写入的大小
+func writeFile(path string, open func() (io.ReadCloser, error)) (int64, error) {
+	body, err := open()
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	written, err := io.Copy(tempFile, body)
+	if cerr := tempFile.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tempFile.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return 0, fmt.Errorf("failed to write local file: %w", err)
+	}
+	return written, nil
+}
+
+// readFileRange 读取本地文件中从 offset 开始的 length 字节，length 小于 0 时读到末尾
+func readFileRange(path string, offset int64, length int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if length < 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		return io.ReadAll(f)
+	}
+	buf := make([]byte, length)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// cacheKey 返回内容在本地缓存中的标识，未记录 SHA-256 的旧内容对象路径可能被覆盖，不缓存
+func cacheKey(meta ContentMeta) string {
+	if !isContentHash(meta.Hash) {
+		return ""
+	}
+	return meta.Hash
+}
+
+// readCachedRange 从本地缓存读取内容的一段，未缓存时返回 false
+func readCachedRange(meta ContentMeta, offset int64, length int64) ([]byte, bool) {
+	cache := getLocalCache()
+	key := cacheKey(meta)
+	if cache == nil || key == "" {
+		return nil, false
+	}
+	path, ok := cache.lookup(key)
+	if !ok {
+		return nil, false
+	}
+	data, err := readFileRange(path, offset, length)
+	if err != nil {
+		// 读取前已被淘汰或删除
+		return nil, false
+	}
+	return data, true
+}
+
+// fillCache 将内容填充到下载缓存，加密内容的明文不进入下载缓存
+func fillCache(meta ContentMeta) error {
+	cache := getLocalCache()
+	key := cacheKey(meta)
+	if cache == nil || key == "" || meta.Envelope != nil {
+		return errNotCacheable
+	}
+	_, err := cache.fill(key, meta.Size, func() (io.ReadCloser, error) {
+		return openRestored(meta, func() (io.ReadCloser, error) {
+			return openContent(meta)
+		})
+	})
+	return err
+}
+
+// IsCached 判断内容是否已在本地缓存中
+func IsCached(meta ContentMeta) bool {
+	cache := getLocalCache()
+	key := cacheKey(meta)
+	if cache == nil || key == "" {
+		return false
+	}
+	_, ok := cache.lookup(key)
+	return ok
+}
+
+// PinContent 将内容保存为离线文件 dest，离线文件不被淘汰；对象已归档时发起恢复并返回 ErrObjectRestoring
+func PinContent(meta ContentMeta, dest string) error {
+	cache := getLocalCache()
+	if cache == nil {
+		return errors.New("本地保存目录未配置")
+	}
+	return cache.pin(cacheKey(meta), meta.Envelope == nil, dest, func() (io.ReadCloser, error) {
+		return openRestored(meta, func() (io.ReadCloser, error) {
+			return openContent(meta)
+		})
+	})
+}
+
+// UnpinContent 取消离线文件 path，内容转为下载缓存或删除
+func UnpinContent(path string) error {
+	cache := getLocalCache()
+	if cache == nil {
+		return errors.New("本地保存目录未配置")
+	}
+	return cache.unpin(path)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// cacheKeyForTest 返回第 i 个测试内容的标识
+func cacheKeyForTest(i int) string {
+	return fmt.Sprintf("%064x", i)
+}
+
+func openForTest(data []byte, calls *int32) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		if calls != nil {
+			atomic.AddInt32(calls, 1)
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+func Test_localCache_evict(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := newLocalCache(dir, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("x"), 100)
+	for i := 0; i < 2; i++ {
+		if _, err := cache.fill(cacheKeyForTest(i), 100, openForTest(data, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 访问第一个内容后，第二个成为最久未使用的
+	cache.lookup(cacheKeyForTest(0))
+	if _, err := cache.fill(cacheKeyForTest(2), 100, openForTest(data, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.lookup(cacheKeyForTest(1)); ok {
+		t.Errorf("最久未使用的内容未被淘汰")
+	}
+	if _, err := os.Stat(filepath.Join(dir, cacheDirName, cacheKeyForTest(1))); !os.IsNotExist(err) {
+		t.Errorf("淘汰的缓存文件未删除: %v", err)
+	}
+	if cache.used != 200 {
+		t.Errorf("used = %d, want 200", cache.used)
+	}
+	if _, err := cache.fill(cacheKeyForTest(3), 300, openForTest(data, nil)); err != errNotCacheable {
+		t.Errorf("超出预算的内容: err = %v, want errNotCacheable", err)
+	}
+
+	// 离线文件不被淘汰，取消后转为下载缓存
+	dest := filepath.Join(dir, "1_note.md")
+	if err := cache.pin(cacheKeyForTest(0), true, dest, openForTest(data, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if path, ok := cache.lookup(cacheKeyForTest(0)); !ok || path != dest {
+		t.Errorf("下载缓存未转为离线文件: %s", path)
+	}
+	for i := 4; i < 7; i++ {
+		cache.fill(cacheKeyForTest(i), 100, openForTest(data, nil))
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Errorf("离线文件被淘汰: %v", err)
+	}
+	if err := cache.unpin(dest); err != nil {
+		t.Fatal(err)
+	}
+	if path, ok := cache.lookup(cacheKeyForTest(0)); !ok || path != filepath.Join(dir, cacheDirName, cacheKeyForTest(0)) {
+		t.Errorf("取消离线后未转为下载缓存: %s, %v", path, ok)
+	}
+
+	// 重新打开时按已有文件恢复占用
+	reopened, err := newLocalCache(dir, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.used != cache.used || reopened.lru.Len() != cache.lru.Len() {
+		t.Errorf("reopened used = %d, want %d", reopened.used, cache.used)
+	}
+}
+
+func Test_localCache_singleflight(t *testing.T) {
+	cache, err := newLocalCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("content"), 1000)
+	var calls int32
+	release := make(chan struct{})
+	open := func() (io.ReadCloser, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	var wg sync.WaitGroup
+	paths := make([]string, 8)
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paths[i], _ = cache.fill(cacheKeyForTest(0), int64(len(data)), open)
+		}(i)
+	}
+	// 等待所有请求进入填充后再放行读取
+	for atomic.LoadInt32(&calls) == 0 {
+	}
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("并发填充读取了 %d 次，want 1", calls)
+	}
+	for _, path := range paths {
+		got, err := readFileRange(path, 7, 14)
+		if err != nil || !bytes.Equal(got, data[7:21]) {
+			t.Fatalf("读取缓存文件失败: %s, %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cloudisk/pkg/config"
+	"github.com/klauspost/compress/zstd"
+)
+
+// 内容的压缩方式，与 HTTP Content-Encoding 的取值一致
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// compressTypes 未设置 COMPRESSION_TYPES 时压缩的文件类型，均为文本内容
+var compressTypes = []string{"document", "code", "txt", "drawio", "mind"}
+
+// compressFor 返回 fileType 类型、size 字节的新内容使用的压缩方式，不压缩时为空
+func compressFor(fileType string, size int64) string {
+	if config.CompressionCodec != EncodingGzip && config.CompressionCodec != EncodingZstd {
+		return ""
+	}
+	if size < config.CompressionMinSize {
+		return ""
+	}
+	types := config.CompressionTypes
+	if len(types) == 0 {
+		types = compressTypes
+	}
+	if !isContain(types, strings.ToLower(fileType)) {
+		return ""
+	}
+	return config.CompressionCodec
+}
+
+// newEncoder 返回按 encoding 压缩后写入 w 的 Writer，关闭时写入剩余数据
+func newEncoder(w io.Writer, encoding string) (io.WriteCloser, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewWriter(w), nil
+	case EncodingZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("不支持的压缩方式: %s", encoding)
+}
+
+// newDecoder 返回解压 r 的 Reader
+func newDecoder(r io.Reader, encoding string) (io.ReadCloser, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewReader(r)
+	case EncodingZstd:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("不支持的压缩方式: %s", encoding)
+}
+
+// compressSpool 按 encoding 压缩暂存的内容，返回暂存的压缩结果
+func compressSpool(sf *spooledFile, encoding string) (*spooledFile, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		encoder, err := newEncoder(pw, encoding)
+		if err == nil {
+			if _, err = io.Copy(encoder, sf.File); err == nil {
+				err = encoder.Close()
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+	compressed, err := spool(pr)
+	pr.Close()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := sf.File.Seek(0, io.SeekStart); err != nil {
+		compressed.Remove()
+		return nil, fmt.Errorf("failed to seek temp file: %w", err)
+	}
+	return compressed, nil
+}
+
+// decodeRange 解压 body 中的内容，返回从 offset 开始的 length 字节，length 小于 0 时读到末尾
+func decodeRange(body io.ReadCloser, encoding string, offset int64, length int64) (io.ReadCloser, error) {
+	decoder, err := newDecoder(body, encoding)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to decompress content: %v", err)
+	}
+	if _, err := io.CopyN(io.Discard, decoder, offset); err != nil {
+		decoder.Close()
+		body.Close()
+		return nil, fmt.Errorf("failed to decompress content: %v", err)
+	}
+	var reader io.Reader = decoder
+	if length >= 0 {
+		reader = io.LimitReader(decoder, length)
+	}
+	return readCloser{Reader: reader, Closer: closers{decoder, body}}, nil
+}
+
+// closers 依次关闭多个 Closer，返回第一个错误
+type closers []io.Closer
+
+func (c closers) Close() error {
+	var first error
+	for _, closer := range c {
+		if err := closer.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// AcceptsEncoding 判断 Accept-Encoding 请求头是否接受 encoding，q=0 表示不接受
+func AcceptsEncoding(header string, encoding string) bool {
+	if encoding == "" {
+		return false
+	}
+	accepted := false
+	for _, item := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(item, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != encoding && name != "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == encoding {
+			// 明确列出的取值优先于 *
+			return q > 0
+		}
+		accepted = q > 0
+	}
+	return accepted
+}
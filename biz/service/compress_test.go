@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cloudisk/pkg/config"
+)
+
+// compressForTest 暂存 data 并按 encoding 压缩，返回压缩后的数据
+func compressForTest(t *testing.T, data []byte, encoding string) []byte {
+	t.Helper()
+	sf, err := spool(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Remove()
+	compressed, err := compressSpool(sf, encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer compressed.Remove()
+	out, err := io.ReadAll(compressed.File)
+	if err != nil || int64(len(out)) != compressed.Size {
+		t.Fatalf("读取压缩结果失败: %v", err)
+	}
+	return out
+}
+
+func Test_compress_readContentRange(t *testing.T) {
+	master := make([]byte, 32)
+	rand.Read(master)
+	km, err := newLocalKeyManager("k1:" + base64.StdEncoding.EncodeToString(master))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetKeyManager(km)
+
+	data := []byte(strings.Repeat("# 标题\n正文内容 markdown text\n", 10000))
+	store := newMemStore()
+	for _, encoding := range []string{EncodingGzip, EncodingZstd} {
+		compressed := compressForTest(t, data, encoding)
+		if len(compressed) >= len(data) {
+			t.Fatalf("%s: 压缩后没有变小: %d", encoding, len(compressed))
+		}
+		store.objects["plain"] = compressed
+		plain := ContentMeta{Key: "plain", Size: int64(len(data)), Encoding: encoding, EncodedSize: int64(len(compressed))}
+		encrypted := encryptForTest(t, store, "encrypted", compressed)
+		encrypted.Size, encrypted.Encoding, encrypted.EncodedSize = int64(len(data)), encoding, int64(len(compressed))
+
+		for _, meta := range []ContentMeta{plain, encrypted} {
+			got, err := readRange(t, store, meta, 0, -1)
+			if err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("%s %s: 读取全部内容失败: %v", encoding, meta.Key, err)
+			}
+			got, err = readRange(t, store, meta, 100000, 500)
+			if err != nil || !bytes.Equal(got, data[100000:100500]) {
+				t.Fatalf("%s %s: 读取范围失败: %v", encoding, meta.Key, err)
+			}
+			body, err := readPayloadRange(store, meta, 0, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload, _ := io.ReadAll(body)
+			body.Close()
+			if !bytes.Equal(payload, compressed) {
+				t.Fatalf("%s %s: 读取压缩数据不一致", encoding, meta.Key)
+			}
+		}
+	}
+}
+
+func Test_compressFor(t *testing.T) {
+	codec, types, minSize := config.CompressionCodec, config.CompressionTypes, config.CompressionMinSize
+	defer func() {
+		config.CompressionCodec, config.CompressionTypes, config.CompressionMinSize = codec, types, minSize
+	}()
+	config.CompressionCodec, config.CompressionTypes, config.CompressionMinSize = EncodingZstd, nil, 1024
+
+	cases := []struct {
+		fileType string
+		size     int64
+		want     string
+	}{
+		{"document", 4096, EncodingZstd},
+		{"code", 4096, EncodingZstd},
+		{"mind", 1024, EncodingZstd},
+		{"document", 100, ""},
+		{"picture", 4096, ""},
+	}
+	for _, c := range cases {
+		if got := compressFor(c.fileType, c.size); got != c.want {
+			t.Errorf("compressFor(%q, %d) = %q, want %q", c.fileType, c.size, got, c.want)
+		}
+	}
+	config.CompressionTypes = []string{"txt"}
+	if got := compressFor("document", 4096); got != "" {
+		t.Errorf("COMPRESSION_TYPES=txt: compressFor(document) = %q", got)
+	}
+	config.CompressionCodec = "brotli"
+	if got := compressFor("txt", 4096); got != "" {
+		t.Errorf("不支持的压缩方式: compressFor = %q", got)
+	}
+}
+
+func Test_AcceptsEncoding(t *testing.T) {
+	cases := []struct {
+		header   string
+		encoding string
+		want     bool
+	}{
+		{"gzip, deflate, br", EncodingGzip, true},
+		{"gzip, deflate, br", EncodingZstd, false},
+		{"br;q=1.0, zstd;q=0.8", EncodingZstd, true},
+		{"gzip;q=0", EncodingGzip, false},
+		{"*", EncodingZstd, true},
+		{"*, gzip;q=0", EncodingGzip, false},
+		{"", EncodingGzip, false},
+		{"gzip", "", false},
+	}
+	for _, c := range cases {
+		if got := AcceptsEncoding(c.header, c.encoding); got != c.want {
+			t.Errorf("AcceptsEncoding(%q, %q) = %v, want %v", c.header, c.encoding, got, c.want)
+		}
+	}
+}
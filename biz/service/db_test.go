@@ -0,0 +1,56 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 在临时目录创建 SQLite 数据库，建好用到的表并设为 query 的默认连接
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&gorm_gen.File{},
+		&gorm_gen.File_User{},
+		&gorm_gen.FileContent{},
+		&entity.FileBlob{},
+		&entity.FileQuota{},
+		&entity.FileJob{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.SetDefault(db)
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// useTestStore 将 profile 存储配置替换为内存存储
+func useTestStore(t *testing.T, profile string) *memStore {
+	t.Helper()
+	store := newMemStore()
+	profileStoresMu.Lock()
+	profileStores[profile] = store
+	profileStoresMu.Unlock()
+	t.Cleanup(func() {
+		profileStoresMu.Lock()
+		delete(profileStores, profile)
+		profileStoresMu.Unlock()
+	})
+	return store
+}
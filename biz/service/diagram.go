@@ -0,0 +1,654 @@
+package service
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// diagramPadding 导出图片四周的留白(px)
+const diagramPadding = 20
+
+// diagramShape 图形节点，坐标为左上角
+type diagramShape struct {
+	Kind      string // rect、rounded、ellipse、rhombus、text
+	X, Y      float64
+	W, H      float64
+	Fill      string // 为空时不填充
+	Stroke    string // 为空时不描边
+	FontColor string
+	FontSize  float64
+	Label     string // 多行以 \n 分隔
+}
+
+// diagramEdge 连线，Points 至少两个点
+type diagramEdge struct {
+	Points [][2]float64
+	Stroke string
+	Arrow  bool // 终点是否有箭头
+	Label  string
+}
+
+// diagram 与格式无关的图形，由 drawio 或思维导图解析得到，再输出为 SVG 或 PNG
+type diagram struct {
+	Shapes []diagramShape
+	Edges  []diagramEdge
+}
+
+// bounds 返回包含全部图形和连线的范围
+func (d *diagram) bounds() (minX, minY, maxX, maxY float64) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	extend := func(x, y float64) {
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	for _, s := range d.Shapes {
+		extend(s.X, s.Y)
+		extend(s.X+s.W, s.Y+s.H)
+	}
+	for _, e := range d.Edges {
+		for _, p := range e.Points {
+			extend(p[0], p[1])
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return 0, 0, 0, 0
+	}
+	return minX, minY, maxX, maxY
+}
+
+// ---------- drawio ----------
+
+type mxFile struct {
+	Diagrams []struct {
+		Name  string `xml:"name,attr"`
+		Inner string `xml:",innerxml"`
+	} `xml:"diagram"`
+}
+
+type mxGeometry struct {
+	X      float64 `xml:"x,attr"`
+	Y      float64 `xml:"y,attr"`
+	Width  float64 `xml:"width,attr"`
+	Height float64 `xml:"height,attr"`
+	Points []struct {
+		X  float64 `xml:"x,attr"`
+		Y  float64 `xml:"y,attr"`
+		As string  `xml:"as,attr"`
+	} `xml:"mxPoint"`
+	Waypoints []struct {
+		X float64 `xml:"x,attr"`
+		Y float64 `xml:"y,attr"`
+	} `xml:"Array>mxPoint"`
+}
+
+type mxCell struct {
+	ID       string      `xml:"id,attr"`
+	Parent   string      `xml:"parent,attr"`
+	Value    string      `xml:"value,attr"`
+	Style    string      `xml:"style,attr"`
+	Vertex   string      `xml:"vertex,attr"`
+	Edge     string      `xml:"edge,attr"`
+	Source   string      `xml:"source,attr"`
+	Target   string      `xml:"target,attr"`
+	Geometry *mxGeometry `xml:"mxGeometry"`
+}
+
+// mxObject 带自定义属性的节点（UserObject、object），标签在 label 属性中
+type mxObject struct {
+	ID    string `xml:"id,attr"`
+	Label string `xml:"label,attr"`
+	Cell  mxCell `xml:"mxCell"`
+}
+
+type mxGraphModel struct {
+	Cells       []mxCell   `xml:"root>mxCell"`
+	UserObjects []mxObject `xml:"root>UserObject"`
+	Objects     []mxObject `xml:"root>object"`
+}
+
+// drawioModel 从 drawio 文件中取出第一页的 mxGraphModel，页面内容可能经过 deflate + base64 + URL 编码压缩
+func drawioModel(data []byte) (*mxGraphModel, error) {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("<mxGraphModel")) {
+		return parseGraphModel(trimmed)
+	}
+
+	var file mxFile
+	if err := xml.Unmarshal(trimmed, &file); err != nil {
+		return nil, fmt.Errorf("解析 drawio 文件失败: %v", err)
+	}
+	if len(file.Diagrams) == 0 {
+		return nil, errors.New("drawio 文件中没有图形")
+	}
+	inner := strings.TrimSpace(file.Diagrams[0].Inner)
+	if strings.HasPrefix(inner, "<") {
+		return parseGraphModel([]byte(inner))
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(inner)
+	if err != nil {
+		return nil, fmt.Errorf("解码 drawio 页面失败: %v", err)
+	}
+	raw, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), 64<<20))
+	if err != nil {
+		return nil, fmt.Errorf("解压 drawio 页面失败: %v", err)
+	}
+	decoded, err := url.PathUnescape(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("解码 drawio 页面失败: %v", err)
+	}
+	return parseGraphModel([]byte(decoded))
+}
+
+func parseGraphModel(data []byte) (*mxGraphModel, error) {
+	var model mxGraphModel
+	if err := xml.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("解析 drawio 图形失败: %v", err)
+	}
+	for _, objects := range [][]mxObject{model.UserObjects, model.Objects} {
+		for _, object := range objects {
+			cell := object.Cell
+			cell.ID, cell.Value = object.ID, object.Label
+			model.Cells = append(model.Cells, cell)
+		}
+	}
+	return &model, nil
+}
+
+// parseDrawioStyle 解析 key=value;... 格式的样式，不带等号的项（如 ellipse、rhombus、text）记为 shape
+func parseDrawioStyle(style string) map[string]string {
+	styles := map[string]string{}
+	for _, item := range strings.Split(style, ";") {
+		if item == "" {
+			continue
+		}
+		if key, value, ok := strings.Cut(item, "="); ok {
+			styles[key] = value
+		} else if _, exists := styles["shape"]; !exists {
+			styles["shape"] = item
+		}
+	}
+	return styles
+}
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// plainLabel 将 html=1 的标签转为纯文本
+func plainLabel(label string) string {
+	label = htmlBreakPattern.ReplaceAllString(label, "\n")
+	label = htmlTagPattern.ReplaceAllString(label, "")
+	label = html.UnescapeString(label)
+	lines := strings.Split(label, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// parseDrawio 将 drawio 文件转换为图形，子节点坐标相对于上级节点（分组、容器）
+func parseDrawio(data []byte) (*diagram, error) {
+	model, err := drawioModel(data)
+	if err != nil {
+		return nil, err
+	}
+	cells := map[string]*mxCell{}
+	for i := range model.Cells {
+		cells[model.Cells[i].ID] = &model.Cells[i]
+	}
+
+	var origin func(id string, depth int) (float64, float64)
+	origin = func(id string, depth int) (float64, float64) {
+		cell, ok := cells[id]
+		if !ok || cell.Vertex != "1" || cell.Geometry == nil || depth > 50 {
+			return 0, 0
+		}
+		x, y := origin(cell.Parent, depth+1)
+		return x + cell.Geometry.X, y + cell.Geometry.Y
+	}
+	boxes := map[string][4]float64{}
+
+	d := &diagram{}
+	for _, cell := range model.Cells {
+		if cell.Vertex != "1" || cell.Geometry == nil {
+			continue
+		}
+		styles := parseDrawioStyle(cell.Style)
+		x, y := origin(cell.Parent, 0)
+		shape := diagramShape{
+			Kind:      "rect",
+			X:         x + cell.Geometry.X,
+			Y:         y + cell.Geometry.Y,
+			W:         cell.Geometry.Width,
+			H:         cell.Geometry.Height,
+			Fill:      drawioColor(styles["fillColor"], "#ffffff"),
+			Stroke:    drawioColor(styles["strokeColor"], "#000000"),
+			FontColor: drawioColor(styles["fontColor"], "#000000"),
+			FontSize:  12,
+			Label:     plainLabel(cell.Value),
+		}
+		if size, err := strconv.ParseFloat(styles["fontSize"], 64); err == nil && size > 0 {
+			shape.FontSize = size
+		}
+		switch styles["shape"] {
+		case "ellipse", "doubleEllipse":
+			shape.Kind = "ellipse"
+		case "rhombus":
+			shape.Kind = "rhombus"
+		case "text", "edgeLabel":
+			shape.Kind = "text"
+		default:
+			if styles["rounded"] == "1" {
+				shape.Kind = "rounded"
+			}
+		}
+		if shape.Kind == "text" {
+			if _, ok := styles["fillColor"]; !ok {
+				shape.Fill = ""
+			}
+			if _, ok := styles["strokeColor"]; !ok {
+				shape.Stroke = ""
+			}
+		}
+		boxes[cell.ID] = [4]float64{shape.X, shape.Y, shape.W, shape.H}
+		d.Shapes = append(d.Shapes, shape)
+	}
+
+	for _, cell := range model.Cells {
+		if cell.Edge != "1" {
+			continue
+		}
+		styles := parseDrawioStyle(cell.Style)
+		ox, oy := origin(cell.Parent, 0)
+		var start, end [2]float64
+		var hasStart, hasEnd bool
+		var waypoints [][2]float64
+		if g := cell.Geometry; g != nil {
+			for _, p := range g.Points {
+				switch p.As {
+				case "sourcePoint":
+					start, hasStart = [2]float64{ox + p.X, oy + p.Y}, true
+				case "targetPoint":
+					end, hasEnd = [2]float64{ox + p.X, oy + p.Y}, true
+				}
+			}
+			for _, p := range g.Waypoints {
+				waypoints = append(waypoints, [2]float64{ox + p.X, oy + p.Y})
+			}
+		}
+		source, hasSource := boxes[cell.Source]
+		target, hasTarget := boxes[cell.Target]
+		if hasSource {
+			start, hasStart = boxCenter(source), true
+		}
+		if hasTarget {
+			end, hasEnd = boxCenter(target), true
+		}
+		if !hasStart || !hasEnd {
+			continue
+		}
+
+		points := append([][2]float64{start}, waypoints...)
+		points = append(points, end)
+		// 连接到节点时从节点边框开始和结束
+		if hasSource {
+			points[0] = clipToBox(source, points[1], points[0])
+		}
+		if hasTarget {
+			points[len(points)-1] = clipToBox(target, points[len(points)-2], points[len(points)-1])
+		}
+		d.Edges = append(d.Edges, diagramEdge{
+			Points: points,
+			Stroke: drawioColor(styles["strokeColor"], "#000000"),
+			Arrow:  styles["endArrow"] != "none",
+			Label:  plainLabel(cell.Value),
+		})
+	}
+	if len(d.Shapes) == 0 && len(d.Edges) == 0 {
+		return nil, errors.New("drawio 文件中没有图形")
+	}
+	return d, nil
+}
+
+// drawioColor 返回样式中的颜色，none 表示不绘制
+func drawioColor(value string, def string) string {
+	switch value {
+	case "":
+		return def
+	case "none":
+		return ""
+	case "default":
+		return def
+	}
+	return value
+}
+
+func boxCenter(box [4]float64) [2]float64 {
+	return [2]float64{box[0] + box[2]/2, box[1] + box[3]/2}
+}
+
+// clipToBox 返回从 from 指向 box 内的点 to 的线段与 box 边框的交点
+func clipToBox(box [4]float64, from [2]float64, to [2]float64) [2]float64 {
+	center := boxCenter(box)
+	dx, dy := from[0]-center[0], from[1]-center[1]
+	if dx == 0 && dy == 0 {
+		return to
+	}
+	scale := math.Inf(1)
+	if dx != 0 {
+		scale = math.Min(scale, box[2]/2/math.Abs(dx))
+	}
+	if dy != 0 {
+		scale = math.Min(scale, box[3]/2/math.Abs(dy))
+	}
+	if scale >= 1 {
+		return to
+	}
+	return [2]float64{center[0] + dx*scale, center[1] + dy*scale}
+}
+
+// ---------- 思维导图 ----------
+
+// mindNode 思维导图节点
+type mindNode struct {
+	Text     string
+	Children []*mindNode
+}
+
+// kityNode 百度脑图（kityminder）格式的节点
+type kityNode struct {
+	Data struct {
+		Text string `json:"text"`
+	} `json:"data"`
+	Children []*kityNode `json:"children"`
+}
+
+// jsMindNode jsMind node_tree 格式的节点
+type jsMindNode struct {
+	Topic    string        `json:"topic"`
+	Children []*jsMindNode `json:"children"`
+}
+
+// parseMindTree 解析思维导图，支持 kityminder（{"root": ...}）和 jsMind（{"data": ...}）格式
+func parseMindTree(data []byte) (*mindNode, error) {
+	var doc struct {
+		Root *kityNode   `json:"root"`
+		Data *jsMindNode `json:"data"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析思维导图失败: %v", err)
+	}
+	var fromKity func(n *kityNode, depth int) *mindNode
+	fromKity = func(n *kityNode, depth int) *mindNode {
+		node := &mindNode{Text: n.Data.Text}
+		for _, child := range n.Children {
+			if child != nil && depth < 100 {
+				node.Children = append(node.Children, fromKity(child, depth+1))
+			}
+		}
+		return node
+	}
+	var fromJsMind func(n *jsMindNode, depth int) *mindNode
+	fromJsMind = func(n *jsMindNode, depth int) *mindNode {
+		node := &mindNode{Text: plainLabel(n.Topic)}
+		for _, child := range n.Children {
+			if child != nil && depth < 100 {
+				node.Children = append(node.Children, fromJsMind(child, depth+1))
+			}
+		}
+		return node
+	}
+	switch {
+	case doc.Root != nil:
+		return fromKity(doc.Root, 0), nil
+	case doc.Data != nil:
+		return fromJsMind(doc.Data, 0), nil
+	}
+	return nil, errors.New("无法识别的思维导图格式")
+}
+
+// 思维导图布局参数(px)
+const (
+	mindNodeHeight = 32
+	mindRowGap     = 12
+	mindColumnGap  = 48
+	mindFontSize   = 14
+)
+
+// textWidth 估算文字宽度：全角字符按字号计算，其余按半个字号
+func textWidth(text string, fontSize float64) float64 {
+	var width float64
+	for _, line := range strings.Split(text, "\n") {
+		var w float64
+		for _, r := range line {
+			if utf8.RuneLen(r) > 1 {
+				w += fontSize
+			} else {
+				w += fontSize * 0.6
+			}
+		}
+		width = math.Max(width, w)
+	}
+	return width
+}
+
+// parseMind 将思维导图按从左到右的树形布局转换为图形，每层的节点左对齐
+func parseMind(data []byte) (*diagram, error) {
+	root, err := parseMindTree(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每层的最大宽度决定下一层的起始位置
+	var columns []float64
+	var measure func(n *mindNode, depth int)
+	measure = func(n *mindNode, depth int) {
+		if len(columns) <= depth {
+			columns = append(columns, 0)
+		}
+		columns[depth] = math.Max(columns[depth], mindNodeWidth(n.Text))
+		for _, child := range n.Children {
+			measure(child, depth+1)
+		}
+	}
+	measure(root, 0)
+	offsets := make([]float64, len(columns))
+	for i := 1; i < len(columns); i++ {
+		offsets[i] = offsets[i-1] + columns[i-1] + mindColumnGap
+	}
+
+	d := &diagram{}
+	nextY := 0.0
+	// layout 返回节点的垂直中心，叶子节点依次向下排列，父节点位于子节点中间
+	var layout func(n *mindNode, depth int) float64
+	layout = func(n *mindNode, depth int) float64 {
+		var center float64
+		var childCenters []float64
+		if len(n.Children) == 0 {
+			center = nextY + mindNodeHeight/2
+			nextY += mindNodeHeight + mindRowGap
+		} else {
+			for _, child := range n.Children {
+				childCenters = append(childCenters, layout(child, depth+1))
+			}
+			center = (childCenters[0] + childCenters[len(childCenters)-1]) / 2
+		}
+
+		x, w := offsets[depth], mindNodeWidth(n.Text)
+		shape := diagramShape{
+			Kind: "rounded", X: x, Y: center - mindNodeHeight/2, W: w, H: mindNodeHeight,
+			Fill: "#ffffff", Stroke: "#1e88e5", FontColor: "#333333", FontSize: mindFontSize, Label: n.Text,
+		}
+		if depth == 0 {
+			shape.Fill, shape.FontColor = "#1e88e5", "#ffffff"
+		}
+		d.Shapes = append(d.Shapes, shape)
+
+		// 父节点右侧到子节点左侧的折线
+		for _, childCenter := range childCenters {
+			startX, endX := x+w, offsets[depth+1]
+			midX := startX + mindColumnGap/2
+			d.Edges = append(d.Edges, diagramEdge{
+				Points: [][2]float64{{startX, center}, {midX, center}, {midX, childCenter}, {endX, childCenter}},
+				Stroke: "#90caf9",
+			})
+		}
+		return center
+	}
+	layout(root, 0)
+	return d, nil
+}
+
+func mindNodeWidth(text string) float64 {
+	return math.Max(textWidth(text, mindFontSize)+24, 48)
+}
+
+// ---------- SVG ----------
+
+// renderSVG 输出 SVG，坐标平移到左上角并留白
+func renderSVG(d *diagram) []byte {
+	minX, minY, maxX, maxY := d.bounds()
+	width := math.Ceil(maxX - minX + diagramPadding*2)
+	height := math.Ceil(maxY - minY + diagramPadding*2)
+	dx, dy := diagramPadding-minX, diagramPadding-minY
+
+	var b bytes.Buffer
+	attr := func(s string) string {
+		var buf bytes.Buffer
+		xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	}
+	num := func(f float64) string {
+		return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+	}
+	paint := func(fill string, stroke string) string {
+		if fill == "" {
+			fill = "none"
+		}
+		s := fmt.Sprintf(`fill="%s"`, attr(fill))
+		if stroke != "" {
+			s += fmt.Sprintf(` stroke="%s" stroke-width="1"`, attr(stroke))
+		}
+		return s
+	}
+	text := func(x, y float64, label string, color string, size float64) {
+		if label == "" {
+			return
+		}
+		lines := strings.Split(label, "\n")
+		lineHeight := size * 1.2
+		top := y - lineHeight*float64(len(lines)-1)/2
+		fmt.Fprintf(&b, `<text x="%s" y="%s" fill="%s" font-size="%s" text-anchor="middle" dominant-baseline="central" font-family="Helvetica, Arial, 'PingFang SC', 'Microsoft YaHei', sans-serif">`,
+			num(x), num(top), attr(color), num(size))
+		for i, line := range lines {
+			if i == 0 {
+				fmt.Fprintf(&b, `<tspan x="%s">%s</tspan>`, num(x), attr(line))
+			} else {
+				fmt.Fprintf(&b, `<tspan x="%s" dy="%s">%s</tspan>`, num(x), num(lineHeight), attr(line))
+			}
+		}
+		b.WriteString("</text>\n")
+	}
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="0 0 %s %s">`+"\n",
+		num(width), num(height), num(width), num(height))
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="#ffffff"/>`+"\n")
+
+	for _, e := range d.Edges {
+		points := make([]string, 0, len(e.Points))
+		for _, p := range e.Points {
+			points = append(points, num(p[0]+dx)+","+num(p[1]+dy))
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1"/>`+"\n", strings.Join(points, " "), attr(e.Stroke))
+		if e.Arrow {
+			head := arrowHead(e.Points[len(e.Points)-2], e.Points[len(e.Points)-1])
+			fmt.Fprintf(&b, `<polygon points="%s,%s %s,%s %s,%s" fill="%s"/>`+"\n",
+				num(head[0][0]+dx), num(head[0][1]+dy), num(head[1][0]+dx), num(head[1][1]+dy),
+				num(head[2][0]+dx), num(head[2][1]+dy), attr(e.Stroke))
+		}
+	}
+	for _, s := range d.Shapes {
+		x, y := s.X+dx, s.Y+dy
+		switch s.Kind {
+		case "ellipse":
+			fmt.Fprintf(&b, `<ellipse cx="%s" cy="%s" rx="%s" ry="%s" %s/>`+"\n",
+				num(x+s.W/2), num(y+s.H/2), num(s.W/2), num(s.H/2), paint(s.Fill, s.Stroke))
+		case "rhombus":
+			fmt.Fprintf(&b, `<polygon points="%s,%s %s,%s %s,%s %s,%s" %s/>`+"\n",
+				num(x+s.W/2), num(y), num(x+s.W), num(y+s.H/2), num(x+s.W/2), num(y+s.H), num(x), num(y+s.H/2), paint(s.Fill, s.Stroke))
+		case "text":
+			if s.Fill != "" || s.Stroke != "" {
+				fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="%s" %s/>`+"\n", num(x), num(y), num(s.W), num(s.H), paint(s.Fill, s.Stroke))
+			}
+		default:
+			radius := 0.0
+			if s.Kind == "rounded" {
+				radius = roundedRadius(s)
+			}
+			fmt.Fprintf(&b, `<rect x="%s" y="%s" width="%s" height="%s" rx="%s" %s/>`+"\n",
+				num(x), num(y), num(s.W), num(s.H), num(radius), paint(s.Fill, s.Stroke))
+		}
+		text(x+s.W/2, y+s.H/2, s.Label, s.FontColor, s.FontSize)
+	}
+	for _, e := range d.Edges {
+		if e.Label != "" {
+			mid := polylineMidpoint(e.Points)
+			text(mid[0]+dx, mid[1]+dy, e.Label, "#000000", 11)
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+// roundedRadius 圆角半径，与 drawio 默认的 arcSize 接近
+func roundedRadius(s diagramShape) float64 {
+	return math.Min(math.Min(s.W, s.H)*0.15, 10)
+}
+
+// arrowHead 返回终点处箭头三角形的三个顶点
+func arrowHead(from [2]float64, to [2]float64) [3][2]float64 {
+	angle := math.Atan2(to[1]-from[1], to[0]-from[0])
+	const length, spread = 8.0, 0.4
+	return [3][2]float64{
+		to,
+		{to[0] - length*math.Cos(angle-spread), to[1] - length*math.Sin(angle-spread)},
+		{to[0] - length*math.Cos(angle+spread), to[1] - length*math.Sin(angle+spread)},
+	}
+}
+
+// polylineMidpoint 返回折线长度一半处的点，用于放置连线标签
+func polylineMidpoint(points [][2]float64) [2]float64 {
+	var total float64
+	for i := 1; i < len(points); i++ {
+		total += math.Hypot(points[i][0]-points[i-1][0], points[i][1]-points[i-1][1])
+	}
+	half := total / 2
+	for i := 1; i < len(points); i++ {
+		segment := math.Hypot(points[i][0]-points[i-1][0], points[i][1]-points[i-1][1])
+		if segment >= half && segment > 0 {
+			t := half / segment
+			return [2]float64{points[i-1][0] + (points[i][0]-points[i-1][0])*t, points[i-1][1] + (points[i][1]-points[i-1][1])*t}
+		}
+		half -= segment
+	}
+	return points[0]
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudisk/pkg/config"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+var (
+	diagramFont     *opentype.Font
+	diagramFontOnce sync.Once
+	diagramFaces    = map[float64]font.Face{}
+	diagramFacesMu  sync.Mutex
+)
+
+// diagramFace 返回指定字号的字体：优先使用 EXPORT_FONT_FILE（需包含中文字形），否则使用内置的 Go 字体
+func diagramFace(size float64) font.Face {
+	diagramFontOnce.Do(func() {
+		data := goregular.TTF
+		if config.ExportFontFile != "" {
+			if custom, err := os.ReadFile(config.ExportFontFile); err == nil {
+				data = custom
+			} else {
+				log.Printf("读取导出字体失败: %s, 错误: %v", config.ExportFontFile, err)
+			}
+		}
+		f, err := opentype.Parse(data)
+		if err != nil {
+			log.Printf("解析导出字体失败, 改用内置字体: %v", err)
+			f, _ = opentype.Parse(goregular.TTF)
+		}
+		diagramFont = f
+	})
+
+	diagramFacesMu.Lock()
+	defer diagramFacesMu.Unlock()
+	if face, ok := diagramFaces[size]; ok {
+		return face
+	}
+	face, err := opentype.NewFace(diagramFont, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil
+	}
+	diagramFaces[size] = face
+	return face
+}
+
+// parseColor 解析 #rgb、#rrggbb 格式的颜色，无法识别时返回黑色
+func parseColor(value string) color.Color {
+	switch strings.ToLower(value) {
+	case "white":
+		return color.White
+	case "black":
+		return color.Black
+	}
+	hex := strings.TrimPrefix(value, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return color.Black
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.Black
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
+
+// pngCanvas 按 scale 缩放绘制到图片
+type pngCanvas struct {
+	img    *image.RGBA
+	scale  float64
+	dx, dy float64
+	z      *vector.Rasterizer
+}
+
+func (c *pngCanvas) point(p [2]float64) (float32, float32) {
+	return float32((p[0] + c.dx) * c.scale), float32((p[1] + c.dy) * c.scale)
+}
+
+// fill 以抗锯齿方式填充多边形
+func (c *pngCanvas) fill(points [][2]float64, col string) {
+	if col == "" || len(points) < 3 {
+		return
+	}
+	bounds := c.img.Bounds()
+	c.z.Reset(bounds.Dx(), bounds.Dy())
+	c.z.MoveTo(c.point(points[0]))
+	for _, p := range points[1:] {
+		c.z.LineTo(c.point(p))
+	}
+	c.z.ClosePath()
+	c.z.Draw(c.img, bounds, image.NewUniform(parseColor(col)), image.Point{})
+}
+
+// stroke 沿折线逐段绘制宽度为 width 的线段
+func (c *pngCanvas) stroke(points [][2]float64, closed bool, width float64, col string) {
+	if col == "" || len(points) < 2 {
+		return
+	}
+	if closed {
+		points = append(points, points[0])
+	}
+	half := width / 2
+	for i := 1; i < len(points); i++ {
+		a, b := points[i-1], points[i]
+		length := math.Hypot(b[0]-a[0], b[1]-a[1])
+		if length == 0 {
+			continue
+		}
+		nx, ny := -(b[1]-a[1])/length*half, (b[0]-a[0])/length*half
+		c.fill([][2]float64{{a[0] + nx, a[1] + ny}, {b[0] + nx, b[1] + ny}, {b[0] - nx, b[1] - ny}, {a[0] - nx, a[1] - ny}}, col)
+	}
+}
+
+// text 以 (x, y) 为中心绘制多行文字
+func (c *pngCanvas) text(x, y float64, label string, col string, size float64) {
+	if label == "" {
+		return
+	}
+	face := diagramFace(size * c.scale)
+	if face == nil {
+		return
+	}
+	metrics := face.Metrics()
+	lineHeight := float64(metrics.Height.Round())
+	ascent := float64(metrics.Ascent.Round())
+	lines := strings.Split(label, "\n")
+	top := (y+c.dy)*c.scale - lineHeight*float64(len(lines))/2
+	drawer := &font.Drawer{Dst: c.img, Src: image.NewUniform(parseColor(col)), Face: face}
+	for i, line := range lines {
+		width := float64(drawer.MeasureString(line).Round())
+		drawer.Dot = fixed.P(int((x+c.dx)*c.scale-width/2), int(top+lineHeight*float64(i)+ascent))
+		drawer.DrawString(line)
+	}
+}
+
+// shapeOutline 返回图形轮廓的多边形顶点
+func shapeOutline(s diagramShape) [][2]float64 {
+	switch s.Kind {
+	case "ellipse":
+		const segments = 64
+		points := make([][2]float64, 0, segments)
+		cx, cy, rx, ry := s.X+s.W/2, s.Y+s.H/2, s.W/2, s.H/2
+		for i := 0; i < segments; i++ {
+			angle := 2 * math.Pi * float64(i) / segments
+			points = append(points, [2]float64{cx + rx*math.Cos(angle), cy + ry*math.Sin(angle)})
+		}
+		return points
+	case "rhombus":
+		return [][2]float64{{s.X + s.W/2, s.Y}, {s.X + s.W, s.Y + s.H/2}, {s.X + s.W/2, s.Y + s.H}, {s.X, s.Y + s.H/2}}
+	case "rounded":
+		r := roundedRadius(s)
+		corners := [4][3]float64{
+			{s.X + s.W - r, s.Y + r, -math.Pi / 2},
+			{s.X + s.W - r, s.Y + s.H - r, 0},
+			{s.X + r, s.Y + s.H - r, math.Pi / 2},
+			{s.X + r, s.Y + r, math.Pi},
+		}
+		var points [][2]float64
+		for _, corner := range corners {
+			for i := 0; i <= 8; i++ {
+				angle := corner[2] + math.Pi/2*float64(i)/8
+				points = append(points, [2]float64{corner[0] + r*math.Cos(angle), corner[1] + r*math.Sin(angle)})
+			}
+		}
+		return points
+	}
+	return [][2]float64{{s.X, s.Y}, {s.X + s.W, s.Y}, {s.X + s.W, s.Y + s.H}, {s.X, s.Y + s.H}}
+}
+
+// renderPNG 将图形栅格化为 PNG，scale 为缩放倍数，图片过大时自动减小
+func renderPNG(d *diagram, scale float64) ([]byte, error) {
+	minX, minY, maxX, maxY := d.bounds()
+	width := maxX - minX + diagramPadding*2
+	height := maxY - minY + diagramPadding*2
+	if scale <= 0 {
+		scale = 1
+	}
+	if pixels := width * height * scale * scale; pixels > thumbnailMaxPixels {
+		scale = math.Sqrt(thumbnailMaxPixels / (width * height))
+	}
+	w, h := int(math.Ceil(width*scale)), int(math.Ceil(height*scale))
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("图片尺寸无效: %dx%d", w, h)
+	}
+
+	c := &pngCanvas{
+		img:   image.NewRGBA(image.Rect(0, 0, w, h)),
+		scale: scale,
+		dx:    diagramPadding - minX,
+		dy:    diagramPadding - minY,
+		z:     vector.NewRasterizer(w, h),
+	}
+	draw.Draw(c.img, c.img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	for _, e := range d.Edges {
+		c.stroke(e.Points, false, 1, e.Stroke)
+		if e.Arrow {
+			head := arrowHead(e.Points[len(e.Points)-2], e.Points[len(e.Points)-1])
+			c.fill(head[:], e.Stroke)
+		}
+	}
+	for _, s := range d.Shapes {
+		outline := shapeOutline(s)
+		c.fill(outline, s.Fill)
+		c.stroke(outline, true, 1, s.Stroke)
+		c.text(s.X+s.W/2, s.Y+s.H/2, s.Label, s.FontColor, s.FontSize)
+	}
+	for _, e := range d.Edges {
+		if e.Label != "" {
+			mid := polylineMidpoint(e.Points)
+			c.text(mid[0], mid[1], e.Label, "#000000", 11)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return nil, fmt.Errorf("生成 PNG 失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
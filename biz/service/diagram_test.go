@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"image/png"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testGraphModel = `<mxGraphModel><root>
+<mxCell id="0"/><mxCell id="1" parent="0"/>
+<mxCell id="a" value="开始" style="rounded=1;fillColor=#dae8fc;" vertex="1" parent="1"><mxGeometry x="0" y="0" width="100" height="40" as="geometry"/></mxCell>
+<mxCell id="b" value="&lt;b&gt;判断&lt;/b&gt;&lt;br&gt;是否通过" style="rhombus;html=1;" vertex="1" parent="1"><mxGeometry x="0" y="100" width="100" height="60" as="geometry"/></mxCell>
+<UserObject label="结束" id="c"><mxCell style="ellipse;" vertex="1" parent="1"><mxGeometry x="200" y="110" width="80" height="40" as="geometry"/></mxCell></UserObject>
+<mxCell id="e1" edge="1" source="a" target="b" parent="1"><mxGeometry relative="1" as="geometry"/></mxCell>
+<mxCell id="e2" value="是" style="endArrow=none;" edge="1" source="b" target="c" parent="1"><mxGeometry relative="1" as="geometry"/></mxCell>
+</root></mxGraphModel>`
+
+func compressDrawio(model string) string {
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	w.Write([]byte(url.PathEscape(model)))
+	w.Close()
+	return `<mxfile><diagram name="第 1 页">` + base64.StdEncoding.EncodeToString(buf.Bytes()) + `</diagram></mxfile>`
+}
+
+func Test_ParseDrawio(t *testing.T) {
+	for _, source := range []string{testGraphModel, `<mxfile><diagram>` + testGraphModel + `</diagram></mxfile>`, compressDrawio(testGraphModel)} {
+		d, err := parseDrawio([]byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Shapes) != 3 || len(d.Edges) != 2 {
+			t.Fatalf("unexpected diagram %+v", d)
+		}
+		if d.Shapes[0].Kind != "rounded" || d.Shapes[1].Kind != "rhombus" || d.Shapes[2].Kind != "ellipse" {
+			t.Errorf("unexpected shape kinds %+v", d.Shapes)
+		}
+		if d.Shapes[1].Label != "判断\n是否通过" || d.Shapes[2].Label != "结束" {
+			t.Errorf("unexpected labels %q, %q", d.Shapes[1].Label, d.Shapes[2].Label)
+		}
+		// 连线从节点 a 的底边连到节点 b 的顶点
+		edge := d.Edges[0]
+		if edge.Points[0] != [2]float64{50, 40} || edge.Points[1] != [2]float64{50, 100} || !edge.Arrow {
+			t.Errorf("unexpected edge %+v", edge)
+		}
+		if d.Edges[1].Arrow || d.Edges[1].Label != "是" {
+			t.Errorf("unexpected edge %+v", d.Edges[1])
+		}
+	}
+}
+
+func Test_ParseMind(t *testing.T) {
+	kity := `{"root":{"data":{"text":"中心主题"},"children":[{"data":{"text":"分支一"},"children":[]},{"data":{"text":"分支二"},"children":[{"data":{"text":"子节点"}}]}]},"template":"default"}`
+	jsMind := `{"meta":{"name":"demo"},"format":"node_tree","data":{"id":"root","topic":"中心主题","children":[{"id":"1","topic":"分支一"},{"id":"2","topic":"分支二","children":[{"id":"3","topic":"子节点"}]}]}}`
+	for _, source := range []string{kity, jsMind} {
+		d, err := parseMind([]byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Shapes) != 4 || len(d.Edges) != 3 {
+			t.Fatalf("unexpected diagram %+v", d)
+		}
+		root := d.Shapes[len(d.Shapes)-1]
+		if root.Label != "中心主题" || root.X != 0 {
+			t.Errorf("unexpected root %+v", root)
+		}
+		for _, s := range d.Shapes[:len(d.Shapes)-1] {
+			if s.X <= root.X+root.W {
+				t.Errorf("child %q should be right of root", s.Label)
+			}
+		}
+	}
+	if _, err := parseMind([]byte(`{"foo":1}`)); err == nil {
+		t.Error("unknown mind format should fail")
+	}
+}
+
+func Test_RenderDiagram(t *testing.T) {
+	d, err := parseDrawio([]byte(testGraphModel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := string(renderSVG(d))
+	for _, want := range []string{`<svg xmlns="http://www.w3.org/2000/svg" width="320" height="200"`, "<ellipse", "<polygon", `<tspan x="70">判断</tspan>`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg missing %q", want)
+		}
+	}
+
+	data, err := renderPNG(d, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 640 || b.Dy() != 400 {
+		t.Errorf("unexpected png size %v", b)
+	}
+	// 节点 a 的填充色
+	if r, g, b, _ := img.At(2*(20+50), 2*(20+10)).RGBA(); r>>8 != 0xda || g>>8 != 0xe8 || b>>8 != 0xfc {
+		t.Errorf("unexpected fill color %x %x %x", r>>8, g>>8, b>>8)
+	}
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/cloudisk/pkg/config"
+)
+
+// 内容加密采用分块的 AES-256-GCM：明文按 encChunkSize 分块，每块单独加密并附带认证标签，
+// 第 i 块的 nonce 为 8 字节随机前缀加 4 字节块序号，最后一块的附加数据为 1，防止截断；读取任意范围时只需解密覆盖的块
+const (
+	encCipher     = "aes-256-gcm"
+	encChunkSize  = 64 << 10
+	encTagSize    = 16
+	encPrefixSize = 8
+)
+
+// Envelope 内容的加密信息：每个内容对象有独立的数据密钥，以主密钥加密后与 nonce 前缀一起记录
+type Envelope struct {
+	KeyID   string // 主密钥ID
+	DataKey string // 主密钥加密后的数据密钥，base64
+	Nonce   string // 分块 nonce 的随机前缀，base64
+}
+
+// KeyManager 加密和解密数据密钥的主密钥服务，可接入云厂商的 KMS，默认使用 ENCRYPTION_MASTER_KEYS 中的本地主密钥
+type KeyManager interface {
+	WrapKey(dataKey []byte) (keyID string, wrapped []byte, err error)
+	UnwrapKey(keyID string, wrapped []byte) ([]byte, error)
+}
+
+var (
+	keyManager     KeyManager
+	keyManagerErr  error
+	keyManagerOnce sync.Once
+)
+
+// SetKeyManager 替换主密钥服务，需在处理请求之前调用
+func SetKeyManager(km KeyManager) {
+	keyManagerOnce.Do(func() {})
+	keyManager, keyManagerErr = km, nil
+}
+
+// getKeyManager 返回主密钥服务，未设置时按 ENCRYPTION_MASTER_KEYS 创建本地主密钥服务
+func getKeyManager() (KeyManager, error) {
+	keyManagerOnce.Do(func() {
+		keyManager, keyManagerErr = newLocalKeyManager(config.EncryptionMasterKeys)
+	})
+	return keyManager, keyManagerErr
+}
+
+// localKeyManager 使用配置中的主密钥加密数据密钥，第一个主密钥用于新内容，其余用于解密轮换前的内容
+type localKeyManager struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// newLocalKeyManager 解析逗号分隔的 id:base64 主密钥列表，每个主密钥为 32 字节
+func newLocalKeyManager(spec string) (*localKeyManager, error) {
+	km := &localKeyManager{keys: map[string]cipher.AEAD{}}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(item, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("主密钥格式错误，应为 id:base64: %s", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("主密钥 %s 应为 base64 编码的 32 字节", id)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		if km.current == "" {
+			km.current = id
+		}
+		km.keys[id] = aead
+	}
+	if km.current == "" {
+		return nil, errors.New("未配置主密钥，请设置 ENCRYPTION_MASTER_KEYS")
+	}
+	return km, nil
+}
+
+// WrapKey 以当前主密钥加密数据密钥，结果为 nonce 加密文
+func (k *localKeyManager) WrapKey(dataKey []byte) (string, []byte, error) {
+	aead := k.keys[k.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return k.current, aead.Seal(nonce, nonce, dataKey, []byte(k.current)), nil
+}
+
+// UnwrapKey 以 keyID 对应的主密钥解密数据密钥
+func (k *localKeyManager) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("主密钥不存在: %s", keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("数据密钥格式错误")
+	}
+	dataKey, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("解密数据密钥失败: %v", err)
+	}
+	return dataKey, nil
+}
+
+// newGCM 以 32 字节密钥创建 AES-256-GCM
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// newEnvelope 为新内容生成数据密钥和 nonce 前缀，返回加密信息和明文数据密钥
+func newEnvelope() (*Envelope, []byte, error) {
+	km, err := getKeyManager()
+	if err != nil {
+		return nil, nil, err
+	}
+	secret := make([]byte, 32+encPrefixSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+	dataKey, prefix := secret[:32], secret[32:]
+	keyID, wrapped, err := km.WrapKey(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &Envelope{
+		KeyID:   keyID,
+		DataKey: base64.StdEncoding.EncodeToString(wrapped),
+		Nonce:   base64.StdEncoding.EncodeToString(prefix),
+	}, dataKey, nil
+}
+
+// open 解密数据密钥，返回分块加密使用的 AEAD 和 nonce 前缀
+func (e *Envelope) open() (cipher.AEAD, []byte, error) {
+	km, err := getKeyManager()
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(e.DataKey)
+	if err != nil {
+		return nil, nil, errors.New("数据密钥格式错误")
+	}
+	prefix, err := base64.StdEncoding.DecodeString(e.Nonce)
+	if err != nil || len(prefix) != encPrefixSize {
+		return nil, nil, errors.New("nonce 格式错误")
+	}
+	dataKey, err := km.UnwrapKey(e.KeyID, wrapped)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, prefix, nil
+}
+
+// encryptFor 判断写入 profile 存储配置的新内容是否需要加密
+func encryptFor(profile string) bool {
+	if config.EncryptionEnabled {
+		return true
+	}
+	p := getStorageRouting().profile(profile)
+	return p != nil && p.Encrypt
+}
+
+// encChunks 返回 size 字节明文的分块数，空内容也有一个只含认证标签的块
+func encChunks(size int64) int64 {
+	if size <= 0 {
+		return 1
+	}
+	return (size + encChunkSize - 1) / encChunkSize
+}
+
+// encryptedSize 返回 size 字节明文加密后的大小
+func encryptedSize(size int64) int64 {
+	return max(size, 0) + encChunks(size)*encTagSize
+}
+
+// chunkNonce 返回第 index 块的 nonce
+func chunkNonce(prefix []byte, index int64) []byte {
+	nonce := make([]byte, encPrefixSize+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[encPrefixSize:], uint32(index))
+	return nonce
+}
+
+// chunkAAD 返回分块的附加数据，标记是否为最后一块
+func chunkAAD(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// encryptReader 以流的方式分块加密
+type encryptReader struct {
+	src    *bufio.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	index  int64
+	plain  []byte
+	out    []byte // 尚未读出的密文
+	done   bool
+}
+
+// newEncryptReader 返回读取 r 的明文并输出密文的 Reader
+func newEncryptReader(r io.Reader, aead cipher.AEAD, prefix []byte) io.Reader {
+	return &encryptReader{
+		src:    bufio.NewReaderSize(r, encChunkSize),
+		aead:   aead,
+		prefix: prefix,
+		plain:  make([]byte, encChunkSize),
+	}
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.src, r.plain)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, err
+		}
+		last := n < encChunkSize
+		if !last {
+			if _, err := r.src.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return 0, err
+			}
+		}
+		r.out = r.aead.Seal(r.out[:0], chunkNonce(r.prefix, r.index), r.plain[:n], chunkAAD(last))
+		r.index++
+		r.done = last
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// decryptReader 从第 index 块开始分块解密，解密完第 stop 块后结束；final 为整个内容最后一块的序号
+type decryptReader struct {
+	src    io.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	index  int64
+	stop   int64
+	final  int64
+	buf    []byte
+	out    []byte // 尚未读出的明文
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.index > r.stop {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.src, r.buf)
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			if r.index != r.final {
+				return 0, errors.New("密文不完整")
+			}
+		} else if err != nil {
+			return 0, err
+		}
+		plain, err := r.aead.Open(r.out[:0], chunkNonce(r.prefix, r.index), r.buf[:n], chunkAAD(r.index == r.final))
+		if err != nil {
+			return 0, fmt.Errorf("解密失败，第 %d 块: %v", r.index, err)
+		}
+		r.out = plain
+		r.index++
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// readCloser 将解密后的 Reader 与对象内容的 Closer 组合
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// getObjectRange 读取对象中从 offset 开始的 length 字节，length 小于 0 时读到末尾；
+// 云存储不支持按范围读取时读取全部内容并跳过 offset 之前的部分
+func getObjectRange(store ObjectStore, key string, offset int64, length int64) (io.ReadCloser, error) {
+	if offset == 0 && length < 0 {
+		return store.GetObject(key)
+	}
+	store = primaryStore(store)
+	if ranger, ok := store.(ObjectRangeReader); ok {
+		return ranger.GetObjectRange(key, offset, length)
+	}
+	body, err := store.GetObject(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, body, offset); err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to skip object content: %v", err)
+	}
+	if length < 0 {
+		return body, nil
+	}
+	return readCloser{Reader: io.LimitReader(body, length), Closer: body}, nil
+}
+
+// openContentRange 读取内容中从 offset 开始的 length 字节原内容，length 小于 0 时读到末尾；
+// 加密内容只下载并解密覆盖的块，压缩内容需读取并解压 offset 之前的全部数据
+func openContentRange(meta ContentMeta, offset int64, length int64) (io.ReadCloser, error) {
+	store, err := profileStore(meta.Profile)
+	if err != nil {
+		return nil, err
+	}
+	return readContentRange(store, meta, offset, length)
+}
+
+// readContentRange 从 store 读取内容的一段原内容，见 openContentRange
+func readContentRange(store ObjectStore, meta ContentMeta, offset int64, length int64) (io.ReadCloser, error) {
+	if meta.Encoding == "" {
+		return readPayloadRange(store, meta, offset, length)
+	}
+	body, err := readPayloadRange(store, meta, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	return decodeRange(body, meta.Encoding, offset, length)
+}
+
+// openPayload 读取解压前的存储内容，加密内容解密后返回，调用方负责关闭
+func openPayload(meta ContentMeta) (io.ReadCloser, error) {
+	store, err := profileStore(meta.Profile)
+	if err != nil {
+		return nil, err
+	}
+	return readPayloadRange(store, meta, 0, -1)
+}
+
+// readPayloadRange 从 store 读取加密前存储内容的一段，即压缩内容解压前的数据
+func readPayloadRange(store ObjectStore, meta ContentMeta, offset int64, length int64) (io.ReadCloser, error) {
+	if meta.Envelope == nil {
+		return getObjectRange(store, meta.Key, offset, length)
+	}
+	aead, prefix, err := meta.Envelope.open()
+	if err != nil {
+		return nil, err
+	}
+	size := meta.payloadSize()
+	if length < 0 || offset+length > size {
+		length = max(size-offset, 0)
+	}
+	first := offset / encChunkSize
+	stop := first
+	if length > 0 {
+		stop = (offset + length - 1) / encChunkSize
+	}
+	start := first * (encChunkSize + encTagSize)
+	end := min((stop+1)*(encChunkSize+encTagSize), encryptedSize(size))
+	body, err := getObjectRange(store, meta.Key, start, end-start)
+	if err != nil {
+		return nil, err
+	}
+	plain := &decryptReader{
+		src:    body,
+		aead:   aead,
+		prefix: prefix,
+		index:  first,
+		stop:   stop,
+		final:  encChunks(size) - 1,
+		buf:    make([]byte, encChunkSize+encTagSize),
+	}
+	if _, err := io.CopyN(io.Discard, plain, offset-first*encChunkSize); err != nil {
+		body.Close()
+		return nil, err
+	}
+	return readCloser{Reader: io.LimitReader(plain, length), Closer: body}, nil
+}
+
+// openContent 读取内容的全部原内容，调用方负责关闭
+func openContent(meta ContentMeta) (io.ReadCloser, error) {
+	return openContentRange(meta, 0, -1)
+}
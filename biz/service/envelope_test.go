@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"testing"
+)
+
+// encryptForTest 以新的数据密钥加密 data 并写入 store，返回对应的存储元数据
+func encryptForTest(t *testing.T, store *memStore, key string, data []byte) ContentMeta {
+	t.Helper()
+	envelope, dataKey, err := newEnvelope()
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, _ := newGCM(dataKey)
+	prefix, _ := base64.StdEncoding.DecodeString(envelope.Nonce)
+	encrypted, err := io.ReadAll(newEncryptReader(bytes.NewReader(data), aead, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(encrypted)) != encryptedSize(int64(len(data))) {
+		t.Fatalf("密文大小 = %d, want %d", len(encrypted), encryptedSize(int64(len(data))))
+	}
+	store.objects[key] = encrypted
+	return ContentMeta{Key: key, Size: int64(len(data)), Envelope: envelope}
+}
+
+func readRange(t *testing.T, store ObjectStore, meta ContentMeta, offset, length int64) ([]byte, error) {
+	t.Helper()
+	body, err := readContentRange(store, meta, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func Test_envelope_roundTrip(t *testing.T) {
+	master := make([]byte, 32)
+	rand.Read(master)
+	km, err := newLocalKeyManager("k1:" + base64.StdEncoding.EncodeToString(master))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetKeyManager(km)
+
+	store := newMemStore()
+	for _, size := range []int{0, 1, encChunkSize - 1, encChunkSize, encChunkSize + 1, 3*encChunkSize + 5} {
+		data := make([]byte, size)
+		rand.Read(data)
+		meta := encryptForTest(t, store, "blob", data)
+
+		got, err := readRange(t, store, meta, 0, -1)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Fatalf("size %d: 解密全部内容失败: %v", size, err)
+		}
+		if size == 0 {
+			continue
+		}
+		ranges := [][2]int64{{0, 1}, {int64(size) - 1, 1}, {int64(size) / 2, int64(size) / 3}, {1, -1}}
+		if size > encChunkSize+2 {
+			// 跨越分块边界
+			ranges = append(ranges, [2]int64{encChunkSize - 2, 4})
+		}
+		for _, r := range ranges {
+			want := data[r[0]:]
+			if r[1] >= 0 {
+				want = want[:r[1]]
+			}
+			got, err := readRange(t, store, meta, r[0], r[1])
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("size %d: range(%d, %d) = %d 字节, %v, want %d 字节", size, r[0], r[1], len(got), err, len(want))
+			}
+		}
+	}
+}
+
+func Test_envelope_tamper(t *testing.T) {
+	master := make([]byte, 32)
+	rand.Read(master)
+	km, _ := newLocalKeyManager("k1:" + base64.StdEncoding.EncodeToString(master))
+	SetKeyManager(km)
+
+	store := newMemStore()
+	data := bytes.Repeat([]byte("cloudisk"), encChunkSize/4)
+	meta := encryptForTest(t, store, "blob", data)
+	encrypted := store.objects["blob"]
+
+	store.objects["blob"] = encrypted[:encChunkSize+encTagSize]
+	if _, err := readRange(t, store, meta, 0, -1); err == nil {
+		t.Error("截断到分块边界的密文应解密失败")
+	}
+
+	modified := bytes.Clone(encrypted)
+	modified[10] ^= 1
+	store.objects["blob"] = modified
+	if _, err := readRange(t, store, meta, 0, 100); err == nil {
+		t.Error("篡改的密文应解密失败")
+	}
+
+	// 轮换主密钥后仍可解密旧内容
+	other := make([]byte, 32)
+	rand.Read(other)
+	rotated, _ := newLocalKeyManager("k2:" + base64.StdEncoding.EncodeToString(other) + ",k1:" + base64.StdEncoding.EncodeToString(master))
+	SetKeyManager(rotated)
+	store.objects["blob"] = encrypted
+	if got, err := readRange(t, store, meta, 0, -1); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("轮换主密钥后解密失败: %v", err)
+	}
+	if _, err := newLocalKeyManager("k1:short"); err == nil {
+		t.Error("长度错误的主密钥应报错")
+	}
+}
+
+func Test_ParseByteRange(t *testing.T) {
+	cases := []struct {
+		header         string
+		offset, length int64
+		ok             bool
+	}{
+		{"", 0, -1, true},
+		{"bytes=0-99", 0, 100, true},
+		{"bytes=100-", 100, 900, true},
+		{"bytes=-100", 900, 100, true},
+		{"bytes=-5000", 0, 1000, true},
+		{"bytes=990-2000", 990, 10, true},
+		{"bytes=0-1,5-6", 0, -1, true},
+		{"items=0-1", 0, -1, true},
+		{"bytes=1000-", 0, 0, false},
+		{"bytes=5-1", 0, 0, false},
+		{"bytes=-0", 0, 0, false},
+	}
+	for _, c := range cases {
+		offset, length, err := ParseByteRange(c.header, 1000)
+		if (err == nil) != c.ok || (c.ok && (offset != c.offset || length != c.length)) {
+			t.Errorf("ParseByteRange(%q) = %d, %d, %v, want %d, %d", c.header, offset, length, err, c.offset, c.length)
+		}
+	}
+}
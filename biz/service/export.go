@@ -0,0 +1,281 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/common"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"golang.org/x/net/html"
+)
+
+// ErrExportUnsupported 文件类型或导出格式不支持
+var ErrExportUnsupported = errors.New("该文件不支持导出为此格式")
+
+// Export 导出的文件
+type Export struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// markdown 渲染器：GFM（表格、任务列表、删除线、自动链接），代码块使用内联样式高亮，便于单独保存的 HTML 和 PDF 保持样式；
+// 不启用 WithUnsafe，文档中的原始 HTML 不会输出
+var markdown = goldmark.New(
+	goldmark.WithExtensions(
+		extension.GFM,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle("github"),
+			highlighting.WithFormatOptions(chromahtml.WithLineNumbers(false)),
+		),
+	),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+)
+
+var exportTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { max-width: 860px; margin: 40px auto; padding: 0 20px; color: #24292f; font: 15px/1.7 -apple-system, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; }
+h1, h2 { border-bottom: 1px solid #d8dee4; padding-bottom: .3em; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #d0d7de; padding: 6px 13px; }
+tr:nth-child(2n) { background: #f6f8fa; }
+code { background: #f6f8fa; padding: .2em .4em; border-radius: 4px; font: 13px Menlo, Consolas, monospace; }
+pre { padding: 12px 16px; overflow: auto; border-radius: 6px; background: #f6f8fa; }
+pre code { padding: 0; background: none; }
+blockquote { margin: 0; padding: 0 1em; color: #57606a; border-left: .25em solid #d0d7de; }
+img { max-width: 100%; }
+</style>
+</head>
+<body>
+{{.Body}}
+</body>
+</html>
+`))
+
+// renderDocument 将文档内容渲染为完整的 HTML 页面，md 按 Markdown 渲染，纯文本原样显示
+func renderDocument(title string, ext string, source []byte) ([]byte, error) {
+	var body bytes.Buffer
+	if ext == "md" {
+		if err := markdown.Convert(source, &body); err != nil {
+			return nil, fmt.Errorf("渲染 Markdown 失败: %v", err)
+		}
+	} else {
+		body.WriteString("<pre>")
+		template.HTMLEscape(&body, source)
+		body.WriteString("</pre>")
+	}
+
+	var page bytes.Buffer
+	err := exportTemplate.Execute(&page, map[string]any{"Title": title, "Body": template.HTML(body.String())})
+	if err != nil {
+		return nil, fmt.Errorf("渲染 HTML 失败: %v", err)
+	}
+	return page.Bytes(), nil
+}
+
+// htmlToPdf 调用 Gotenberg 的 Chromium 转换接口将 HTML 转为 PDF
+func htmlToPdf(page []byte) ([]byte, error) {
+	if config.ExportPdfUrl == "" {
+		return nil, errors.New("未配置 PDF 转换服务")
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("files", "index.html")
+	if err != nil {
+		return nil, err
+	}
+	part.Write(stripExternalResources(page))
+	writer.Close()
+
+	url := strings.TrimRight(config.ExportPdfUrl, "/") + "/forms/chromium/convert/html"
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Post(url, writer.FormDataContentType(), &body)
+	if err != nil {
+		return nil, fmt.Errorf("PDF 转换失败: %v", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("PDF 转换失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("PDF 转换失败: %d %s", resp.StatusCode, capText(string(data), 512))
+	}
+	return data, nil
+}
+
+// resourceAttrs 浏览器渲染时会自动加载的资源属性
+var resourceAttrs = map[string]bool{"src": true, "srcset": true, "poster": true, "data": true, "background": true}
+
+// stripExternalResources 去掉页面中引用外部地址的图片等资源，只保留 data: 内联的内容；
+// PDF 转换服务在内网中渲染页面，文档中的图片地址可能指向内网服务（SSRF）
+func stripExternalResources(page []byte) []byte {
+	var out bytes.Buffer
+	z := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			out.Write(z.Raw())
+			continue
+		}
+		raw := append([]byte(nil), z.Raw()...)
+		token := z.Token()
+		attrs := token.Attr[:0]
+		for _, attr := range token.Attr {
+			external := resourceAttrs[attr.Key] || (token.Data == "link" && attr.Key == "href")
+			if external && !strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Val)), "data:") {
+				continue
+			}
+			attrs = append(attrs, attr)
+		}
+		if len(attrs) == len(token.Attr) {
+			out.Write(raw)
+			continue
+		}
+		token.Attr = attrs
+		out.WriteString(token.String())
+	}
+	return out.Bytes()
+}
+
+// readFileObject 读取文件最新内容，超过 limit 字节时返回错误
+func readFileObject(file *gorm_gen.File, limit int64) ([]byte, error) {
+	if file.Size > limit {
+		return nil, fmt.Errorf("文件过大: %s", formatSize(file.Size))
+	}
+	meta := GetContentMeta(file)
+	body, err := openContent(meta)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("文件过大: 超过%s", formatSize(limit))
+	}
+	return data, nil
+}
+
+// ExportFile 导出文件：文档（document 类型的 md、text）导出为 html 或 pdf，drawio 和思维导图导出为 svg 或 png
+func ExportFile(user *User, id int64, format string) (*Export, error) {
+	file, err := permissionFind(int(id), user, 0)
+	if err != nil {
+		return nil, err
+	}
+	format = strings.ToLower(format)
+	switch file.Type {
+	case "document":
+		return exportDocument(file, format)
+	case "drawio", "mind":
+		return exportDiagram(file, format)
+	}
+	return nil, ErrExportUnsupported
+}
+
+// exportDocument 将文档渲染为 HTML，需要时再转为 PDF
+func exportDocument(file *gorm_gen.File, format string) (*Export, error) {
+	if format != "" && format != "html" && format != "pdf" {
+		return nil, ErrExportUnsupported
+	}
+	source, err := readFileObject(file, config.ExportMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	page, err := renderDocument(file.Name, file.Ext, source)
+	if err != nil {
+		return nil, err
+	}
+	if format == "pdf" {
+		data, err := htmlToPdf(page)
+		if err != nil {
+			return nil, err
+		}
+		return &Export{FileName: file.Name + ".pdf", ContentType: "application/pdf", Data: data}, nil
+	}
+	return &Export{FileName: file.Name + ".html", ContentType: "text/html; charset=utf-8", Data: page}, nil
+}
+
+// exportDiagram 将 drawio 图形（第一页）或思维导图渲染为 SVG 或 PNG
+func exportDiagram(file *gorm_gen.File, format string) (*Export, error) {
+	if format != "" && format != "svg" && format != "png" {
+		return nil, ErrExportUnsupported
+	}
+	source, err := readFileObject(file, config.ExportMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	var d *diagram
+	if file.Type == "drawio" {
+		d, err = parseDrawio(source)
+	} else {
+		d, err = parseMind(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if format == "png" {
+		data, err := renderPNG(d, float64(config.ExportPngScale))
+		if err != nil {
+			return nil, err
+		}
+		return &Export{FileName: file.Name + ".png", ContentType: "image/png", Data: data}, nil
+	}
+	return &Export{FileName: file.Name + ".svg", ContentType: "image/svg+xml", Data: renderSVG(d)}, nil
+}
+
+// SaveExport 导出文件并保存到原文件所在的文件夹，按普通上传登记内容、配额和策略，重名时自动改名
+func SaveExport(user *User, id int64, format string) (*common.File, error) {
+	export, err := ExportFile(user, id, format)
+	if err != nil {
+		return nil, err
+	}
+	file, err := query.Q.File.Where(query.File.ID.Eq(id)).First()
+	if err != nil {
+		return nil, errors.New("文件不存在或已被删除")
+	}
+	return saveSibling(user, file, export)
+}
+
+// saveSibling 将生成的文件保存到 file 所在的文件夹
+func saveSibling(user *User, file *gorm_gen.File, export *Export) (*common.File, error) {
+	user_id, current_pid, err := prepareUploadFolder(user, int(file.Pid), "")
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(export.Data))
+	if err := checkFile(export.FileName, size); err != nil {
+		return nil, err
+	}
+	if err := checkQuota(ownerOf(user_id, current_pid), size); err != nil {
+		return nil, err
+	}
+	blob, err := putBlob(routeProfile(user_id, current_pid, export.FileName), getFileType(export.FileName), bytes.NewReader(export.Data), "")
+	if err != nil {
+		return nil, err
+	}
+	return createUploadRecord(user, user_id, current_pid, export.FileName, "", false, blob)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudisk/pkg/config"
+)
+
+func Test_RenderDocument(t *testing.T) {
+	source := "# 标题\n\n| 名称 | 大小 |\n| --- | --- |\n| a.txt | 1KB |\n\n```go\nfunc main() {}\n```\n\n<script>alert(1)</script>\n"
+	page, err := renderDocument("说明", "md", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(page)
+	for _, want := range []string{"<title>说明</title>", `<h1 id=`, "<table>", "<td>a.txt</td>", `<pre tabindex="0" style=`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("rendered html missing %q", want)
+		}
+	}
+	if strings.Contains(html, "<script>") {
+		t.Error("raw html should not be rendered")
+	}
+
+	page, _ = renderDocument("纯文本", "text", []byte("a < b"))
+	if !strings.Contains(string(page), "<pre>a &lt; b</pre>") {
+		t.Errorf("plain text should be escaped, got %s", page)
+	}
+}
+
+func Test_HtmlToPdf(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("files")
+		if r.URL.Path != "/forms/chromium/convert/html" || err != nil || header.Filename != "index.html" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		if data, _ := io.ReadAll(file); strings.Contains(string(data), "http://internal") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("%PDF-1.4"))
+	}))
+	defer server.Close()
+
+	old := config.ExportPdfUrl
+	config.ExportPdfUrl = server.URL
+	defer func() { config.ExportPdfUrl = old }()
+
+	data, err := htmlToPdf([]byte(`<html><img src="http://internal/secret"></html>`))
+	if err != nil || string(data) != "%PDF-1.4" {
+		t.Errorf("unexpected pdf %q, %v", data, err)
+	}
+}
+
+func Test_stripExternalResources(t *testing.T) {
+	source := "![内网](http://169.254.169.254/latest/meta-data) ![内联](data:image/png;base64,iVBORw0KGgo=) [链接](http://example.com)\n"
+	page, err := renderDocument("说明", "md", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(stripExternalResources(page))
+	if strings.Contains(html, "169.254.169.254") {
+		t.Errorf("external image should be stripped, got %s", html)
+	}
+	for _, want := range []string{`src="data:image/png;base64,iVBORw0KGgo="`, `alt="内网"`, `href="http://example.com"`, "<title>说明</title>", "img { max-width: 100%; }"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("stripped html missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
+	"github.com/ledongthuc/pdf"
+	"gorm.io/gen"
+)
+
+// ErrExtractUnsupported 文件类型不支持全文提取
+var ErrExtractUnsupported = errors.New("该文件类型不支持全文提取")
+
+// extractable 判断文件类型和扩展名是否支持全文提取，旧版 doc/xls/ppt 二进制格式不支持
+func extractable(fileType string, ext string) bool {
+	switch fileType {
+	case "word", "excel", "ppt":
+		return strings.HasSuffix(strings.ToLower(ext), "x")
+	case "pdf", "code", "txt", "document":
+		return true
+	}
+	return false
+}
+
+// enqueueExtract 为文件内容记录登记全文提取任务，由后台任务协程异步处理
+func enqueueExtract(file *gorm_gen.File, contentID int64) {
+	if !extractable(file.Type, file.Ext) || contentID == 0 {
+		return
+	}
+	if _, err := enqueueJob(0, JobExtract, extractJob{ContentID: contentID}); err != nil {
+		log.Printf("登记全文提取任务失败, 内容ID: %d, 错误: %v", contentID, err)
+	}
+}
+
+// ExtractContent 提取文件内容记录对应对象的纯文本，写入 Text 字段
+func ExtractContent(contentID int64) error {
+	fileContent, err := query.Q.FileContent.Where(query.FileContent.ID.Eq(contentID)).First()
+	if err != nil {
+		return fmt.Errorf("文件内容不存在: %v", err)
+	}
+	file, err := query.Q.File.Where(query.File.ID.Eq(fileContent.Fid)).First()
+	if err != nil {
+		return fmt.Errorf("文件不存在: %v", err)
+	}
+	if !extractable(file.Type, file.Ext) {
+		return ErrExtractUnsupported
+	}
+	if fileContent.Size > config.ExtractMaxFileSize {
+		return fmt.Errorf("文件过大，跳过全文提取: %s", formatSize(fileContent.Size))
+	}
+
+	meta := parseContentMeta(fileContent)
+	if meta.Key == "" {
+		meta.Key = GetFilePath(file)
+	}
+	body, err := openContent(meta)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %v", err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(io.LimitReader(body, config.ExtractMaxFileSize))
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %v", err)
+	}
+
+	text, err := extractText(file.Type, data)
+	if err != nil {
+		return err
+	}
+	// 只更新 text，不改变 updated_at，避免影响最新版本的判断
+	_, err = query.Q.FileContent.Where(query.FileContent.ID.Eq(contentID)).
+		UpdateColumn(query.FileContent.Text, capText(text, config.ExtractMaxText))
+	if err != nil {
+		return err
+	}
+	indexFiles(file.ID)
+	return nil
+}
+
+// ExtractFiles 为支持全文提取且最新内容尚无文本的文件补充提取，用于存量数据
+func ExtractFiles(fn func(fileID int64, err error)) error {
+	var files []*gorm_gen.File
+	return query.Q.File.Where(query.File.Type.In("word", "excel", "ppt", "pdf", "code", "txt", "document")).
+		FindInBatches(&files, 100, func(tx gen.Dao, batch int) error {
+			for _, file := range files {
+				if !extractable(file.Type, file.Ext) {
+					continue
+				}
+				fileContent, err := query.Q.FileContent.Where(query.FileContent.Fid.Eq(file.ID)).
+					Order(query.FileContent.ID.Desc()).First()
+				if err != nil || fileContent.Text != "" {
+					continue
+				}
+				fn(file.ID, ExtractContent(fileContent.ID))
+			}
+			return nil
+		})
+}
+
+// extractText 按文件类型提取纯文本
+func extractText(fileType string, data []byte) (string, error) {
+	switch fileType {
+	case "word":
+		return ooxmlText(data, "word/document.xml", "word/header*.xml", "word/footer*.xml", "word/footnotes.xml")
+	case "excel":
+		return ooxmlText(data, "xl/sharedStrings.xml", "xl/worksheets/sheet*.xml")
+	case "ppt":
+		return ooxmlText(data, "ppt/slides/slide*.xml", "ppt/notesSlides/notesSlide*.xml")
+	case "pdf":
+		return pdfText(data)
+	case "code", "txt", "document":
+		return strings.ToValidUTF8(string(data), ""), nil
+	}
+	return "", ErrExtractUnsupported
+}
+
+// ooxmlText 读取 OOXML 压缩包中匹配的 xml 部件，收集 <t> 元素中的文字，段落和单元格以换行分隔
+func ooxmlText(data []byte, patterns ...string) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("解析文档失败: %v", err)
+	}
+
+	var parts []*zip.File
+	for _, pattern := range patterns {
+		var matched []*zip.File
+		for _, f := range zr.File {
+			if ok, _ := path.Match(pattern, f.Name); ok {
+				matched = append(matched, f)
+			}
+		}
+		// slide10 排在 slide2 之后
+		sort.Slice(matched, func(i, j int) bool {
+			if len(matched[i].Name) != len(matched[j].Name) {
+				return len(matched[i].Name) < len(matched[j].Name)
+			}
+			return matched[i].Name < matched[j].Name
+		})
+		parts = append(parts, matched...)
+	}
+
+	var sb strings.Builder
+	for _, part := range parts {
+		if int64(sb.Len()) > config.ExtractMaxText {
+			break
+		}
+		rc, err := part.Open()
+		if err != nil {
+			return "", fmt.Errorf("解析文档失败: %v", err)
+		}
+		err = xmlText(io.LimitReader(rc, config.ExtractMaxFileSize), &sb)
+		rc.Close()
+		if err != nil {
+			return "", fmt.Errorf("解析文档失败: %v", err)
+		}
+	}
+	return sb.String(), nil
+}
+
+// xmlText 收集 xml 中 <t> 元素（w:t、a:t 等）的文字，在段落、行、共享字符串结束时换行
+func xmlText(r io.Reader, sb *strings.Builder) error {
+	decoder := xml.NewDecoder(r)
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteString("\t")
+			case "br":
+				sb.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p", "si", "row":
+				sb.WriteString("\n")
+			case "c":
+				sb.WriteString("\t")
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+// pdfText 提取 PDF 的文字，解析库遇到异常文件时可能 panic
+func pdfText(data []byte) (text string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("解析PDF失败: %v", r)
+		}
+	}()
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("解析PDF失败: %v", err)
+	}
+	plain, err := reader.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("解析PDF失败: %v", err)
+	}
+	raw, err := io.ReadAll(io.LimitReader(plain, config.ExtractMaxText+utf8.UTFMax))
+	if err != nil {
+		return "", fmt.Errorf("解析PDF失败: %v", err)
+	}
+	return strings.ToValidUTF8(string(raw), ""), nil
+}
+
+// capText 将文本截断到 limit 字节以内，不截断多字节字符
+func capText(text string, limit int64) string {
+	if limit <= 0 || int64(len(text)) <= limit {
+		return text
+	}
+	text = text[:limit]
+	for len(text) > 0 && !utf8.ValidString(text) {
+		text = text[:len(text)-1]
+	}
+	return text
+}
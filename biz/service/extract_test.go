@@ -0,0 +1,44 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_OoxmlText(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	parts := map[string]string{
+		"ppt/slides/slide2.xml":  `<p:sld xmlns:a="a" xmlns:p="p"><a:p><a:r><a:t>第二页</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/slide10.xml": `<p:sld xmlns:a="a" xmlns:p="p"><a:p><a:r><a:t>第十页</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/slide1.xml":  `<p:sld xmlns:a="a" xmlns:p="p"><a:p><a:r><a:t>Hello</a:t></a:r><a:r><a:t> World</a:t></a:r></a:p></p:sld>`,
+		"ppt/presentation.xml":   `<p:presentation xmlns:p="p"><a:t xmlns:a="a">ignored</a:t></p:presentation>`,
+	}
+	for name, content := range parts {
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+
+	text, err := extractText("ppt", buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Hello World\n第二页\n第十页\n" {
+		t.Errorf("unexpected text %q", text)
+	}
+}
+
+func Test_CapText(t *testing.T) {
+	if got := capText("中文abc", 4); got != "中" {
+		t.Errorf("capText should not split runes, got %q", got)
+	}
+	if got := capText(strings.Repeat("a", 10), 0); len(got) != 10 {
+		t.Errorf("zero limit should not truncate")
+	}
+	if !extractable("word", "docx") || extractable("word", "doc") || extractable("picture", "png") {
+		t.Errorf("unexpected extractable result")
+	}
+}
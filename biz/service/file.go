@@ -1,11 +1,14 @@
 package service
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -19,15 +22,149 @@ import (
 
 	"github.com/cloudisk/biz/dal/query"
 	"github.com/cloudisk/biz/model/common"
+	"github.com/cloudisk/biz/model/entity"
 	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
 )
 
+// ObjectStore 按对象路径读写的存储接口，各云存储均实现，用于迁移等跨存储操作。
+// 各实现的语义一致：PutObject 覆盖已有对象并返回写入后的大小；对象不存在时 GetObject 返回 ErrObjectNotFound；
+// 归档对象未恢复时 GetObject 返回 ErrObjectArchived；Delete 删除不存在的对象不报错
+type ObjectStore interface {
+	PutObject(file io.Reader, objectName string, opts PutOptions) (ContentLength int64, err error)
+	GetObject(objectName string) (io.ReadCloser, error)
+	Delete(objectName string) error
+}
+
+// ErrObjectNotFound 云存储中不存在该对象
+var ErrObjectNotFound = errors.New("对象不存在")
+
+// ErrObjectArchived 对象已归档，需要先恢复才能读取
+var ErrObjectArchived = errors.New("对象已归档")
+
+// ObjectInfo 云存储中的对象
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	StorageClass string // 统一后的存储类型，见 storageClassName
+}
+
+// 各云存储共有的存储类型
+const (
+	StorageStandard    = "standard"
+	StorageIA          = "ia"
+	StorageArchive     = "archive"
+	StorageColdArchive = "cold_archive"
+	StorageDeepArchive = "deep_archive"
+)
+
+// storageClassNames 各云存储返回的存储类型名称（小写）与统一名称的对应关系
+var storageClassNames = map[string]string{
+	"":                StorageStandard,
+	"standard":        StorageStandard,
+	"maz_standard":    StorageStandard,
+	"ia":              StorageIA,
+	"standard_ia":     StorageIA,
+	"maz_standard_ia": StorageIA,
+	"archive":         StorageArchive,
+	"coldarchive":     StorageColdArchive,
+	"deep_archive":    StorageDeepArchive,
+	"deepcoldarchive": StorageDeepArchive,
+}
+
+// storageClassName 将 OSS、COS 返回的存储类型统一为小写名称，无法识别的类型原样转为小写
+func storageClassName(raw string) string {
+	if name, ok := storageClassNames[strings.ToLower(raw)]; ok {
+		return name
+	}
+	return strings.ToLower(raw)
+}
+
+// needsRestore 判断该存储类型的对象是否需要先恢复才能读取
+func needsRestore(class string) bool {
+	return class == StorageArchive || class == StorageColdArchive || class == StorageDeepArchive
+}
+
+// ObjectTierer 支持转换存储类型和恢复归档对象的云存储，class 为统一后的存储类型名称
+type ObjectTierer interface {
+	SetStorageClass(objectName string, class string) error
+	// Restore 发起归档对象的恢复，恢复后的副本保留 days 天；已在恢复中时不报错
+	Restore(objectName string, days int) error
+	// Restored 判断已发起的恢复是否完成
+	Restored(objectName string) (bool, error)
+}
+
+// ObjectRangeReader 支持按字节范围读取对象的云存储，length 小于 0 时读到末尾
+type ObjectRangeReader interface {
+	GetObjectRange(objectName string, offset int64, length int64) (io.ReadCloser, error)
+}
+
+// httpRange 返回 HTTP Range 请求头的值
+func httpRange(offset int64, length int64) string {
+	if length < 0 {
+		return fmt.Sprintf("bytes=%d-", offset)
+	}
+	return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+}
+
+// ObjectLister 分页列出存储桶中的对象，marker 为上一页返回的起点，没有下一页时返回空字符串
+type ObjectLister interface {
+	List(prefix, marker string, limit int) ([]ObjectInfo, string, error)
+}
+
+// listAll 逐页列出 prefix 下的全部对象
+func listAll(lister ObjectLister, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	marker := ""
+	for {
+		page, next, err := lister.List(prefix, marker, 1000)
+		if err != nil {
+			return objects, err
+		}
+		objects = append(objects, page...)
+		if next == "" {
+			return objects, nil
+		}
+		marker = next
+	}
+}
+
 // CloudUploader 定义统一的云存储上传接口
 type CloudUploader interface {
+	ObjectStore
+	ObjectLister
 	Upload(file multipart.File, objectName string, pid int64) (ContentLength int64, err error)
 	ReaderUpload(file io.ReadCloser, objectName string) (ContentLength int64, err error)
 }
 
+// uploadPath 返回 Upload 写入的对象路径：已包含路径分隔符（webkitRelativePath）时直接使用，否则按 pid 的文件夹层级拼接
+func uploadPath(objectName string, pid int64) string {
+	if strings.Contains(objectName, "/") {
+		return objectName
+	}
+	paths := []string{}
+	for currentPid := pid; currentPid > 0; {
+		parentFile, err := query.Q.File.Where(query.File.ID.Eq(currentPid)).First()
+		if err != nil {
+			log.Printf("找不到父文件夹，ID: %d, 错误: %v", currentPid, err)
+			break
+		}
+		if parentFile.Type == "folder" {
+			paths = append([]string{parentFile.Name}, paths...)
+		}
+		currentPid = parentFile.Pid
+	}
+	return strings.Join(append(paths, objectName), "/")
+}
+
+// PutOptions 上传对象时的附加参数
+type PutOptions struct {
+	ContentMD5 string // base64 编码的 MD5，由云端校验上传内容
+	CRC64      string // CRC64-ECMA 十进制字符串，与云端返回值比对
+}
+
 var (
 	alioss            *OssUploader   = NewOssUploader()
 	cosUploader       *CosUploader   = NewCosUploader()
@@ -35,19 +172,44 @@ var (
 	folderCreateMutex sync.Mutex
 )
 
-// getCloudUploader 根据环境变量返回对应的云存储上传器
+// legacyProvider 未记录存储配置的内容所在的云存储，此前的内容均写入阿里云OSS
+const legacyProvider = "aliyun"
+
+// getCloudUploader 返回 CLOUD_PROVIDER 对应的默认云存储上传器
 func getCloudUploader() CloudUploader {
-	cloudProvider := os.Getenv("CLOUD_PROVIDER")
-	switch cloudProvider {
+	return withReplicas(defaultProvider(), providerUploader(defaultProvider()))
+}
+
+// defaultProvider 返回新内容写入的默认云存储，CLOUD_PROVIDER 无效时使用阿里云OSS
+func defaultProvider() string {
+	if _, err := getObjectStore(config.CloudProvider); err != nil {
+		return legacyProvider
+	}
+	return config.CloudProvider
+}
+
+// providerUploader 返回指定名称的云存储上传器，名称无效时返回阿里云OSS
+func providerUploader(provider string) CloudUploader {
+	switch provider {
+	case "tencent":
+		return cosUploader
+	case "qiniu":
+		return qiniuUploader
+	}
+	return alioss
+}
+
+// getObjectStore 返回指定名称的云存储：aliyun、tencent、qiniu
+func getObjectStore(provider string) (ObjectStore, error) {
+	switch provider {
 	case "aliyun":
-		return alioss
-	// case "tencent":
-	// return cosUploader
-	// case "qiniu":
-	// return qiniuUploader
-	default:
-		return alioss // 默认使用阿里云OSS
+		return alioss, nil
+	case "tencent":
+		return cosUploader, nil
+	case "qiniu":
+		return qiniuUploader, nil
 	}
+	return nil, fmt.Errorf("不支持的云存储: %s", provider)
 }
 
 func IsContainInt(items []int64, item int64) bool {
@@ -81,7 +243,7 @@ func getShareInfo(file *gorm_gen.File) (*gorm_gen.File, error) {
 	}
 	pid := file.Pid
 	for pid > 0 {
-		result, err := query.Q.File.Where(query.File.Pid.Eq(int64(pid))).First()
+		result, err := query.Q.File.Where(query.File.ID.Eq(int64(pid))).First()
 		if err != nil {
 			return nil, err
 		}
@@ -100,7 +262,7 @@ func getPermission(file *gorm_gen.File, userids []int64) int {
 		return 1000
 	}
 	row, err := getShareInfo(file)
-	if err != nil {
+	if err != nil || row == nil {
 		return -1
 	}
 	fileUser, err := query.Q.File_User.Where(query.File_User.FileID.Eq(row.ID)).Where(query.File_User.Userid.In(userids...)).Order(query.File_User.Permission).First()
@@ -135,42 +297,38 @@ func permissionFind(id int, user *User, limit int) (*gorm_gen.File, error) {
 	return file, err
 }
 
-func saveBeforePP(f *gorm_gen.File) bool {
-	if f == nil {
-		return false
-	}
-
-	var pid int64 = f.Pid
-	var pshare int64 = 0
+// fillParents 按文件夹层级设置 Pids（如 ,3,7,）和 Pshare（最外层的共享文件夹），与 DooTask 保存文件前的处理一致；
+// 本服务创建文件和文件夹时都需调用，否则共享成员搜索不到且路径显示为根目录
+func fillParents(q *query.Query, f *gorm_gen.File) {
+	var pshare int64
 	if f.Share > 0 {
 		pshare = f.ID
 	}
-
-	var parentIds []int64
-	for pid > 0 {
-		parentIds = append(parentIds, pid)
-		file, err := query.Q.File.Where(query.File.ID.Eq(pid)).First()
+	var parentIds []string
+	for pid := f.Pid; pid > 0; {
+		parentIds = append([]string{strconv.FormatInt(pid, 10)}, parentIds...)
+		parent, err := q.File.Where(query.File.ID.Eq(pid)).First()
 		if err != nil {
-			// 如果找不到父文件夹，就停止查找，但不影响当前文件的保存
+			// 找不到上级文件夹时停止查找，不影响当前文件的保存
 			break
 		}
-		pid = file.Pid
-		if file.Share > 0 {
-			pshare = file.ID
+		if parent.Share > 0 {
+			pshare = parent.ID
 		}
+		pid = parent.Pid
 	}
-
-	// 设置文件的 Pids
+	f.Pids = ""
 	if len(parentIds) > 0 {
-		// 反转数组以获得正确的路径顺序
-		for i, j := 0, len(parentIds)-1; i < j; i, j = i+1, j-1 {
-			parentIds[i], parentIds[j] = parentIds[j], parentIds[i]
-		}
-		f.Pids = "," + fmt.Sprintf("%v", parentIds) + ","
-	} else {
-		f.Pids = ""
+		f.Pids = "," + strings.Join(parentIds, ",") + ","
 	}
 	f.Pshare = pshare
+}
+
+func saveBeforePP(f *gorm_gen.File) bool {
+	if f == nil {
+		return false
+	}
+	fillParents(query.Q, f)
 
 	// 保存文件记录
 	err := query.Q.File.Create(f)
@@ -182,8 +340,13 @@ func saveBeforePP(f *gorm_gen.File) bool {
 }
 
 func HandleDuplicateName(f *gorm_gen.File) error {
+	return handleDuplicateName(query.Q, f)
+}
+
+// handleDuplicateName 与 HandleDuplicateName 相同，在事务 q 中查询同名文件
+func handleDuplicateName(q *query.Query, f *gorm_gen.File) error {
 	// 检查是否存在同名文件
-	count, err := query.Q.File.Where(query.File.Pid.Eq(f.Pid),
+	count, err := q.File.Where(query.File.Pid.Eq(f.Pid),
 		query.File.Userid.Eq(f.Userid),
 		query.File.Ext.Eq(f.Ext),
 		query.File.Name.Eq(f.Name)).Count()
@@ -206,7 +369,7 @@ func HandleDuplicateName(f *gorm_gen.File) error {
 
 	// 查找所有相关文件名的最大编号
 	var files []*gorm_gen.File
-	err = query.Q.File.Where(query.File.Pid.Eq(f.Pid),
+	err = q.File.Where(query.File.Pid.Eq(f.Pid),
 		query.File.Userid.Eq(f.Userid),
 		query.File.Ext.Eq(f.Ext),
 		query.File.Name.Like(baseName+"%")).Scan(&files)
@@ -234,7 +397,7 @@ func HandleDuplicateName(f *gorm_gen.File) error {
 	newName := fmt.Sprintf("%s (%d)", baseName, maxNum)
 
 	// 再次检查新文件名是否存在（以防并发）
-	count, err = query.Q.File.Where(query.File.Pid.Eq(f.Pid),
+	count, err = q.File.Where(query.File.Pid.Eq(f.Pid),
 		query.File.Userid.Eq(f.Userid),
 		query.File.Ext.Eq(f.Ext),
 		query.File.Name.Eq(newName)).Count()
@@ -245,7 +408,7 @@ func HandleDuplicateName(f *gorm_gen.File) error {
 	if count > 0 {
 		// 如果新文件名已存在，递归处理
 		f.Name = newName
-		return HandleDuplicateName(f)
+		return handleDuplicateName(q, f)
 	}
 
 	f.Name = newName
@@ -328,29 +491,78 @@ func getFileNameExt(filename string) string {
 	return name
 }
 
-func Upload(user *User, pid int, webkitRelativePath string, overwrite bool, file multipart.FileHeader) (*common.File, error) {
+func Upload(user *User, pid int, webkitRelativePath string, overwrite bool, file multipart.FileHeader, hash string) (*common.File, error) {
+	user_id, current_pid, err := prepareUploadFolder(user, pid, webkitRelativePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFile(file.Filename, file.Size); err != nil {
+		return nil, err
+	}
+	if !overwrite {
+		// 上传前按表单中的大小预先检查配额，避免无效上传
+		if err := checkQuota(ownerOf(user_id, current_pid), file.Size); err != nil {
+			return nil, err
+		}
+	}
+
+	// 处理文件上传
+	_file_open, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("打开上传文件失败: %v", err)
+	}
+	defer _file_open.Close()
+
+	blob, err := putBlob(routeProfile(user_id, current_pid, file.Filename), getFileType(file.Filename), _file_open, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return createUploadRecord(user, user_id, current_pid, file.Filename, webkitRelativePath, overwrite, blob)
+}
+
+// InstantUpload 秒传：客户端先提交内容哈希，云端已有相同内容时直接创建文件记录
+func InstantUpload(user *User, pid int, webkitRelativePath string, overwrite bool, filename string, hash string, size int64) (*common.File, error) {
+	hash = strings.ToLower(hash)
+	if len(hash) != 64 {
+		return nil, errors.New("无效的文件哈希")
+	}
+
+	user_id, current_pid, err := prepareUploadFolder(user, pid, webkitRelativePath)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := acquireBlob(routeProfile(user_id, current_pid, filename), hash, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return createUploadRecord(user, user_id, current_pid, filename, webkitRelativePath, overwrite, blob)
+}
+
+// prepareUploadFolder 检查上传权限和数量限制，按 webkitRelativePath 创建文件夹层级，
+// 返回文件拥有者ID和最终的上级文件夹ID
+func prepareUploadFolder(user *User, pid int, webkitRelativePath string) (int64, int64, error) {
 	user_id := int64(user.Userid)
+	if err := checkFolderItems(user_id, int64(pid)); err != nil {
+		return 0, 0, err
+	}
 	if pid > 0 {
-		count, _ := query.Q.File.Where(query.File.Pid.Eq(int64(pid))).Count()
-		if count >= 300 {
-			return nil, errors.New("每个文件夹里最多只能创建300个文件或文件夹")
-		}
 		row, err := permissionFind(pid, user, 1)
 		if err != nil {
-			return nil, err
+			return 0, 0, err
 		}
 		user_id = row.Userid
-	} else {
-		count, _ := query.Q.File.Where(query.File.Userid.Eq(int64(user.Userid)), query.File.Pid.Eq(0)).Count()
-		if count >= 300 {
-			return nil, errors.New("每个文件夹里最多只能创建300个文件或文件夹")
-		}
 	}
 
 	// 处理文件夹路径
 	var current_pid int64 = int64(pid)
 	if webkitRelativePath != "" {
 		dirs := strings.Split(webkitRelativePath, "/")
+		if err := getPolicy().CheckDepth(folderDepth(current_pid) + int64(len(dirs)-1)); err != nil {
+			return 0, 0, err
+		}
 		// 创建文件夹层级
 		for _, dirName := range dirs[0 : len(dirs)-1] {
 			if dirName == "" {
@@ -373,6 +585,10 @@ func Upload(user *User, pid int, webkitRelativePath string, overwrite bool, file
 				folder_id = existingFolder.ID
 				folderCreateMutex.Unlock()
 			} else {
+				if err := checkFolderItems(user_id, current_pid); err != nil {
+					folderCreateMutex.Unlock()
+					return 0, 0, err
+				}
 				// 文件夹不存在，在事务中创建
 				err = query.Q.Transaction(func(tx *query.Query) error {
 					// 再次检查文件夹是否存在（双重检查）
@@ -397,6 +613,7 @@ func Upload(user *User, pid int, webkitRelativePath string, overwrite bool, file
 						CreatedID: int64(user.Userid),
 					}
 					HandleDuplicateName(newFolder)
+					fillParents(tx, newFolder)
 					if err := tx.File.Create(newFolder); err != nil {
 						return err
 					}
@@ -406,27 +623,38 @@ func Upload(user *User, pid int, webkitRelativePath string, overwrite bool, file
 				folderCreateMutex.Unlock()
 
 				if err != nil {
-					return nil, fmt.Errorf("创建文件夹失败: %v", err)
+					return 0, 0, fmt.Errorf("创建文件夹失败: %v", err)
 				}
+				indexFiles(folder_id)
 			}
 
 			if folder_id == 0 {
-				return nil, fmt.Errorf("创建文件夹失败：无法获取有效的文件夹ID")
+				return 0, 0, fmt.Errorf("创建文件夹失败：无法获取有效的文件夹ID")
 			}
 			current_pid = folder_id
 		}
 	}
 
-	// 处理文件上传
-	_file_open, _ := file.Open()
-	defer _file_open.Close()
+	return user_id, current_pid, nil
+}
 
-	filetype := getFileType(file.Filename)
+// createUploadRecord 为已登记的内容创建文件和文件内容记录，失败时释放内容引用
+func createUploadRecord(user *User, user_id int64, current_pid int64, filename string, webkitRelativePath string, overwrite bool, blob *entity.FileBlob) (*common.File, error) {
+	if err := checkFile(filename, blob.Size); err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		return nil, err
+	}
+	if err := checkMime(getFileType(filename), policyExt(filename), blob.Mime); err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		return nil, err
+	}
+
+	filetype := getFileType(filename)
 	_file := gorm_gen.File{
 		Pid:       current_pid,
 		Type:      filetype,
-		Name:      getFileNameWithoutExt(file.Filename),
-		Ext:       getFileNameExt(file.Filename),
+		Name:      getFileNameWithoutExt(filename),
+		Ext:       getFileNameExt(filename),
 		Userid:    user_id,
 		CreatedID: int64(user.Userid),
 	}
@@ -441,32 +669,45 @@ func Upload(user *User, pid int, webkitRelativePath string, overwrite bool, file
 	}
 	if newfile == nil {
 		overwrite = false
-		newfile = &_file
-		if err := HandleDuplicateName(newfile); err != nil {
-			return nil, fmt.Errorf("处理同名文件失败: %v", err)
+		if err := checkFolderItems(user_id, current_pid); err != nil {
+			releaseBlob(blob.Profile, blob.ObjectKey)
+			return nil, err
 		}
+		newfile = &_file
 	}
 
-	uploader := getCloudUploader()
-
-	// 构建上传路径
-	uploadPath := newfile.Name + "." + newfile.Ext
-	if webkitRelativePath != "" {
-		uploadPath = webkitRelativePath
+	// 覆盖上传时只计入大小差值
+	owner := ownerOf(newfile.Userid, newfile.Pid)
+	delta := blob.Size
+	if overwrite {
+		delta -= newfile.Size
 	}
-
-	contentLength, err := uploader.Upload(_file_open, uploadPath, int64(pid))
-	if err != nil {
+	if err := reserveQuota(owner, delta); err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
 		return nil, err
 	}
 
-	newfile.Size = contentLength
+	var oldMeta ContentMeta
+	if overwrite {
+		oldMeta = GetContentMeta(newfile)
+	}
+	newfile.Size = blob.Size
 
 	// 保存文件记录
-	err = query.Q.Transaction(func(tx *query.Query) error {
-		HandleDuplicateName(newfile)
-		if err := tx.File.Create(newfile); err != nil {
-			return err
+	var contentID int64
+	err := query.Q.Transaction(func(tx *query.Query) error {
+		if overwrite {
+			if err := tx.File.Save(newfile); err != nil {
+				return err
+			}
+		} else {
+			if err := handleDuplicateName(tx, newfile); err != nil {
+				return fmt.Errorf("处理同名文件失败: %v", err)
+			}
+			fillParents(tx, newfile)
+			if err := tx.File.Create(newfile); err != nil {
+				return err
+			}
 		}
 
 		baseURL := os.Getenv("SERVER_URL")
@@ -478,6 +719,7 @@ func Upload(user *User, pid int, webkitRelativePath string, overwrite bool, file
 			"url":       "",
 			"cloud_url": downloadURL,
 		}
+		setBlobContent(content, blob)
 		jsonData, err := json.Marshal(content)
 		if err != nil {
 			return err
@@ -487,15 +729,24 @@ func Upload(user *User, pid int, webkitRelativePath string, overwrite bool, file
 			Fid:     newfile.ID,
 			Content: string(jsonData),
 			Text:    "",
-			Size:    contentLength,
+			Size:    blob.Size,
 			Userid:  user_id,
 		}
-		return tx.FileContent.Create(&filecontent)
+		if err := tx.FileContent.Create(&filecontent); err != nil {
+			return err
+		}
+		contentID = filecontent.ID
+		return nil
 	})
 
 	if err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		releaseQuota(owner, delta)
 		return nil, fmt.Errorf("file upload failed, SQL create failed: %v", err)
 	}
+	refreshThumbnails(newfile.Type, oldMeta, blob)
+	enqueueExtract(newfile, contentID)
+	indexFiles(newfile.ID)
 
 	// 获取最新的文件记录
 	newfile, _ = query.Q.File.Where(query.File.ID.Eq(newfile.ID)).First()
@@ -527,70 +778,49 @@ func Upload(user *User, pid int, webkitRelativePath string, overwrite bool, file
 	return resp, nil
 }
 
-func Io_Upload(user *User, fileID int, webkitRelativePath string, overwrite bool, file io.ReadCloser, filename string) (*common.File, error) {
+func Io_Upload(user *User, fileID int, webkitRelativePath string, overwrite bool, file io.ReadCloser, filename string, hash string) (*common.File, error) {
 	// 获取文件记录
 	existingFile, err := query.Q.File.Where(query.File.ID.Eq(int64(fileID))).First()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get file record: %v", err)
 	}
 
-	// 构建文件夹路径
-	paths := []string{}
-	if existingFile.Pids != "" {
-		// 移除开头和结尾的逗号
-		pids := strings.Trim(existingFile.Pids, ",")
-		if pids != "" {
-			// 分割成ID数组
-			pidArray := strings.Split(pids, ",")
-			for _, pidStr := range pidArray {
-				pid, err := strconv.ParseInt(pidStr, 10, 64)
-				if err != nil {
-					log.Printf("解析pid失败: %v", err)
-					continue
-				}
-				// 获取文件夹信息
-				folder, err := query.Q.File.Where(query.File.ID.Eq(pid)).First()
-				if err != nil {
-					log.Printf("获取文件夹信息失败, ID: %d, 错误: %v", pid, err)
-					continue
-				}
-				if folder.Type == "folder" {
-					log.Printf("从pids添加文件夹到路径: %s", folder.Name)
-					paths = append(paths, folder.Name)
-				}
-			}
-		}
+	// 获取现有的content内容
+	fileContent, err := query.Q.FileContent.Where(query.FileContent.Fid.Eq(existingFile.ID)).
+		Order(query.FileContent.ID.Desc()).First()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file content: %v", err)
 	}
 
-	// 构建完整的文件路径
-	originalFileName := existingFile.Name
-	if existingFile.Ext != "" {
-		originalFileName = originalFileName + "." + existingFile.Ext
-	}
-	fullPath := originalFileName
-	if len(paths) > 0 {
-		fullPath = strings.Join(paths, "/") + "/" + originalFileName
-		log.Printf("构建的完整文件路径: %s", fullPath)
-	}
+	oldMeta := GetContentMeta(existingFile)
 
 	// 上传文件
-	uploader := getCloudUploader()
-	contentLength, err := uploader.ReaderUpload(file, fullPath)
+	defer file.Close()
+	blob, err := putBlob(routeFile(existingFile), existingFile.Type, file, hash)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	contentLength := blob.Size
+
+	if err := checkFileSize(existingFile, contentLength); err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		return nil, err
+	}
+	if err := checkMime(existingFile.Type, existingFile.Ext, blob.Mime); err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		return nil, err
+	}
+	owner := ownerOf(existingFile.Userid, existingFile.Pid)
+	delta := contentLength - existingFile.Size
+	if err := reserveQuota(owner, delta); err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		return nil, err
+	}
 
 	// 生成下载URL
 	baseURL := os.Getenv("SERVER_URL")
 	downloadURL := fmt.Sprintf("http://%s/api/file/content/downloading?id=%d", baseURL, existingFile.ID)
 
-	// 获取现有的content内容
-	_, err = query.Q.FileContent.Where(query.FileContent.Fid.Eq(existingFile.ID)).First()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get file content: %v", err)
-	}
-
 	// 构建新的content内容
 	content := map[string]interface{}{
 		"from":      "",
@@ -599,19 +829,28 @@ func Io_Upload(user *User, fileID int, webkitRelativePath string, overwrite bool
 		"url":       "",
 		"cloud_url": downloadURL,
 	}
+	setBlobContent(content, blob)
 	jsonData, err := json.Marshal(content)
 	if err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		releaseQuota(owner, delta)
 		return nil, fmt.Errorf("failed to marshal content: %v", err)
 	}
 
-	// 更新file_contents表中的content字段
-	_, err = query.Q.FileContent.Where(query.FileContent.Fid.Eq(existingFile.ID)).
+	// 更新最新一条file_contents记录的content字段，旧内容的引用随之释放
+	_, err = query.Q.FileContent.Where(query.FileContent.ID.Eq(fileContent.ID)).
 		Updates(map[string]interface{}{
 			"content": string(jsonData),
+			"size":    contentLength,
 		})
 	if err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		releaseQuota(owner, delta)
 		return nil, fmt.Errorf("failed to update content field: %v", err)
 	}
+	if err := releaseBlob(oldMeta.Profile, oldMeta.Key); err != nil {
+		log.Printf("释放旧内容失败, ID: %d, 错误: %v", existingFile.ID, err)
+	}
 
 	// 更新文件大小
 	_, err = query.Q.File.Where(query.File.ID.Eq(existingFile.ID)).
@@ -621,6 +860,9 @@ func Io_Upload(user *User, fileID int, webkitRelativePath string, overwrite bool
 	if err != nil {
 		return nil, fmt.Errorf("failed to update file size: %v", err)
 	}
+	refreshThumbnails(existingFile.Type, oldMeta, blob)
+	enqueueExtract(existingFile, fileContent.ID)
+	indexFiles(existingFile.ID)
 
 	fullName := existingFile.Name + "." + existingFile.Ext
 	if webkitRelativePath != "" {
@@ -657,31 +899,7 @@ func OfficeUpload(user *User, id int, status int, key string, urlStr string) err
 	}
 
 	if status == 2 {
-		// 构建文件名和路径
-		originalFileName := row.Name
-		if row.Ext != "" {
-			originalFileName = originalFileName + "." + row.Ext
-		}
-
-		// 构建文件夹路径
-		paths := []string{}
-		currentPid := row.Pid
-		for currentPid > 0 {
-			parentFile, err := query.Q.File.Where(query.File.ID.Eq(currentPid)).First()
-			if err != nil {
-				break
-			}
-			if parentFile.Type == "folder" {
-				paths = append([]string{parentFile.Name}, paths...)
-			}
-			currentPid = parentFile.Pid
-		}
-
-		// 构建完整的文件路径
-		fullPath := originalFileName
-		if len(paths) > 0 {
-			fullPath = strings.Join(paths, "/") + "/" + originalFileName
-		}
+		fullPath := GetFilePath(row)
 
 		parsedURL, err := url.Parse(urlStr)
 		if err != nil {
@@ -728,12 +946,27 @@ func OfficeUpload(user *User, id int, status int, key string, urlStr string) err
 		}
 		defer response.Body.Close()
 
-		uploader := getCloudUploader()
-		contentLength, err := uploader.ReaderUpload(response.Body, fullPath)
+		blob, err := putBlob(routeFile(row), row.Type, response.Body, "")
 		if err != nil {
 			fmt.Printf("Cloud upload failed: %v\n", err)
 			return fmt.Errorf("failed to upload to cloud: %v", err)
 		}
+		contentLength := blob.Size
+
+		if err := checkFileSize(row, contentLength); err != nil {
+			releaseBlob(blob.Profile, blob.ObjectKey)
+			return err
+		}
+		if err := checkMime(row.Type, row.Ext, blob.Mime); err != nil {
+			releaseBlob(blob.Profile, blob.ObjectKey)
+			return err
+		}
+		owner := ownerOf(row.Userid, row.Pid)
+		delta := contentLength - row.Size
+		if err := reserveQuota(owner, delta); err != nil {
+			releaseBlob(blob.Profile, blob.ObjectKey)
+			return err
+		}
 
 		log.Printf("office文件上传成功: %s", fullPath)
 
@@ -741,23 +974,32 @@ func OfficeUpload(user *User, id int, status int, key string, urlStr string) err
 		if baseURL == "" {
 			baseURL = "localhost:8888"
 		}
-		downloadURL := fmt.Sprintf("http://%s/api/file/content/downloading_office?key=%s", baseURL, fullPath)
+		downloadURL := fmt.Sprintf("http://%s/api/file/content/downloading_office?key=%s", baseURL, url.QueryEscape(blob.ObjectKey))
 		content := map[string]interface{}{
 			"from":      loadURL,
 			"cloud_url": downloadURL,
 		}
+		setBlobContent(content, blob)
 		jsonData, err := json.Marshal(content)
 		if err != nil {
+			releaseBlob(blob.Profile, blob.ObjectKey)
+			releaseQuota(owner, delta)
 			return err
 		}
 		filecontent := gorm_gen.FileContent{Fid: row.ID, Content: string(jsonData), Text: "", Size: contentLength, Userid: int64(user.Userid)}
-		query.Q.FileContent.Create(&filecontent)
+		if err := query.Q.FileContent.Create(&filecontent); err != nil {
+			releaseBlob(blob.Profile, blob.ObjectKey)
+			releaseQuota(owner, delta)
+			return err
+		}
 		row.Size = contentLength
 		row.UpdatedAt = time.Now()
 		_, err = query.Q.File.Where(query.File.ID.Eq(row.ID)).Updates(row)
 		if err != nil {
 			return err
 		}
+		enqueueExtract(row, filecontent.ID)
+		indexFiles(row.ID)
 	}
 	return nil
 }
@@ -770,7 +1012,7 @@ func DeleteLocalFileWithUser(user *User, fileID int32) error {
 	}
 
 	// 获取本地下载目录
-	localDir := os.Getenv("LOCAL_DOWNLOAD_DIR")
+	localDir := config.LocalDownloadDir
 	if localDir == "" {
 		return errors.New("local download directory not configured")
 	}
@@ -779,10 +1021,9 @@ func DeleteLocalFileWithUser(user *User, fileID int32) error {
 	localFileName := fmt.Sprintf("%d_%s.%s", fileID, file.Name, file.Ext)
 	localFilePath := filepath.Join(localDir, localFileName)
 
-	// 删除本地文件
-	err = os.Remove(localFilePath)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to delete local file: %w", err)
+	// 取消离线文件，内容转为下载缓存，由 LRU 淘汰
+	if err := UnpinContent(localFilePath); err != nil {
+		return err
 	}
 
 	// 获取文件内容记录
@@ -863,9 +1104,11 @@ func GetWorkDir() string {
 	return "/app" // 默认工作目录
 }
 
-// GetFileContentURL 获取文件内容的URL
+// GetFileContentURL 获取文件最新内容的本地路径：SaveOffline 记录的离线文件为绝对路径，
+// DooTask 上传的内容为相对于工作目录的路径
 func GetFileContentURL(fileID int64) (string, error) {
-	content, err := query.Q.FileContent.Where(query.FileContent.Fid.Eq(fileID)).First()
+	content, err := query.Q.FileContent.Where(query.FileContent.Fid.Eq(fileID)).
+		Order(query.FileContent.UpdatedAt.Desc()).First()
 	if err != nil {
 		return "", err
 	}
@@ -882,13 +1125,10 @@ func GetFileContentURL(fileID int64) (string, error) {
 	}
 
 	url = strings.ReplaceAll(url, "\\/", "/")
-
-	// 开发环境
-	return "/Users/hitosea-005/Desktop/dootask_0.40.78/public/" + url, nil
-
-	// 正式环境
-	// return GetWorkDir() + "/" + url, nil
-
+	if filepath.IsAbs(url) {
+		return url, nil
+	}
+	return filepath.Join(GetWorkDir(), url), nil
 }
 
 func DownloadFileFromURL(url string, localPath string) error {
@@ -996,6 +1236,7 @@ func SaveContent(user *User, id int64, content string) (*common.FileContent, err
 		}
 		contentString = contentData["content"].(string)
 		fileExt = file.Ext
+		text = contentString
 	default:
 		return nil, fmt.Errorf("不支持的文件类型: %s", file.Type)
 	}
@@ -1006,52 +1247,26 @@ func SaveContent(user *User, id int64, content string) (*common.FileContent, err
 		return nil, fmt.Errorf("更新文件扩展名失败: %v", err)
 	}
 
-	// 添加延时，确保数据库更新完成
-	time.Sleep(time.Millisecond * 500)
-
-	// 刷新文件信息以获取最新的文件名和后缀
-	file, err = query.Q.File.Where(query.File.ID.Eq(id)).First()
+	// 上传内容到云存储
+	blob, err := putBlob(routeFile(file), file.Type, strings.NewReader(contentString), "")
 	if err != nil {
-		return nil, fmt.Errorf("刷新文件信息失败: %v", err)
-	}
-
-	// 构建文件路径
-	paths := []string{}
-	if file.Pids != "" {
-		pids := strings.Trim(file.Pids, ",")
-		if pids != "" {
-			pidArray := strings.Split(pids, ",")
-			for _, pidStr := range pidArray {
-				pid, err := strconv.ParseInt(pidStr, 10, 64)
-				if err != nil {
-					continue
-				}
-				folder, err := query.Q.File.Where(query.File.ID.Eq(pid)).First()
-				if err != nil {
-					continue
-				}
-				if folder.Type == "folder" {
-					paths = append(paths, folder.Name)
-				}
-			}
-		}
+		return nil, fmt.Errorf("上传内容失败: %v", err)
 	}
+	size := blob.Size
 
-	// 构建完整的文件路径
-	originalFileName := file.Name
-	if file.Ext != "" {
-		originalFileName = originalFileName + "." + file.Ext
+	if err := checkFileSize(file, size); err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		return nil, err
 	}
-	fullPath := originalFileName
-	if len(paths) > 0 {
-		fullPath = strings.Join(paths, "/") + "/" + originalFileName
+	if err := checkMime(file.Type, file.Ext, blob.Mime); err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		return nil, err
 	}
-
-	// 上传内容到云存储
-	contentReader := strings.NewReader(contentString)
-	size, err := getCloudUploader().ReaderUpload(io.NopCloser(contentReader), fullPath)
-	if err != nil {
-		return nil, fmt.Errorf("上传内容失败: %v", err)
+	owner := ownerOf(file.Userid, file.Pid)
+	delta := size - file.Size
+	if err := reserveQuota(owner, delta); err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		return nil, err
 	}
 
 	// 生成下载URL
@@ -1066,8 +1281,11 @@ func SaveContent(user *User, id int64, content string) (*common.FileContent, err
 		"url":       "",
 		"cloud_url": downloadURL,
 	}
+	setBlobContent(contentMap, blob)
 	jsonData, err := json.Marshal(contentMap)
 	if err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		releaseQuota(owner, delta)
 		return nil, fmt.Errorf("内容序列化失败: %v", err)
 	}
 
@@ -1075,7 +1293,7 @@ func SaveContent(user *User, id int64, content string) (*common.FileContent, err
 	fileContent := &gorm_gen.FileContent{
 		Fid:       id,
 		Content:   string(jsonData),
-		Text:      text,
+		Text:      capText(text, config.ExtractMaxText),
 		Size:      size,
 		Userid:    int64(user.Userid),
 		CreatedAt: time.Now(),
@@ -1099,8 +1317,11 @@ func SaveContent(user *User, id int64, content string) (*common.FileContent, err
 	})
 
 	if err != nil {
+		releaseBlob(blob.Profile, blob.ObjectKey)
+		releaseQuota(owner, delta)
 		return nil, err
 	}
+	indexFiles(file.ID)
 
 	return &common.FileContent{
 		ID:        fileContent.ID,
@@ -1113,3 +1334,320 @@ func SaveContent(user *User, id int64, content string) (*common.FileContent, err
 		UpdatedAt: fileContent.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}, nil
 }
+
+// ContentMeta 文件内容记录 content 字段中的存储元数据
+type ContentMeta struct {
+	Profile     string // 存储配置ID或云存储名称，为空时为此前写入阿里云OSS的内容
+	Key         string // 对象路径
+	Hash        string // SHA-256
+	MD5         string
+	CRC64       string
+	Mime        string    // 上传时按文件头识别的 MIME 类型
+	Size        int64     // 内容大小(B)，压缩或加密的内容为原内容大小
+	Encoding    string    // 压缩方式，未压缩时为空
+	EncodedSize int64     // 压缩后的大小(B)
+	Envelope    *Envelope // 加密信息，未加密时为 nil
+}
+
+// payloadSize 返回加密前存储内容的大小，压缩的内容为压缩后的大小
+func (m ContentMeta) payloadSize() int64 {
+	if m.Encoding != "" {
+		return m.EncodedSize
+	}
+	return m.Size
+}
+
+// ContentType 返回下载时使用的 Content-Type，未识别类型时按扩展名推断
+func (m ContentMeta) ContentType(filename string) string {
+	if m.Mime != "" && m.Mime != "application/octet-stream" {
+		return m.Mime
+	}
+	if contentType := mime.TypeByExtension(filepath.Ext(filename)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// IsInlineType 判断内容类型能否在浏览器中直接预览，HTML、SVG 等可执行脚本的类型只允许下载
+func IsInlineType(contentType string) bool {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	switch {
+	case contentType == "image/svg+xml":
+		return false
+	case strings.HasPrefix(contentType, "image/"), strings.HasPrefix(contentType, "audio/"), strings.HasPrefix(contentType, "video/"):
+		return true
+	case contentType == "application/pdf", contentType == "text/plain":
+		return true
+	}
+	return false
+}
+
+// ETag 返回以内容哈希作为实体标签的 ETag 响应头，没有哈希时返回空字符串
+func (m ContentMeta) ETag() string {
+	if m.Hash == "" {
+		return ""
+	}
+	return `"` + m.Hash + `"`
+}
+
+// Digest 返回 RFC 3230 格式的 Digest 响应头，例如 sha-256=...,md5=...
+func (m ContentMeta) Digest() string {
+	digests := []string{}
+	if raw, err := hex.DecodeString(m.Hash); err == nil && len(raw) > 0 {
+		digests = append(digests, "sha-256="+base64.StdEncoding.EncodeToString(raw))
+	}
+	if raw, err := hex.DecodeString(m.MD5); err == nil && len(raw) > 0 {
+		digests = append(digests, "md5="+base64.StdEncoding.EncodeToString(raw))
+	}
+	return strings.Join(digests, ",")
+}
+
+// parseContentMeta 解析文件内容记录中的存储元数据，旧数据缺失的字段为空
+func parseContentMeta(fileContent *gorm_gen.FileContent) ContentMeta {
+	var content map[string]interface{}
+	if err := json.Unmarshal([]byte(fileContent.Content), &content); err != nil {
+		return ContentMeta{}
+	}
+	var meta ContentMeta
+	meta.Profile, _ = content["profile"].(string)
+	if meta.Profile == "" {
+		// storagectl migrate 切换后的内容只记录所在的云存储
+		meta.Profile, _ = content["provider"].(string)
+	}
+	meta.Key, _ = content["key"].(string)
+	meta.Hash, _ = content["hash"].(string)
+	meta.MD5, _ = content["md5"].(string)
+	meta.CRC64, _ = content["crc64"].(string)
+	meta.Mime, _ = content["mime"].(string)
+	meta.Size = fileContent.Size
+	if encoding, _ := content["encoding"].(string); encoding != "" {
+		meta.Encoding = encoding
+		encodedSize, _ := content["encoded_size"].(float64)
+		meta.EncodedSize = int64(encodedSize)
+	}
+	if cipher, _ := content["cipher"].(string); cipher == encCipher {
+		meta.Envelope = &Envelope{}
+		meta.Envelope.KeyID, _ = content["key_id"].(string)
+		meta.Envelope.DataKey, _ = content["data_key"].(string)
+		meta.Envelope.Nonce, _ = content["nonce"].(string)
+	}
+	return meta
+}
+
+// GetFilePath 按文件夹层级拼接文件路径，去重之前上传的文件以此路径存放在存储桶中
+func GetFilePath(file *gorm_gen.File) string {
+	paths := []string{}
+	currentPid := file.Pid
+	for currentPid > 0 {
+		parentFile, err := query.Q.File.Where(query.File.ID.Eq(currentPid)).First()
+		if err != nil {
+			log.Printf("获取父文件夹信息失败, ID: %d, 错误: %v", currentPid, err)
+			break
+		}
+		if parentFile.Type == "folder" {
+			paths = append([]string{parentFile.Name}, paths...)
+		}
+		currentPid = parentFile.Pid
+	}
+
+	fileName := file.Name
+	if file.Ext != "" {
+		fileName = fileName + "." + file.Ext
+	}
+	if len(paths) > 0 {
+		return strings.Join(paths, "/") + "/" + fileName
+	}
+	return fileName
+}
+
+// GetContentMeta 返回文件最新内容的存储元数据，旧数据没有记录对象路径时按文件夹层级推导
+func GetContentMeta(file *gorm_gen.File) ContentMeta {
+	var meta ContentMeta
+	fileContent, err := query.Q.FileContent.
+		Where(query.FileContent.Fid.Eq(file.ID)).
+		Order(query.FileContent.ID.Desc()).
+		First()
+	if err == nil {
+		meta = parseContentMeta(fileContent)
+	}
+	if meta.Key == "" {
+		meta.Key = GetFilePath(file)
+		meta.Size = file.Size
+	}
+	return meta
+}
+
+// GetObjectKey 返回文件最新内容在存储桶中的对象路径
+func GetObjectKey(file *gorm_gen.File) string {
+	return GetContentMeta(file).Key
+}
+
+// GetBlobMeta 按对象路径查找已登记内容的存储元数据
+func GetBlobMeta(key string) ContentMeta {
+	blob, err := query.Q.FileBlob.Where(query.FileBlob.ObjectKey.Eq(key)).First()
+	if err != nil {
+		return ContentMeta{Key: key}
+	}
+	return blobMeta(blob)
+}
+
+// blobMeta 返回已登记内容的存储元数据
+func blobMeta(blob *entity.FileBlob) ContentMeta {
+	meta := ContentMeta{
+		Profile:     blob.Profile,
+		Key:         blob.ObjectKey,
+		Hash:        blob.Hash,
+		MD5:         blob.MD5,
+		CRC64:       blob.CRC64,
+		Mime:        blob.Mime,
+		Size:        blob.Size,
+		Encoding:    blob.Encoding,
+		EncodedSize: blob.EncodedSize,
+	}
+	if blob.Cipher == encCipher {
+		meta.Envelope = &Envelope{KeyID: blob.KeyID, DataKey: blob.DataKey, Nonce: blob.Nonce}
+	}
+	return meta
+}
+
+// DeleteFiles 删除文件或文件夹（含全部子级），释放其历史版本引用的云端内容并扣减已用空间
+func DeleteFiles(user *User, id int) error {
+	row, err := permissionFind(id, user, 1000)
+	if err != nil {
+		return err
+	}
+	pshare := shareRootOf(row)
+
+	files, err := collectDescendants(query.Q, row, false)
+	if err != nil {
+		return fmt.Errorf("查询子文件失败: %v", err)
+	}
+	ids := make([]int64, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.ID)
+	}
+
+	fileContents, err := query.Q.FileContent.Where(query.FileContent.Fid.In(ids...)).Find()
+	if err != nil {
+		return fmt.Errorf("查询文件内容失败: %v", err)
+	}
+
+	err = query.Q.Transaction(func(tx *query.Query) error {
+		if _, err := tx.FileContent.Where(query.FileContent.Fid.In(ids...)).Delete(); err != nil {
+			return err
+		}
+		_, err := tx.File.Where(query.File.ID.In(ids...)).Delete()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("删除文件失败: %v", err)
+	}
+
+	for _, file := range files {
+		if file.Type != "folder" {
+			addUsage(quotaOwner{Userid: file.Userid, Pshare: pshare}, -file.Size)
+		}
+	}
+	unindexFiles(ids...)
+	for _, fileContent := range fileContents {
+		meta := parseContentMeta(fileContent)
+		if err := releaseBlob(meta.Profile, meta.Key); err != nil {
+			log.Printf("释放文件内容失败, ID: %d, 错误: %v", fileContent.Fid, err)
+		}
+	}
+	return nil
+}
+
+// RestoreFiles 恢复已删除的文件或文件夹（含同时删除的子级），重新引用云端内容并计入已用空间
+func RestoreFiles(user *User, id int) error {
+	row, err := query.Q.File.Unscoped().Where(query.File.ID.Eq(int64(id)), query.File.DeletedAt.IsNotNull()).First()
+	if err != nil {
+		return errors.New("文件不存在或未被删除")
+	}
+	userids := []int64{int64(user.Userid)}
+	if !IsContainInt(userids, row.Userid) && !IsContainInt(userids, row.CreatedID) {
+		return errors.New("仅限所有者或创建者操作")
+	}
+	if row.Pid > 0 {
+		if _, err := query.Q.File.Where(query.File.ID.Eq(row.Pid)).First(); err != nil {
+			return errors.New("上级文件夹不存在或已被删除")
+		}
+	}
+
+	if err := checkFolderItems(row.Userid, row.Pid); err != nil {
+		return err
+	}
+
+	files, err := collectDescendants(query.Q, row, true)
+	if err != nil {
+		return fmt.Errorf("查询子文件失败: %v", err)
+	}
+	ids := make([]int64, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.ID)
+	}
+
+	// 与 DeleteFiles 计入同一共享根目录，按各文件的拥有者预占空间
+	pshare := shareRootOf(row)
+	sizes := map[int64]int64{}
+	for _, file := range files {
+		if file.Type != "folder" {
+			sizes[file.Userid] += file.Size
+		}
+	}
+	reserved := map[int64]int64{}
+	release := func() {
+		for userid, size := range reserved {
+			releaseQuota(quotaOwner{Userid: userid, Pshare: pshare}, size)
+		}
+	}
+	for userid, size := range sizes {
+		if err := reserveQuota(quotaOwner{Userid: userid, Pshare: pshare}, size); err != nil {
+			release()
+			return err
+		}
+		reserved[userid] = size
+	}
+
+	fileContents, err := query.Q.FileContent.Unscoped().
+		Where(query.FileContent.Fid.In(ids...), query.FileContent.DeletedAt.IsNotNull()).Find()
+	if err != nil {
+		return fmt.Errorf("查询文件内容失败: %v", err)
+	}
+
+	// 重新引用内容，已被清理的内容无法恢复
+	acquired := []ContentMeta{}
+	for _, fileContent := range fileContents {
+		meta := parseContentMeta(fileContent)
+		if meta.Hash == "" {
+			continue
+		}
+		if _, err := reacquireBlob(meta.Profile, meta.Key); err != nil {
+			for _, m := range acquired {
+				releaseBlob(m.Profile, m.Key)
+			}
+			release()
+			return fmt.Errorf("文件内容已被清理，无法恢复: %v", err)
+		}
+		acquired = append(acquired, meta)
+	}
+
+	err = query.Q.Transaction(func(tx *query.Query) error {
+		if _, err := tx.FileContent.Unscoped().Where(query.FileContent.Fid.In(ids...)).
+			Update(query.FileContent.DeletedAt, nil); err != nil {
+			return err
+		}
+		_, err := tx.File.Unscoped().Where(query.File.ID.In(ids...)).Update(query.File.DeletedAt, nil)
+		return err
+	})
+	if err != nil {
+		for _, m := range acquired {
+			releaseBlob(m.Profile, m.Key)
+		}
+		release()
+		return fmt.Errorf("恢复文件失败: %v", err)
+	}
+
+	indexFiles(ids...)
+	return nil
+}
@@ -1,7 +1,11 @@
 package service
 
 import (
+	"fmt"
 	"testing"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/gorm_gen"
 )
 
 func Test_Trim(t *testing.T) {
@@ -9,3 +13,59 @@ func Test_Trim(t *testing.T) {
 	t.Log(getFileNameExt("10.11.111会议纪要（1）.docx"))
 	t.Log("very good")
 }
+
+// createTestFile 创建文件记录
+func createTestFile(t *testing.T, file *gorm_gen.File) *gorm_gen.File {
+	t.Helper()
+	if err := query.Q.File.Create(file); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func Test_getPermission(t *testing.T) {
+	newTestDB(t)
+	shared := createTestFile(t, &gorm_gen.File{Name: "项目", Type: "folder", Share: 1, Userid: 1, CreatedID: 1})
+	sub := createTestFile(t, &gorm_gen.File{Pid: shared.ID, Name: "设计", Type: "folder", Userid: 1, CreatedID: 1})
+	file := createTestFile(t, &gorm_gen.File{Pid: sub.ID, Name: "架构图", Ext: "png", Type: "picture", Userid: 1, CreatedID: 1})
+	private := createTestFile(t, &gorm_gen.File{Name: "私人", Type: "folder", Userid: 1, CreatedID: 1})
+	query.Q.File_User.Create(&gorm_gen.File_User{FileID: shared.ID, Userid: 2, Permission: 1})
+
+	cases := []struct {
+		name    string
+		file    *gorm_gen.File
+		userids []int64
+		want    int
+	}{
+		{"owner", file, []int64{0, 1}, 1000},
+		{"member of the share root", file, []int64{0, 2}, 1},
+		{"member on the subfolder", sub, []int64{0, 2}, 1},
+		{"non-member", file, []int64{0, 3}, -1},
+		{"unshared folder", private, []int64{0, 2}, -1},
+	}
+	for _, c := range cases {
+		if got := getPermission(c.file, c.userids); got != c.want {
+			t.Errorf("%s: getPermission = %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func Test_fillParents(t *testing.T) {
+	newTestDB(t)
+	outer := createTestFile(t, &gorm_gen.File{Name: "部门", Type: "folder", Share: 1, Userid: 1})
+	inner := &gorm_gen.File{Pid: outer.ID, Name: "项目", Type: "folder", Share: 1, Userid: 1}
+	fillParents(query.Q, inner)
+	createTestFile(t, inner)
+	file := &gorm_gen.File{Pid: inner.ID, Name: "报告", Ext: "docx", Type: "word", Userid: 1}
+	fillParents(query.Q, file)
+
+	wantPids := fmt.Sprintf(",%d,%d,", outer.ID, inner.ID)
+	if file.Pids != wantPids || file.Pshare != outer.ID {
+		t.Errorf("fillParents = %q, %d, want %q, %d", file.Pids, file.Pshare, wantPids, outer.ID)
+	}
+	root := &gorm_gen.File{Name: "根目录文件", Type: "txt", Userid: 1}
+	fillParents(query.Q, root)
+	if root.Pids != "" || root.Pshare != 0 {
+		t.Errorf("root file = %q, %d", root.Pids, root.Pshare)
+	}
+}
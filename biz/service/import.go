@@ -0,0 +1,235 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/gorm_gen"
+)
+
+// ImportResult 导入结果
+type ImportResult struct {
+	Folders int   // 新建的文件夹数
+	Files   int   // 新建的文件数
+	Skipped int   // 已导入或已由网盘管理而跳过的对象数
+	Size    int64 // 新建文件的总大小
+}
+
+// importTarget 存储桶中的对象在网盘中的位置：文件夹层级和文件名
+func importTarget(prefix string, key string) ([]string, string) {
+	rel := strings.Trim(strings.TrimPrefix(key, prefix), "/")
+	if rel == "" {
+		return nil, ""
+	}
+	parts := strings.Split(rel, "/")
+	dirs := make([]string, 0, len(parts)-1)
+	for _, dir := range parts[:len(parts)-1] {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	if strings.HasSuffix(key, "/") {
+		// 目录占位对象，只创建文件夹
+		return append(dirs, parts[len(parts)-1]), ""
+	}
+	return dirs, parts[len(parts)-1]
+}
+
+// splitFileName 拆分文件名和扩展名，没有扩展名时 ext 为空
+func splitFileName(filename string) (string, string) {
+	ext := path.Ext(filename)
+	if ext == "" || ext == filename {
+		return filename, ""
+	}
+	return strings.TrimSuffix(filename, ext), strings.TrimPrefix(ext, ".")
+}
+
+// importer 导入过程中缓存已解析的文件夹
+type importer struct {
+	profile string
+	userid  int64
+	root    *gorm_gen.File
+	folders map[string]*gorm_gen.File
+	result  ImportResult
+}
+
+// folder 按层级查找或创建文件夹，返回最末一级，dirs 为空时返回导入的目标文件夹
+func (im *importer) folder(dirs []string) (*gorm_gen.File, error) {
+	parent := im.root
+	for i, name := range dirs {
+		cacheKey := strings.Join(dirs[:i+1], "/")
+		if folder, ok := im.folders[cacheKey]; ok {
+			parent = folder
+			continue
+		}
+		var pid int64
+		if parent != nil {
+			pid = parent.ID
+		}
+		folder, err := query.Q.File.Where(
+			query.File.Pid.Eq(pid),
+			query.File.Userid.Eq(im.userid),
+			query.File.Name.Eq(name),
+			query.File.Type.Eq("folder"),
+		).First()
+		if err != nil {
+			folder = &gorm_gen.File{
+				Pid:       pid,
+				Type:      "folder",
+				Name:      name,
+				Userid:    im.userid,
+				CreatedID: im.userid,
+			}
+			fillParents(query.Q, folder)
+			if err := query.Q.File.Create(folder); err != nil {
+				return nil, fmt.Errorf("创建文件夹失败: %v", err)
+			}
+			indexFiles(folder.ID)
+			im.result.Folders++
+		}
+		im.folders[cacheKey] = folder
+		parent = folder
+	}
+	return parent, nil
+}
+
+// file 为对象创建文件和指向原对象的文件内容记录，不复制对象
+func (im *importer) file(parent *gorm_gen.File, filename string, obj ObjectInfo) (*gorm_gen.File, error) {
+	name, ext := splitFileName(filename)
+	var pid int64
+	if parent != nil {
+		pid = parent.ID
+	}
+	file := &gorm_gen.File{
+		Pid:       pid,
+		Type:      getFileType(filename),
+		Name:      name,
+		Ext:       ext,
+		Size:      obj.Size,
+		Userid:    im.userid,
+		CreatedID: im.userid,
+	}
+	if err := HandleDuplicateName(file); err != nil {
+		return nil, fmt.Errorf("处理同名文件失败: %v", err)
+	}
+
+	var contentID int64
+	err := query.Q.Transaction(func(tx *query.Query) error {
+		fillParents(tx, file)
+		if err := tx.File.Create(file); err != nil {
+			return err
+		}
+		downloadURL := fmt.Sprintf("http://%s/api/file/content/downloading?id=%d", os.Getenv("SERVER_URL"), file.ID)
+		content, err := json.Marshal(map[string]interface{}{
+			"from":      "",
+			"type":      file.Type,
+			"ext":       file.Ext,
+			"url":       "",
+			"cloud_url": downloadURL,
+			"key":       obj.Key,
+			"profile":   im.profile,
+		})
+		if err != nil {
+			return err
+		}
+		filecontent := gorm_gen.FileContent{Fid: file.ID, Content: string(content), Size: obj.Size, Userid: im.userid}
+		if err := tx.FileContent.Create(&filecontent); err != nil {
+			return err
+		}
+		contentID = filecontent.ID
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建文件记录失败: %v", err)
+	}
+	addUsage(ownerOf(im.userid, pid), obj.Size)
+	enqueueExtract(file, contentID)
+	indexFiles(file.ID)
+	im.result.Files++
+	im.result.Size += obj.Size
+	return file, nil
+}
+
+// ImportPrefix 将 profile 存储配置（为空时为默认云存储）中 prefix 下的对象按目录结构导入到 userid 的 pid 文件夹中（0 为根目录），
+// 文件直接引用原对象。已被文件内容记录引用的对象会被跳过，重复执行不会产生重复文件；fn 在每个新建文件后回调
+func ImportPrefix(profile string, prefix string, userid int64, pid int64, fn func(key string, file *gorm_gen.File)) (ImportResult, error) {
+	if profile == "" {
+		profile = defaultProvider()
+	}
+	im := &importer{profile: profile, userid: userid, folders: map[string]*gorm_gen.File{}}
+	if userid <= 0 {
+		return im.result, errors.New("请指定导入后的拥有者")
+	}
+	if pid > 0 {
+		root, err := query.Q.File.Where(query.File.ID.Eq(pid), query.File.Type.Eq("folder")).First()
+		if err != nil {
+			return im.result, errors.New("目标文件夹不存在")
+		}
+		if root.Userid != userid {
+			return im.result, errors.New("目标文件夹不属于指定的拥有者")
+		}
+		im.root = root
+	}
+	_, lister, err := profileBucket(profile)
+	if err != nil {
+		return im.result, err
+	}
+	refs, err := collectObjectRefs(profile)
+	if err != nil {
+		return im.result, err
+	}
+
+	marker := ""
+	for {
+		objects, next, err := lister.List(prefix, marker, 1000)
+		if err != nil {
+			return im.result, err
+		}
+		for _, obj := range objects {
+			dirs, filename := importTarget(prefix, obj.Key)
+			if strings.HasPrefix(obj.Key, quarantinePrefix) || isReferenced(obj.Key, refs) || (len(dirs) == 0 && filename == "") {
+				im.result.Skipped++
+				continue
+			}
+			if err := getPolicy().CheckDepth(folderDepth(pid) + int64(len(dirs))); err != nil {
+				return im.result, fmt.Errorf("%s: %v", obj.Key, err)
+			}
+			parent, err := im.folder(dirs)
+			if err != nil {
+				return im.result, err
+			}
+			if filename == "" {
+				continue
+			}
+			file, err := im.file(parent, filename, obj)
+			if err != nil {
+				return im.result, fmt.Errorf("%s: %v", obj.Key, err)
+			}
+			refs[obj.Key] = &objectRef{FileID: file.ID, Required: true}
+			if fn != nil {
+				fn(obj.Key, file)
+			}
+		}
+		if next == "" {
+			break
+		}
+		marker = next
+	}
+	return im.result, nil
+}
+
+// ImportBucket 管理员将存储配置中的前缀导入为文件夹树，userid 为 0 时归属当前会员
+func ImportBucket(user *User, profile string, prefix string, userid int64, pid int64) (ImportResult, error) {
+	if !isContain(user.Identity, "admin") {
+		return ImportResult{}, errors.New("仅限管理员操作")
+	}
+	if userid == 0 {
+		userid = int64(user.Userid)
+	}
+	return ImportPrefix(profile, prefix, userid, pid, nil)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_importTarget(t *testing.T) {
+	cases := []struct {
+		prefix, key string
+		dirs        []string
+		filename    string
+	}{
+		{"projects/", "projects/a.txt", []string{}, "a.txt"},
+		{"projects/", "projects/2020/设计/架构.drawio", []string{"2020", "设计"}, "架构.drawio"},
+		{"projects", "projects/2020//b.pdf", []string{"2020"}, "b.pdf"},
+		{"projects/", "projects/空文件夹/", []string{"空文件夹"}, ""},
+		{"projects/", "projects/", nil, ""},
+	}
+	for _, c := range cases {
+		dirs, filename := importTarget(c.prefix, c.key)
+		if !reflect.DeepEqual(dirs, c.dirs) || filename != c.filename {
+			t.Errorf("importTarget(%s, %s) = %v, %s", c.prefix, c.key, dirs, filename)
+		}
+	}
+}
+
+func Test_splitFileName(t *testing.T) {
+	cases := map[string][2]string{
+		"报告.docx":        {"报告", "docx"},
+		"archive.tar.gz": {"archive.tar", "gz"},
+		"Makefile":       {"Makefile", ""},
+		".env":           {".env", ""},
+	}
+	for filename, want := range cases {
+		if name, ext := splitFileName(filename); name != want[0] || ext != want[1] {
+			t.Errorf("splitFileName(%s) = %s, %s", filename, name, ext)
+		}
+	}
+}
+
+func Test_parsePids(t *testing.T) {
+	if got := parsePids(",3,7,"); !reflect.DeepEqual(got, []int64{3, 7}) {
+		t.Errorf("parsePids = %v", got)
+	}
+}
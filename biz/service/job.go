@@ -0,0 +1,503 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/pkg/config"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// 任务类型
+const (
+	JobDownload = "download"  // 将文件保存为离线文件
+	JobIoUpload = "io_upload" // 将离线文件上传为新内容
+	JobExtract  = "extract"   // 全文提取
+	JobMigrate  = "migrate"   // 将文件内容迁移到另一个云存储
+)
+
+// 任务状态
+const (
+	JobPending  = "pending"
+	JobRunning  = "running"
+	JobDone     = "done"
+	JobDead     = "dead"
+	JobCanceled = "canceled"
+)
+
+// jobLease 领取任务后的租约时长，执行期间定期续约，进程中断时任务在租约到期后被重新领取
+const jobLease = 5 * time.Minute
+
+// jobRestoreWait 归档对象恢复中时的重新检查间隔，等待恢复不计入执行次数
+const jobRestoreWait = 10 * time.Minute
+
+var (
+	jobOnce sync.Once
+	jobKick = make(chan struct{}, 1)
+
+	// ErrJobNotFound 任务不存在或无权查看
+	ErrJobNotFound = errors.New("任务不存在")
+	// ErrJobFinished 任务已结束，无法取消
+	ErrJobFinished = errors.New("任务已结束，无法取消")
+)
+
+// permanentError 重试也不会成功的错误，任务直接进入 dead
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// permanent 标记不需要重试的错误
+func permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// 各类任务的参数，提交者为任务的 Userid，执行时重新读取会员身份并校验权限
+type downloadJob struct {
+	FileID int64 `json:"file_id"`
+}
+
+type ioUploadJob struct {
+	FileID             int    `json:"file_id"`
+	Path               string `json:"path"` // 登记时的离线文件路径
+	WebkitRelativePath string `json:"webkit_relative_path"`
+	Overwrite          bool   `json:"overwrite"`
+	Filename           string `json:"filename"`
+	Hash               string `json:"hash"`
+}
+
+type extractJob struct {
+	ContentID int64 `json:"content_id"`
+}
+
+type migrateJob struct {
+	Options MigrateOptions `json:"options"`
+	Switch  bool           `json:"switch"`
+}
+
+// MigrateJobResult 迁移任务的执行结果
+type MigrateJobResult struct {
+	Total    int `json:"total"`
+	Skipped  int `json:"skipped"`
+	Failed   int `json:"failed"`
+	Switched int `json:"switched"`
+}
+
+// StartJobWorkers 启动 JOB_WORKERS 个协程执行到期的任务，重复调用只启动一次
+func StartJobWorkers() {
+	jobOnce.Do(func() {
+		for i := int64(0); i < max(config.JobWorkers, 1); i++ {
+			go jobWorker()
+		}
+	})
+}
+
+// jobWorker 持续执行到期的任务，有新任务或每 10 秒检查一次
+func jobWorker() {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+	for {
+		for processJobs() > 0 {
+		}
+		select {
+		case <-jobKick:
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueJob 登记任务并唤醒本进程的后台协程，userid 为 0 表示系统任务；
+// 未启动后台协程的进程（如 storagectl）登记的任务由服务进程执行
+func enqueueJob(userid int64, jobType string, payload interface{}) (*entity.FileJob, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &entity.FileJob{
+		Type:        jobType,
+		Userid:      userid,
+		Payload:     string(data),
+		Status:      JobPending,
+		MaxAttempts: int32(max(config.JobMaxAttempts, 1)),
+		NextAt:      time.Now(),
+	}
+	if err := query.Q.FileJob.Create(job); err != nil {
+		return nil, fmt.Errorf("登记任务失败: %v", err)
+	}
+	select {
+	case jobKick <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// EnqueueDownload 登记将文件保存为离线文件的任务，文件不存在或未配置本地目录时直接返回错误
+func EnqueueDownload(user *User, fileID int64) (*entity.FileJob, error) {
+	if _, err := query.Q.File.Where(query.File.ID.Eq(fileID)).First(); err != nil {
+		return nil, errors.New("文件不存在")
+	}
+	if config.LocalDownloadDir == "" {
+		return nil, errors.New("系统配置错误: 本地保存目录未配置")
+	}
+	return enqueueJob(int64(user.Userid), JobDownload, downloadJob{FileID: fileID})
+}
+
+// EnqueueIoUpload 登记将离线文件上传为新内容的任务；先校验写入权限、离线文件、上传策略和配额，
+// 不满足时直接返回错误，不登记任务
+func EnqueueIoUpload(user *User, fileID int, webkitRelativePath string, overwrite bool, filename string, hash string) (*entity.FileJob, error) {
+	row, err := permissionFind(fileID, user, 1)
+	if err != nil {
+		return nil, err
+	}
+	filePath, err := GetFileContentURL(int64(fileID))
+	if err != nil {
+		return nil, errors.New("获取文件路径失败")
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, errors.New("离线文件不存在")
+	}
+	if err := checkFileSize(row, info.Size()); err != nil {
+		return nil, err
+	}
+	if mtype, err := mimetype.DetectFile(filePath); err == nil {
+		if err := checkMime(row.Type, row.Ext, mtype.String()); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkQuota(ownerOf(row.Userid, row.Pid), info.Size()-row.Size); err != nil {
+		return nil, err
+	}
+	return enqueueJob(int64(user.Userid), JobIoUpload, ioUploadJob{
+		FileID:             fileID,
+		Path:               filePath,
+		WebkitRelativePath: webkitRelativePath,
+		Overwrite:          overwrite,
+		Filename:           filename,
+		Hash:               hash,
+	})
+}
+
+// EnqueueMigrate 登记迁移任务，switchAfter 为 true 时全部成功后切换文件内容记录
+func EnqueueMigrate(opts MigrateOptions, switchAfter bool) (*entity.FileJob, error) {
+	return enqueueJob(0, JobMigrate, migrateJob{Options: opts, Switch: switchAfter})
+}
+
+// processJobs 领取并执行一批到期的任务，返回执行的数量；
+// 租约过期的执行中任务视为进程中断，重新领取
+func processJobs() int {
+	rows, err := query.Q.FileJob.Where(
+		query.FileJob.Status.In(JobPending, JobRunning),
+		query.FileJob.NextAt.Lte(time.Now()),
+	).Order(query.FileJob.NextAt).Limit(10).Find()
+	if err != nil {
+		log.Printf("查询任务失败: %v", err)
+		return 0
+	}
+	processed := 0
+	for _, row := range rows {
+		if !claimJob(row) {
+			continue
+		}
+		runJob(row)
+		processed++
+	}
+	return processed
+}
+
+// claimJob 以租约领取任务，多个进程或协程不会重复执行同一任务；领取即计入执行次数，反复中断的任务也会进入 dead。
+// 查询后任务已被他人领取或状态已变化时返回 false
+func claimJob(row *entity.FileJob) bool {
+	info, err := query.Q.FileJob.Where(
+		query.FileJob.ID.Eq(row.ID),
+		query.FileJob.Status.Eq(row.Status),
+		query.FileJob.NextAt.Eq(row.NextAt),
+	).Updates(map[string]interface{}{
+		"status":   JobRunning,
+		"attempts": row.Attempts + 1,
+		"next_at":  time.Now().Add(jobLease),
+	})
+	if err != nil || info.RowsAffected == 0 {
+		return false
+	}
+	row.Status = JobRunning
+	row.Attempts++
+	return true
+}
+
+// runJob 执行任务并保存结果；执行期间被取消的任务保留取消状态，结果丢弃
+func runJob(job *entity.FileJob) {
+	var result interface{}
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		err = errors.New("执行中断次数过多")
+	} else {
+		stop := make(chan struct{})
+		go keepJobLease(job.ID, stop)
+		result, err = executeJob(job)
+		close(stop)
+	}
+
+	updates := jobOutcome(job, result, err, time.Now())
+	info, uerr := query.Q.FileJob.Where(
+		query.FileJob.ID.Eq(job.ID),
+		query.FileJob.Status.Eq(JobRunning),
+	).Updates(updates)
+	if uerr != nil {
+		log.Printf("更新任务失败, 任务ID: %d, 错误: %v", job.ID, uerr)
+		return
+	}
+	if info.RowsAffected == 0 {
+		log.Printf("任务已取消，丢弃执行结果, 任务ID: %d", job.ID)
+		return
+	}
+	if updates["status"] == JobDead {
+		log.Printf("任务失败，不再重试, 任务ID: %d, 类型: %s, 错误: %v", job.ID, job.Type, err)
+	}
+}
+
+// jobOutcome 根据执行结果计算任务的更新：成功为 done；归档恢复中时稍后重新检查，不计入执行次数；
+// 不需要重试的错误或已达到最大执行次数时为 dead，否则按退避时间重新排队
+func jobOutcome(job *entity.FileJob, result interface{}, err error, now time.Time) map[string]interface{} {
+	if err == nil {
+		data, _ := json.Marshal(result)
+		return map[string]interface{}{"status": JobDone, "error": "", "result": string(data)}
+	}
+	updates := map[string]interface{}{"error": capText(err.Error(), 1024)}
+	var perm permanentError
+	switch {
+	case errors.Is(err, ErrObjectRestoring):
+		updates["status"] = JobPending
+		updates["attempts"] = job.Attempts - 1
+		updates["next_at"] = now.Add(jobRestoreWait)
+	case errors.As(err, &perm) || job.Attempts >= job.MaxAttempts:
+		updates["status"] = JobDead
+	default:
+		updates["status"] = JobPending
+		updates["next_at"] = now.Add(jobBackoff(job.Attempts))
+	}
+	return updates
+}
+
+// jobBackoff 第 attempts 次失败后的重试间隔，从 30 秒开始翻倍，最长 1 小时
+func jobBackoff(attempts int32) time.Duration {
+	if attempts > 7 {
+		return time.Hour
+	}
+	return min(30*time.Second<<max(attempts-1, 0), time.Hour)
+}
+
+// keepJobLease 执行期间定期延长租约，避免长时间运行的任务被其他协程重新领取
+func keepJobLease(id int64, stop chan struct{}) {
+	ticker := time.NewTicker(jobLease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			query.Q.FileJob.Where(query.FileJob.ID.Eq(id), query.FileJob.Status.Eq(JobRunning)).
+				Update(query.FileJob.NextAt, time.Now().Add(jobLease))
+		}
+	}
+}
+
+// executeJob 按任务类型解析参数并执行，执行中的 panic 转为错误
+func executeJob(job *entity.FileJob) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("任务执行异常: %v", r)
+		}
+	}()
+	payload := []byte(job.Payload)
+	switch job.Type {
+	case JobDownload:
+		var p downloadJob
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, permanent(err)
+		}
+		path, err := SaveOffline(p.FileID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"path": path}, nil
+	case JobIoUpload:
+		var p ioUploadJob
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, permanent(err)
+		}
+		user, err := jobUser(job, p.FileID)
+		if err != nil {
+			return nil, err
+		}
+		file, err := os.Open(p.Path)
+		if os.IsNotExist(err) {
+			return nil, permanent(fmt.Errorf("离线文件不存在: %s", p.Path))
+		} else if err != nil {
+			return nil, fmt.Errorf("打开文件失败: %v", err)
+		}
+		defer file.Close()
+		item, err := Io_Upload(user, p.FileID, p.WebkitRelativePath, p.Overwrite, file, p.Filename, p.Hash)
+		if IsQuotaError(err) || IsPolicyError(err) {
+			return nil, permanent(err)
+		}
+		return item, err
+	case JobExtract:
+		var p extractJob
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, permanent(err)
+		}
+		err := ExtractContent(p.ContentID)
+		if errors.Is(err, ErrExtractUnsupported) {
+			return nil, permanent(err)
+		}
+		return nil, err
+	case JobMigrate:
+		var p migrateJob
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, permanent(err)
+		}
+		return runMigrateJob(p)
+	}
+	return nil, permanent(fmt.Errorf("未知的任务类型: %s", job.Type))
+}
+
+// jobUser 重新读取任务提交者的身份并校验其对文件 id 仍有写入权限，会员已禁用或权限已收回时不再重试
+func jobUser(job *entity.FileJob, id int) (*User, error) {
+	user, err := userByID(job.Userid)
+	if err != nil {
+		return nil, permanent(err)
+	}
+	if _, err := permissionFind(id, user, 1); err != nil {
+		return nil, permanent(err)
+	}
+	return user, nil
+}
+
+// SaveOffline 将文件保存到本地目录作为离线文件，并记录本地路径，返回本地路径
+func SaveOffline(fileID int64) (string, error) {
+	file, err := query.Q.File.Where(query.File.ID.Eq(fileID)).First()
+	if err != nil {
+		return "", permanent(errors.New("文件不存在"))
+	}
+	if config.LocalDownloadDir == "" {
+		return "", permanent(errors.New("系统配置错误: 本地保存目录未配置"))
+	}
+	localFilePath := filepath.Join(config.LocalDownloadDir, fmt.Sprintf("%d_%s.%s", fileID, file.Name, file.Ext))
+	// 已在下载缓存中时不再从云存储读取
+	if err := PinContent(GetContentMeta(file), localFilePath); err != nil {
+		return "", fmt.Errorf("保存文件失败: %w", err)
+	}
+	if err := UpdateFileContentURLInDB(fileID, localFilePath); err != nil {
+		return "", fmt.Errorf("更新文件信息失败: %v", err)
+	}
+	log.Printf("文件保存成功: %s, ID: %d", localFilePath, fileID)
+	return localFilePath, nil
+}
+
+// runMigrateJob 迁移全部对象，存在失败的对象时返回错误以便重试，已完成的对象由迁移进度跳过
+func runMigrateJob(p migrateJob) (*MigrateJobResult, error) {
+	result := &MigrateJobResult{}
+	failed, err := MigrateObjects(p.Options, func(r MigrateResult) {
+		result.Total++
+		if r.Status == "skipped" {
+			result.Skipped++
+		}
+	})
+	result.Failed = failed
+	if err != nil {
+		return nil, err
+	}
+	if failed > 0 {
+		return nil, fmt.Errorf("迁移失败 %d 个对象", failed)
+	}
+	if p.Switch {
+		if result.Switched, err = SwitchProvider(p.Options.Source, p.Options.Target); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// GetJob 返回任务，仅提交者和管理员可以查看
+func GetJob(user *User, id int64) (*entity.FileJob, error) {
+	job, err := query.Q.FileJob.Where(query.FileJob.ID.Eq(id)).First()
+	if err != nil {
+		return nil, ErrJobNotFound
+	}
+	if job.Userid != int64(user.Userid) && !isContain(user.Identity, "admin") {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// CancelJob 取消待执行或执行中的任务；执行中的任务会继续运行到结束，但结果丢弃且不再重试
+func CancelJob(user *User, id int64) (*entity.FileJob, error) {
+	if _, err := GetJob(user, id); err != nil {
+		return nil, err
+	}
+	return cancelJob(id)
+}
+
+func cancelJob(id int64) (*entity.FileJob, error) {
+	info, err := query.Q.FileJob.Where(
+		query.FileJob.ID.Eq(id),
+		query.FileJob.Status.In(JobPending, JobRunning),
+	).Update(query.FileJob.Status, JobCanceled)
+	if err != nil {
+		return nil, err
+	}
+	if info.RowsAffected == 0 {
+		return nil, ErrJobFinished
+	}
+	return query.Q.FileJob.Where(query.FileJob.ID.Eq(id)).First()
+}
+
+// ListJobs 按状态列出任务，status 为空时列出全部，最新的在前
+func ListJobs(status string, limit int) ([]*entity.FileJob, error) {
+	q := query.Q.FileJob.Order(query.FileJob.ID.Desc()).Limit(limit)
+	if status != "" {
+		q = q.Where(query.FileJob.Status.Eq(status))
+	}
+	return q.Find()
+}
+
+// RetryDeadJobs 将重试耗尽的任务重新排队，id 为 0 时重试全部，返回重新排队的数量
+func RetryDeadJobs(id int64) (int64, error) {
+	q := query.Q.FileJob.Where(query.FileJob.Status.Eq(JobDead))
+	if id != 0 {
+		q = q.Where(query.FileJob.ID.Eq(id))
+	}
+	info, err := q.Updates(map[string]interface{}{
+		"status":   JobPending,
+		"attempts": 0,
+		"next_at":  time.Now(),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return info.RowsAffected, nil
+}
+
+// CancelJobByID 不校验提交者，供 storagectl 使用
+func CancelJobByID(id int64) (*entity.FileJob, error) {
+	return cancelJob(id)
+}
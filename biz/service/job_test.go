@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+)
+
+func Test_jobBackoff(t *testing.T) {
+	cases := map[int32]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		5:  8 * time.Minute,
+		8:  time.Hour,
+		40: time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := jobBackoff(attempts); got != want {
+			t.Errorf("jobBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func Test_jobOutcome(t *testing.T) {
+	now := time.Now()
+	job := &entity.FileJob{Attempts: 2, MaxAttempts: 3}
+
+	updates := jobOutcome(job, map[string]string{"path": "/tmp/a"}, nil, now)
+	if updates["status"] != JobDone || updates["result"] != `{"path":"/tmp/a"}` {
+		t.Errorf("success: got %v", updates)
+	}
+
+	updates = jobOutcome(job, nil, errors.New("timeout"), now)
+	if updates["status"] != JobPending || updates["next_at"] != now.Add(time.Minute) || updates["error"] != "timeout" {
+		t.Errorf("retry: got %v", updates)
+	}
+
+	updates = jobOutcome(job, nil, permanent(errors.New("文件不存在")), now)
+	if updates["status"] != JobDead {
+		t.Errorf("permanent: got %v", updates)
+	}
+
+	updates = jobOutcome(&entity.FileJob{Attempts: 3, MaxAttempts: 3}, nil, errors.New("timeout"), now)
+	if updates["status"] != JobDead {
+		t.Errorf("exhausted: got %v", updates)
+	}
+
+	// 等待归档恢复不计入执行次数
+	updates = jobOutcome(&entity.FileJob{Attempts: 3, MaxAttempts: 3}, nil, fmt.Errorf("保存文件失败: %w", ErrObjectRestoring), now)
+	if updates["status"] != JobPending || updates["attempts"] != int32(2) || updates["next_at"] != now.Add(jobRestoreWait) {
+		t.Errorf("restoring: got %v", updates)
+	}
+}
+
+func loadJob(t *testing.T, id int64) *entity.FileJob {
+	t.Helper()
+	job, err := query.Q.FileJob.Where(query.FileJob.ID.Eq(id)).First()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// dueJob 将任务的下次执行时间（执行中为租约到期时间）改到过去
+func dueJob(t *testing.T, id int64) {
+	t.Helper()
+	_, err := query.Q.FileJob.Where(query.FileJob.ID.Eq(id)).Update(query.FileJob.NextAt, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_processJobsRetry(t *testing.T) {
+	newTestDB(t)
+	// 文件内容不存在是可重试的错误
+	job, err := enqueueJob(1, JobExtract, extractJob{ContentID: 404})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query.Q.FileJob.Where(query.FileJob.ID.Eq(job.ID)).Update(query.FileJob.MaxAttempts, 2)
+
+	if n := processJobs(); n != 1 {
+		t.Fatalf("first run: processed %d, want 1", n)
+	}
+	got := loadJob(t, job.ID)
+	if got.Status != JobPending || got.Attempts != 1 || got.Error == "" {
+		t.Fatalf("after first run: status=%s attempts=%d error=%q", got.Status, got.Attempts, got.Error)
+	}
+	if wait := time.Until(got.NextAt); wait < 20*time.Second || wait > jobBackoff(1) {
+		t.Errorf("backoff: next_at in %v, want about %v", wait, jobBackoff(1))
+	}
+
+	// 退避期间不会被领取
+	if n := processJobs(); n != 0 {
+		t.Fatalf("during backoff: processed %d, want 0", n)
+	}
+
+	dueJob(t, job.ID)
+	if n := processJobs(); n != 1 {
+		t.Fatalf("second run: processed %d, want 1", n)
+	}
+	if got := loadJob(t, job.ID); got.Status != JobDead || got.Attempts != 2 {
+		t.Fatalf("exhausted: status=%s attempts=%d, want dead/2", got.Status, got.Attempts)
+	}
+	if n := processJobs(); n != 0 {
+		t.Errorf("dead job claimed again: processed %d", n)
+	}
+}
+
+func Test_processJobsPermanent(t *testing.T) {
+	newTestDB(t)
+	job, err := enqueueJob(1, "unknown", struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := processJobs(); n != 1 {
+		t.Fatalf("processed %d, want 1", n)
+	}
+	if got := loadJob(t, job.ID); got.Status != JobDead || got.Attempts != 1 {
+		t.Errorf("status=%s attempts=%d, want dead/1", got.Status, got.Attempts)
+	}
+}
+
+func Test_processJobsLease(t *testing.T) {
+	newTestDB(t)
+	// 执行中且租约未到期的任务不会被其他进程领取
+	running := &entity.FileJob{
+		Type:        JobExtract,
+		Payload:     `{"content_id":404}`,
+		Status:      JobRunning,
+		Attempts:    1,
+		MaxAttempts: 3,
+		NextAt:      time.Now().Add(jobLease),
+	}
+	if err := query.Q.FileJob.Create(running); err != nil {
+		t.Fatal(err)
+	}
+	if n := processJobs(); n != 0 {
+		t.Fatalf("lease held: processed %d, want 0", n)
+	}
+
+	// 租约到期视为进程中断，重新领取并计入执行次数
+	dueJob(t, running.ID)
+	if n := processJobs(); n != 1 {
+		t.Fatalf("lease expired: processed %d, want 1", n)
+	}
+	if got := loadJob(t, running.ID); got.Status != JobPending || got.Attempts != 2 {
+		t.Fatalf("re-claimed: status=%s attempts=%d, want pending/2", got.Status, got.Attempts)
+	}
+
+	// 反复中断到超过最大执行次数后不再执行，直接进入 dead
+	query.Q.FileJob.Where(query.FileJob.ID.Eq(running.ID)).Updates(map[string]interface{}{
+		"status":   JobRunning,
+		"attempts": 3,
+	})
+	dueJob(t, running.ID)
+	if n := processJobs(); n != 1 {
+		t.Fatalf("interrupted: processed %d, want 1", n)
+	}
+	got := loadJob(t, running.ID)
+	if got.Status != JobDead || got.Attempts != 4 || got.Error != "执行中断次数过多" {
+		t.Errorf("interrupted: status=%s attempts=%d error=%q", got.Status, got.Attempts, got.Error)
+	}
+}
+
+func Test_claimJob(t *testing.T) {
+	newTestDB(t)
+	job, err := enqueueJob(1, JobExtract, extractJob{ContentID: 404})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个进程查询到同一任务，只有先领取的成功
+	a, b := loadJob(t, job.ID), loadJob(t, job.ID)
+	if !claimJob(a) {
+		t.Fatal("first claim failed")
+	}
+	if a.Status != JobRunning || a.Attempts != 1 {
+		t.Errorf("claimed: status=%s attempts=%d", a.Status, a.Attempts)
+	}
+	if claimJob(b) {
+		t.Fatal("job claimed twice")
+	}
+
+	// 租约到期后，持有过期数据的进程也不能抢占刚重新领取的任务
+	dueJob(t, job.ID)
+	c, d := loadJob(t, job.ID), loadJob(t, job.ID)
+	if !claimJob(c) || c.Attempts != 2 {
+		t.Fatalf("re-claim after lease expiry failed: attempts=%d", c.Attempts)
+	}
+	if claimJob(d) {
+		t.Error("expired lease claimed twice")
+	}
+}
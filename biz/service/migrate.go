@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"golang.org/x/time/rate"
+	"gorm.io/gen"
+	"gorm.io/gorm/clause"
+)
+
+// MigrateOptions 跨云存储迁移参数
+type MigrateOptions struct {
+	Source      string  // 源存储：aliyun、tencent、qiniu
+	Target      string  // 目标存储
+	Concurrency int     // 并发迁移的对象数
+	QPS         float64 // 每秒最多开始迁移的对象数，0 表示不限
+	BPS         int64   // 每秒最多读取的字节数（所有并发合计），0 表示不限
+	Verify      bool    // 写入后重新下载目标对象比对 SHA-256
+}
+
+// MigrateResult 单个对象的迁移结果
+type MigrateResult struct {
+	Key    string
+	Size   int64
+	Status string // done / skipped / failed
+	Err    error
+}
+
+// migrateObject 待迁移的对象及记录的大小、校验值
+type migrateObject struct {
+	Key  string
+	Size int64
+	Hash string
+}
+
+// contentProvider 返回内容所在的存储配置ID或云存储名称
+func contentProvider(meta ContentMeta) string {
+	if meta.Profile == "" {
+		return legacyProvider
+	}
+	return meta.Profile
+}
+
+// collectMigrateObjects 遍历全部文件（含回收站中的）的各个版本中存放在 provider 的内容，按对象路径去重
+func collectMigrateObjects(provider string) ([]migrateObject, error) {
+	var objects []migrateObject
+	seen := map[string]bool{}
+	var files []*gorm_gen.File
+	err := query.Q.File.Unscoped().Where(query.File.Type.Neq("folder")).FindInBatches(&files, 100, func(tx gen.Dao, batch int) error {
+		ids := make([]int64, 0, len(files))
+		byID := map[int64]*gorm_gen.File{}
+		for _, file := range files {
+			ids = append(ids, file.ID)
+			byID[file.ID] = file
+		}
+		contents, err := query.Q.FileContent.Unscoped().Where(query.FileContent.Fid.In(ids...)).Find()
+		if err != nil {
+			return err
+		}
+		for _, content := range contents {
+			meta := parseContentMeta(content)
+			if contentProvider(meta) != provider {
+				continue
+			}
+			if meta.Key == "" {
+				meta.Key = GetFilePath(byID[content.Fid])
+			}
+			if seen[meta.Key] {
+				continue
+			}
+			seen[meta.Key] = true
+			if meta.Envelope != nil || meta.Encoding != "" {
+				// 加密或压缩的内容按存储的数据原样复制，记录的大小和校验值为原内容的，不参与比对
+				objects = append(objects, migrateObject{Key: meta.Key})
+				continue
+			}
+			objects = append(objects, migrateObject{Key: meta.Key, Size: content.Size, Hash: meta.Hash})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk files: %v", err)
+	}
+	return objects, nil
+}
+
+// rateReader 按字节数限速的 Reader，多个 Reader 共享同一个 limiter 时限制总速率
+type rateReader struct {
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (r *rateReader) Read(p []byte) (int, error) {
+	if len(p) > r.limiter.Burst() {
+		p = p[:r.limiter.Burst()]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.limiter.WaitN(context.Background(), n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+// copyObject 将对象从源存储复制到目标存储，复制前后校验大小和 SHA-256
+func copyObject(src, dst ObjectStore, obj migrateObject, limiter *rate.Limiter, verify bool) (int64, error) {
+	body, err := src.GetObject(obj.Key)
+	if err != nil {
+		return 0, fmt.Errorf("读取源对象失败: %v", err)
+	}
+	defer body.Close()
+	var reader io.Reader = body
+	if limiter != nil {
+		reader = &rateReader{r: body, limiter: limiter}
+	}
+	sf, err := spool(reader)
+	if err != nil {
+		return 0, err
+	}
+	defer sf.Remove()
+
+	if obj.Size > 0 && sf.Size != obj.Size {
+		return sf.Size, fmt.Errorf("源对象大小不一致: 记录 %d, 实际 %d", obj.Size, sf.Size)
+	}
+	if obj.Hash != "" && sf.Hash != obj.Hash {
+		return sf.Size, fmt.Errorf("源对象校验值不一致: 记录 %s, 实际 %s", obj.Hash, sf.Hash)
+	}
+
+	size, err := dst.PutObject(sf.File, obj.Key, PutOptions{
+		ContentMD5: base64.StdEncoding.EncodeToString(sf.MD5),
+		CRC64:      strconv.FormatUint(sf.CRC64, 10),
+	})
+	if err != nil {
+		return sf.Size, fmt.Errorf("写入目标对象失败: %v", err)
+	}
+	if size != sf.Size {
+		return sf.Size, fmt.Errorf("目标对象大小不一致: 源 %d, 目标 %d", sf.Size, size)
+	}
+
+	if verify {
+		copied, err := dst.GetObject(obj.Key)
+		if err != nil {
+			return sf.Size, fmt.Errorf("读取目标对象失败: %v", err)
+		}
+		defer copied.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, copied); err != nil {
+			return sf.Size, fmt.Errorf("读取目标对象失败: %v", err)
+		}
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != sf.Hash {
+			return sf.Size, fmt.Errorf("目标对象校验值不一致: 源 %s, 目标 %s", sf.Hash, actual)
+		}
+	}
+	return sf.Size, nil
+}
+
+// saveCheckpoint 记录对象的迁移状态，重复迁移时覆盖
+func saveCheckpoint(opts MigrateOptions, obj migrateObject, result MigrateResult) error {
+	record := &entity.FileMigration{
+		Source:    opts.Source,
+		Target:    opts.Target,
+		ObjectKey: obj.Key,
+		Size:      result.Size,
+		Hash:      obj.Hash,
+		Status:    result.Status,
+	}
+	if result.Err != nil {
+		record.Error = capText(result.Err.Error(), 1024)
+	}
+	return query.Q.FileMigration.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "source"}, {Name: "target"}, {Name: "object_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size", "hash", "status", "error", "updated_at"}),
+	}).Create(record)
+}
+
+// MigrateObjects 将全部文件内容从源存储复制到目标存储；已完成的对象按检查点跳过，失败的对象在下次执行时重试。
+// 返回本次失败的数量
+func MigrateObjects(opts MigrateOptions, fn func(MigrateResult)) (int, error) {
+	if opts.Source == opts.Target {
+		return 0, fmt.Errorf("源存储与目标存储相同: %s", opts.Source)
+	}
+	src, err := getObjectStore(opts.Source)
+	if err != nil {
+		return 0, err
+	}
+	dst, err := getObjectStore(opts.Target)
+	if err != nil {
+		return 0, err
+	}
+	objects, err := collectMigrateObjects(opts.Source)
+	if err != nil {
+		return 0, err
+	}
+
+	done := map[string]bool{}
+	var checkpoints []*entity.FileMigration
+	err = query.Q.FileMigration.Where(
+		query.FileMigration.Source.Eq(opts.Source),
+		query.FileMigration.Target.Eq(opts.Target),
+		query.FileMigration.Status.Eq("done"),
+	).FindInBatches(&checkpoints, 1000, func(tx gen.Dao, batch int) error {
+		for _, c := range checkpoints {
+			done[c.ObjectKey] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to load checkpoints: %v", err)
+	}
+
+	var objectLimiter, byteLimiter *rate.Limiter
+	if opts.QPS > 0 {
+		objectLimiter = rate.NewLimiter(rate.Limit(opts.QPS), 1)
+	}
+	if opts.BPS > 0 {
+		byteLimiter = rate.NewLimiter(rate.Limit(opts.BPS), int(min(opts.BPS, 1<<20)))
+	}
+
+	jobs := make(chan migrateObject)
+	results := make(chan MigrateResult)
+	var wg sync.WaitGroup
+	for i := 0; i < max(opts.Concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range jobs {
+				if objectLimiter != nil {
+					objectLimiter.Wait(context.Background())
+				}
+				result := MigrateResult{Key: obj.Key, Status: "done"}
+				result.Size, result.Err = copyObject(src, dst, obj, byteLimiter, opts.Verify)
+				if result.Err != nil {
+					result.Status = "failed"
+				}
+				if err := saveCheckpoint(opts, obj, result); err != nil && result.Err == nil {
+					result.Status = "failed"
+					result.Err = fmt.Errorf("记录检查点失败: %v", err)
+				}
+				results <- result
+			}
+		}()
+	}
+	go func() {
+		for _, obj := range objects {
+			if done[obj.Key] {
+				results <- MigrateResult{Key: obj.Key, Size: obj.Size, Status: "skipped"}
+				continue
+			}
+			jobs <- obj
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	failed := 0
+	for result := range results {
+		if result.Status == "failed" {
+			failed++
+		}
+		fn(result)
+	}
+	return failed, nil
+}
+
+// providerBaseURL 返回云存储对象访问地址的前缀（不含协议），未配置时返回空字符串
+func providerBaseURL(provider string) string {
+	trim := func(s string) string {
+		s = strings.TrimPrefix(strings.TrimPrefix(s, "https://"), "http://")
+		return strings.TrimRight(s, "/")
+	}
+	switch provider {
+	case "aliyun":
+		if bucket, endpoint := os.Getenv("OSS_BUCKET"), trim(os.Getenv("OSS_ENDPOINT")); bucket != "" && endpoint != "" {
+			return bucket + "." + endpoint
+		}
+	case "tencent":
+		if bucket, region := os.Getenv("COS_BUCKET"), os.Getenv("COS_REGION"); bucket != "" && region != "" {
+			return fmt.Sprintf("%s.cos.%s.myqcloud.com", bucket, region)
+		}
+	case "qiniu":
+		return trim(os.Getenv("QINIU_ENDPOINT"))
+	}
+	return ""
+}
+
+// rewriteContentURLs 将 content 中指向源存储的地址改为目标存储，并记录内容所在的存储；返回是否有修改
+func rewriteContentURLs(content map[string]interface{}, target string, fromBase string, toBase string) bool {
+	changed := false
+	if content["provider"] != target {
+		content["provider"] = target
+		changed = true
+	}
+	if fromBase == "" || toBase == "" {
+		return changed
+	}
+	for _, field := range []string{"url", "cloud_url", "from"} {
+		value, ok := content[field].(string)
+		if !ok {
+			continue
+		}
+		scheme := ""
+		rest := value
+		for _, prefix := range []string{"https://", "http://"} {
+			if strings.HasPrefix(value, prefix) {
+				scheme, rest = prefix, strings.TrimPrefix(value, prefix)
+			}
+		}
+		if scheme == "" || !strings.HasPrefix(rest, fromBase+"/") {
+			continue
+		}
+		content[field] = "https://" + toBase + strings.TrimPrefix(rest, fromBase)
+		changed = true
+	}
+	return changed
+}
+
+// SwitchProvider 将源存储中的文件内容记录和已登记内容切换到目标存储，返回修改的记录数
+func SwitchProvider(source string, target string) (int, error) {
+	fromBase, toBase := providerBaseURL(source), providerBaseURL(target)
+	updated := 0
+	var contents []*gorm_gen.FileContent
+	err := query.Q.FileContent.Unscoped().FindInBatches(&contents, 100, func(tx gen.Dao, batch int) error {
+		for _, fc := range contents {
+			var content map[string]interface{}
+			if err := json.Unmarshal([]byte(fc.Content), &content); err != nil || content == nil {
+				continue
+			}
+			if contentProvider(parseContentMeta(fc)) != source {
+				continue
+			}
+			// 存储位置改由 provider 记录
+			_, hasProfile := content["profile"]
+			delete(content, "profile")
+			if !rewriteContentURLs(content, target, fromBase, toBase) && !hasProfile {
+				continue
+			}
+			data, err := json.Marshal(content)
+			if err != nil {
+				return err
+			}
+			if _, err := query.Q.FileContent.Unscoped().Where(query.FileContent.ID.Eq(fc.ID)).Update(query.FileContent.Content, string(data)); err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return updated, fmt.Errorf("failed to switch file contents: %v", err)
+	}
+	profiles := []string{source}
+	if source == legacyProvider {
+		profiles = append(profiles, "")
+	}
+	_, err = query.Q.FileBlob.Where(query.FileBlob.Profile.In(profiles...)).Update(query.FileBlob.Profile, target)
+	if err != nil {
+		return updated, fmt.Errorf("failed to switch file blobs: %v", err)
+	}
+	return updated, nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func Test_rewriteContentURLs(t *testing.T) {
+	content := map[string]interface{}{
+		"url":       "http://cdn.example.com/docs/a.txt",
+		"cloud_url": "http://server/api/file/content/downloading?id=1",
+		"from":      "https://cdn.example.com/docs/b.txt",
+		"key":       "docs/a.txt",
+	}
+	if !rewriteContentURLs(content, "aliyun", "cdn.example.com", "bucket.oss-cn-hangzhou.aliyuncs.com") {
+		t.Fatal("expected content to change")
+	}
+	if content["url"] != "https://bucket.oss-cn-hangzhou.aliyuncs.com/docs/a.txt" {
+		t.Errorf("unexpected url %v", content["url"])
+	}
+	if content["from"] != "https://bucket.oss-cn-hangzhou.aliyuncs.com/docs/b.txt" {
+		t.Errorf("unexpected from %v", content["from"])
+	}
+	if content["cloud_url"] != "http://server/api/file/content/downloading?id=1" || content["provider"] != "aliyun" {
+		t.Errorf("unexpected content %v", content)
+	}
+	// 再次切换时不再修改
+	if rewriteContentURLs(content, "aliyun", "cdn.example.com", "bucket.oss-cn-hangzhou.aliyuncs.com") {
+		t.Error("expected no change on second run")
+	}
+	// 前缀相同但域名不同的地址不修改
+	other := map[string]interface{}{"provider": "aliyun", "url": "http://cdn.example.com.evil/a.txt"}
+	if rewriteContentURLs(other, "aliyun", "cdn.example.com", "bucket.oss-cn-hangzhou.aliyuncs.com") {
+		t.Errorf("unexpected rewrite %v", other)
+	}
+}
+
+func Test_rateReader(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 3000)
+	limiter := rate.NewLimiter(rate.Limit(10000), 1000)
+	start := time.Now()
+	n, err := io.Copy(io.Discard, &rateReader{r: bytes.NewReader(data), limiter: limiter})
+	if err != nil || n != int64(len(data)) {
+		t.Fatalf("unexpected copy %d, %v", n, err)
+	}
+	// 首个 1000 字节使用初始令牌，其余 2000 字节至少需要 0.2 秒
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("reader not limited, elapsed %v", elapsed)
+	}
+}
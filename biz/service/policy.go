@@ -0,0 +1,240 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
+	"github.com/cloudisk/pkg/constant"
+	utils "github.com/cloudisk/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy 上传策略，数值为 0 时表示不限制
+type Policy struct {
+	FolderMaxItems    int64            `yaml:"folder_max_items"`      // 每个文件夹最多的文件或文件夹数
+	FolderMaxDepth    int64            `yaml:"folder_max_depth"`      // 文件夹最大层级
+	FileMaxSize       int64            `yaml:"file_max_size"`         // 单个文件最大大小(B)
+	FileMaxSizeByType map[string]int64 `yaml:"file_max_size_by_type"` // 按 getFileType 分组的最大大小(B)，未识别的类型为 other
+	AllowedExts       []string         `yaml:"allowed_exts"`          // 允许的扩展名，为空时不限制
+	BlockedExts       []string         `yaml:"blocked_exts"`          // 禁止的扩展名，优先于允许列表
+	MimeReject        bool             `yaml:"mime_reject_mismatch"`  // 文件内容与扩展名不符时拒绝上传
+}
+
+var (
+	policy     *Policy
+	policyOnce sync.Once
+)
+
+// getPolicy 返回当前生效的上传策略：先读取环境变量，再由 POLICY_FILE 覆盖
+func getPolicy() *Policy {
+	policyOnce.Do(func() {
+		policy = &Policy{
+			FolderMaxItems:    config.FolderMaxItems,
+			FolderMaxDepth:    config.FolderMaxDepth,
+			FileMaxSize:       config.FileMaxSize,
+			FileMaxSizeByType: config.FileMaxSizeByType,
+			AllowedExts:       config.FileAllowedExts,
+			BlockedExts:       config.FileBlockedExts,
+			MimeReject:        config.MimeRejectMismatch,
+		}
+		defer policy.normalize()
+		if config.PolicyFile == "" {
+			return
+		}
+		data, err := os.ReadFile(config.PolicyFile)
+		if err != nil {
+			log.Printf("读取上传策略文件失败: %s, 错误: %v", config.PolicyFile, err)
+			return
+		}
+		if err := yaml.Unmarshal(data, policy); err != nil {
+			log.Printf("解析上传策略文件失败: %s, 错误: %v", config.PolicyFile, err)
+		}
+	})
+	return policy
+}
+
+// normalize 统一扩展名和类型名称的大小写
+func (p *Policy) normalize() {
+	lower := func(items []string) []string {
+		for i, item := range items {
+			items[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(item), "."))
+		}
+		return items
+	}
+	p.AllowedExts = lower(p.AllowedExts)
+	p.BlockedExts = lower(p.BlockedExts)
+	sizes := make(map[string]int64, len(p.FileMaxSizeByType))
+	for fileType, size := range p.FileMaxSizeByType {
+		sizes[strings.ToLower(fileType)] = size
+	}
+	p.FileMaxSizeByType = sizes
+}
+
+// IsPolicyError 判断错误是否为违反上传策略
+func IsPolicyError(err error) bool {
+	var withErr utils.WithError
+	if !errors.As(err, &withErr) {
+		return false
+	}
+	switch withErr.Msg {
+	case constant.ErrPolicyFolderItems, constant.ErrPolicyFolderDepth, constant.ErrPolicyFileSize, constant.ErrPolicyFileExt,
+		constant.ErrPolicyFileMime:
+		return true
+	}
+	return false
+}
+
+// policyExt 返回用于策略判断的扩展名，没有扩展名时返回空
+func policyExt(filename string) string {
+	index := strings.LastIndex(filename, ".")
+	if index == -1 {
+		return ""
+	}
+	return strings.ToLower(filename[index+1:])
+}
+
+// CheckName 检查文件扩展名是否允许上传
+func (p *Policy) CheckName(filename string) error {
+	ext := policyExt(filename)
+	allowed := len(p.AllowedExts) == 0 || isContain(p.AllowedExts, ext)
+	if !allowed || isContain(p.BlockedExts, ext) {
+		if ext == "" {
+			ext = filename
+		}
+		return utils.WithMap(constant.ErrPolicyFileExt, map[string]any{"ext": ext},
+			fmt.Errorf("不允许上传 %s 类型的文件", ext))
+	}
+	return nil
+}
+
+// CheckSize 检查文件大小，优先使用文件类型（getFileType 的结果）对应的上限
+func (p *Policy) CheckSize(fileType string, size int64) error {
+	if fileType == "" {
+		fileType = "other"
+	}
+	limit, ok := p.FileMaxSizeByType[fileType]
+	if !ok {
+		limit = p.FileMaxSize
+	}
+	if limit > 0 && size > limit {
+		return utils.WithMap(constant.ErrPolicyFileSize, map[string]any{"limit": formatSize(limit)},
+			fmt.Errorf("文件大小不能超过%s", formatSize(limit)))
+	}
+	return nil
+}
+
+// CheckItems 检查文件夹中已有 count 项时能否再新增
+func (p *Policy) CheckItems(count int64) error {
+	if p.FolderMaxItems > 0 && count >= p.FolderMaxItems {
+		return utils.WithMap(constant.ErrPolicyFolderItems, map[string]any{"limit": p.FolderMaxItems},
+			fmt.Errorf("每个文件夹里最多只能创建%d个文件或文件夹", p.FolderMaxItems))
+	}
+	return nil
+}
+
+// CheckDepth 检查文件夹层级，depth 为新文件夹所在的层级（根目录下为 1）
+func (p *Policy) CheckDepth(depth int64) error {
+	if p.FolderMaxDepth > 0 && depth > p.FolderMaxDepth {
+		return utils.WithMap(constant.ErrPolicyFolderDepth, map[string]any{"limit": p.FolderMaxDepth},
+			fmt.Errorf("文件夹层级不能超过%d层", p.FolderMaxDepth))
+	}
+	return nil
+}
+
+// executableMimes 可执行文件的类型，扩展名不是可执行文件时视为伪装
+var executableMimes = []string{
+	"application/vnd.microsoft.portable-executable",
+	"application/x-elf",
+	"application/x-executable",
+	"application/x-sharedlib",
+	"application/x-mach-binary",
+}
+
+// executableExts 允许内容为可执行文件的扩展名
+var executableExts = []string{"exe", "dll", "sys", "msi", "com", "scr", "so", "o", "elf", "bin", "out", "dylib", "macho"}
+
+// mimeMatches 判断按文件头识别的类型是否与文件类型、扩展名相符，无法识别的内容视为相符
+func mimeMatches(fileType string, ext string, contentType string) bool {
+	contentType, _, _ = strings.Cut(contentType, ";")
+	if contentType == "" || contentType == "application/octet-stream" {
+		return true
+	}
+	if isContain(executableMimes, contentType) {
+		return isContain(executableExts, strings.ToLower(ext))
+	}
+	switch fileType {
+	case "picture", "tif":
+		return strings.HasPrefix(contentType, "image/")
+	case "pdf":
+		return contentType == "application/pdf"
+	case "media":
+		return strings.HasPrefix(contentType, "audio/") || strings.HasPrefix(contentType, "video/")
+	}
+	return true
+}
+
+// CheckMime 检查文件内容与扩展名是否相符，未开启拒绝时只记录日志
+func (p *Policy) CheckMime(fileType string, ext string, contentType string) error {
+	if mimeMatches(fileType, ext, contentType) {
+		return nil
+	}
+	log.Printf("文件内容与扩展名不符: ext=%s, mime=%s", ext, contentType)
+	if !p.MimeReject {
+		return nil
+	}
+	return utils.WithMap(constant.ErrPolicyFileMime, map[string]any{"ext": ext, "mime": contentType},
+		fmt.Errorf("文件内容（%s）与扩展名 %s 不符", contentType, ext))
+}
+
+// checkFile 检查新上传文件的文件名和大小
+func checkFile(filename string, size int64) error {
+	p := getPolicy()
+	if err := p.CheckName(filename); err != nil {
+		return err
+	}
+	return p.CheckSize(getFileType(filename), size)
+}
+
+// checkFileSize 检查已有文件写入新内容后的大小
+func checkFileSize(file *gorm_gen.File, size int64) error {
+	return getPolicy().CheckSize(file.Type, size)
+}
+
+// checkMime 检查写入内容的真实类型
+func checkMime(fileType string, ext string, contentType string) error {
+	return getPolicy().CheckMime(fileType, ext, contentType)
+}
+
+// checkFolderItems 检查文件夹（pid 为 0 时为会员根目录）能否再新增一项
+func checkFolderItems(userid int64, pid int64) error {
+	do := query.Q.File.Where(query.File.Pid.Eq(pid))
+	if pid == 0 {
+		do = do.Where(query.File.Userid.Eq(userid))
+	}
+	count, err := do.Count()
+	if err != nil {
+		return fmt.Errorf("统计文件数量失败: %v", err)
+	}
+	return getPolicy().CheckItems(count)
+}
+
+// folderDepth 返回文件夹的层级，根目录为 0
+func folderDepth(pid int64) int64 {
+	var depth int64
+	for pid > 0 {
+		folder, err := query.Q.File.Where(query.File.ID.Eq(pid)).First()
+		if err != nil {
+			break
+		}
+		depth++
+		pid = folder.Pid
+	}
+	return depth
+}
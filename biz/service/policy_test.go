@@ -0,0 +1,74 @@
+package service
+
+import "testing"
+
+func Test_PolicyCheckName(t *testing.T) {
+	p := &Policy{AllowedExts: []string{"png", "pdf"}, BlockedExts: []string{"pdf"}}
+	if err := p.CheckName("a.PNG"); err != nil {
+		t.Errorf("png should be allowed: %v", err)
+	}
+	if err := p.CheckName("a.pdf"); err == nil || !IsPolicyError(err) {
+		t.Errorf("blocked ext should be rejected with a policy error, got %v", err)
+	}
+	if err := p.CheckName("Makefile"); err == nil {
+		t.Errorf("file without ext should be rejected when allow list is set")
+	}
+}
+
+func Test_PolicyCheckSize(t *testing.T) {
+	p := &Policy{FileMaxSize: 100, FileMaxSizeByType: map[string]int64{"media": 1000, "other": 10}}
+	cases := []struct {
+		fileType string
+		size     int64
+		ok       bool
+	}{
+		{"media", 500, true},
+		{"media", 1001, false},
+		{"pdf", 100, true},
+		{"pdf", 101, false},
+		{"", 11, false},
+	}
+	for _, c := range cases {
+		if err := p.CheckSize(c.fileType, c.size); (err == nil) != c.ok {
+			t.Errorf("CheckSize(%q, %d) = %v", c.fileType, c.size, err)
+		}
+	}
+}
+
+func Test_PolicyLimits(t *testing.T) {
+	p := &Policy{FolderMaxItems: 300, FolderMaxDepth: 3}
+	if p.CheckItems(299) != nil || p.CheckItems(300) == nil {
+		t.Errorf("unexpected folder item check")
+	}
+	if p.CheckDepth(3) != nil || p.CheckDepth(4) == nil {
+		t.Errorf("unexpected folder depth check")
+	}
+	if (&Policy{}).CheckItems(1<<20) != nil {
+		t.Errorf("zero limit should be unlimited")
+	}
+}
+
+func Test_MimeMatches(t *testing.T) {
+	cases := []struct {
+		fileType, ext, mime string
+		ok                  bool
+	}{
+		{"picture", "jpg", "image/jpeg", true},
+		{"picture", "jpg", "application/vnd.microsoft.portable-executable", false},
+		{"", "exe", "application/vnd.microsoft.portable-executable", true},
+		{"pdf", "pdf", "text/plain; charset=utf-8", false},
+		{"code", "go", "text/plain; charset=utf-8", true},
+		{"media", "mp4", "application/octet-stream", true},
+	}
+	for _, c := range cases {
+		if got := mimeMatches(c.fileType, c.ext, c.mime); got != c.ok {
+			t.Errorf("mimeMatches(%q, %q, %q) = %v", c.fileType, c.ext, c.mime, got)
+		}
+	}
+	if err := (&Policy{}).CheckMime("picture", "jpg", "application/x-elf"); err != nil {
+		t.Errorf("mismatch should only be logged when rejection is off: %v", err)
+	}
+	if err := (&Policy{MimeReject: true}).CheckMime("picture", "jpg", "application/x-elf"); !IsPolicyError(err) {
+		t.Errorf("mismatch should be rejected, got %v", err)
+	}
+}
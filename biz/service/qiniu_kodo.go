@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -11,15 +12,20 @@ import (
 	"time"
 
 	"github.com/qiniu/go-sdk/v7/auth/qbox"
+	"github.com/qiniu/go-sdk/v7/client"
 	"github.com/qiniu/go-sdk/v7/storage"
 )
 
+// qiniuNoSuchFile 七牛返回的文件不存在状态码
+const qiniuNoSuchFile = 612
+
 type QiniuCommoner struct {
 	accessKey  string
 	secretKey  string
 	bucketName string
 	endpoint   string
 	zone       string
+	config     *storage.Config // 指定存储区域的配置（如私有部署），为空时按 zone 选择公有云区域
 }
 
 // getZone 根据区域名称获取存储区域配置
@@ -42,6 +48,9 @@ func getZone(zoneName string) *storage.Zone {
 
 // getConfig 获取存储配置
 func (q *QiniuCommoner) getConfig() *storage.Config {
+	if q.config != nil {
+		return q.config
+	}
 	return &storage.Config{
 		Zone:          getZone(q.zone),
 		UseCdnDomains: false,
@@ -59,8 +68,10 @@ func NewQiniuClient() *QiniuCommoner {
 	}
 }
 
-// Upload 将文件上传到七牛云
-func (q *QiniuCommoner) Upload(file multipart.File, objectName string) (int64, error) {
+// Upload 将文件上传到七牛云，对象路径与阿里云一致按文件夹层级拼接
+func (q *QiniuCommoner) Upload(file multipart.File, objectName string, pid int64) (int64, error) {
+	objectName = uploadPath(objectName, pid)
+
 	// 创建凭证
 	mac := qbox.NewMac(q.accessKey, q.secretKey)
 
@@ -137,6 +148,44 @@ func (q *QiniuCommoner) ReaderUpload(file io.ReadCloser, objectName string) (int
 	return fileInfo.Fsize, nil
 }
 
+// PutObject 上传对象；七牛不支持 Content-MD5 校验，opts 中的校验值不生效，迁移时可开启下载复核
+func (q *QiniuCommoner) PutObject(file io.Reader, objectName string, opts PutOptions) (int64, error) {
+	return q.ReaderUpload(io.NopCloser(file), objectName)
+}
+
+// GetObject 通过私有下载链接以流的方式读取对象内容，调用方负责关闭
+func (q *QiniuCommoner) GetObject(objectName string) (io.ReadCloser, error) {
+	return q.getObject(objectName, "")
+}
+
+// GetObjectRange 通过私有下载链接以流的方式读取对象的一段内容，调用方负责关闭
+func (q *QiniuCommoner) GetObjectRange(objectName string, offset int64, length int64) (io.ReadCloser, error) {
+	return q.getObject(objectName, httpRange(offset, length))
+}
+
+func (q *QiniuCommoner) getObject(objectName string, byteRange string) (io.ReadCloser, error) {
+	deadline := time.Now().Add(time.Hour).Unix()
+	req, err := http.NewRequest(http.MethodGet, q.GeneratePrivateURL(objectName, deadline), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object: %w", err)
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download object: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == qiniuNoSuchFile {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, objectName)
+		}
+		return nil, fmt.Errorf("failed to download object, status code: %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
 // GeneratePublicURL 生成公开访问的下载链接
 func (q *QiniuCommoner) GeneratePublicURL(objectName string) string {
 	return storage.MakePublicURL(q.endpoint, objectName)
@@ -216,11 +265,53 @@ func (q *QiniuCommoner) DownloadFileToLocal(objectName string) (string, error) {
 	return localFilePath, nil
 }
 
-// Delete 从七牛云中删除文件
+// Delete 从七牛云中删除文件，文件不存在时不报错
 func (q *QiniuCommoner) Delete(objectName string) error {
 	mac := qbox.NewMac(q.accessKey, q.secretKey)
 	bucketManager := storage.NewBucketManager(mac, q.getConfig())
-	return bucketManager.Delete(q.bucketName, objectName)
+	err := bucketManager.Delete(q.bucketName, objectName)
+	var info *client.ErrorInfo
+	if err != nil && !(errors.As(err, &info) && info.Code == qiniuNoSuchFile) {
+		return fmt.Errorf("failed to delete object: %v", err)
+	}
+	return nil
+}
+
+// SetStorageClass 转换对象的存储类型
+func (q *QiniuCommoner) SetStorageClass(objectName string, class string) error {
+	fileType := -1
+	for i, name := range qiniuStorageClasses {
+		if name == class {
+			fileType = i
+		}
+	}
+	if fileType < 0 {
+		return fmt.Errorf("七牛云不支持的存储类型: %s", class)
+	}
+	bucketManager := storage.NewBucketManager(qbox.NewMac(q.accessKey, q.secretKey), q.getConfig())
+	if err := bucketManager.ChangeType(q.bucketName, objectName, fileType); err != nil {
+		return fmt.Errorf("failed to change storage class: %v", err)
+	}
+	return nil
+}
+
+// Restore 发起归档对象的解冻，七牛云的解冻有效期为 1～7 天
+func (q *QiniuCommoner) Restore(objectName string, days int) error {
+	bucketManager := storage.NewBucketManager(qbox.NewMac(q.accessKey, q.secretKey), q.getConfig())
+	if err := bucketManager.RestoreAr(q.bucketName, objectName, min(max(days, 1), 7)); err != nil {
+		return fmt.Errorf("failed to restore object: %v", err)
+	}
+	return nil
+}
+
+// Restored 按文件信息中的解冻状态判断恢复是否完成：1 为解冻中，2 为已解冻
+func (q *QiniuCommoner) Restored(objectName string) (bool, error) {
+	bucketManager := storage.NewBucketManager(qbox.NewMac(q.accessKey, q.secretKey), q.getConfig())
+	info, err := bucketManager.Stat(q.bucketName, objectName)
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve object info: %v", err)
+	}
+	return info.RestoreStatus == 2, nil
 }
 
 // ListFiles 列出七牛云桶中的文件
@@ -241,6 +332,40 @@ func (q *QiniuCommoner) ListFiles(prefix, marker string, limit int) ([]storage.L
 	return entries, nextMarker, nil
 }
 
+// List 实现 ObjectLister 接口
+func (q *QiniuCommoner) List(prefix, marker string, limit int) ([]ObjectInfo, string, error) {
+	if limit == 0 {
+		limit = 1000
+	}
+	entries, nextMarker, err := q.ListFiles(prefix, marker, limit)
+	if err != nil {
+		return nil, "", err
+	}
+	objects := make([]ObjectInfo, 0, len(entries))
+	for _, entry := range entries {
+		objects = append(objects, ObjectInfo{
+			Key:  entry.Key,
+			Size: entry.Fsize,
+			ETag: entry.Hash,
+			// PutTime 单位为 100 纳秒
+			LastModified: time.Unix(0, entry.PutTime*100),
+			StorageClass: qiniuStorageClass(entry.Type),
+		})
+	}
+	return objects, nextMarker, nil
+}
+
+// qiniuStorageClasses 七牛的存储类型编号，依次为标准、低频、归档、深度归档
+var qiniuStorageClasses = []string{StorageStandard, StorageIA, StorageArchive, StorageDeepArchive}
+
+// qiniuStorageClass 将七牛的存储类型编号转为统一名称
+func qiniuStorageClass(t int) string {
+	if t >= 0 && t < len(qiniuStorageClasses) {
+		return qiniuStorageClasses[t]
+	}
+	return fmt.Sprintf("qiniu_%d", t)
+}
+
 // Copy 从七牛云中复制文件到新位置
 func (q *QiniuCommoner) Copy(srcKey, destKey string, force bool) error {
 	mac := qbox.NewMac(q.accessKey, q.secretKey)
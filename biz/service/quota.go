@@ -0,0 +1,349 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
+	"github.com/cloudisk/pkg/constant"
+	utils "github.com/cloudisk/pkg/utils"
+	"gorm.io/gen/field"
+)
+
+// quotaMutex 串行化本进程内配额记录的初始化和用量变更；并发写入合计不超出上限由 reserveQuota 的条件更新保证，跨进程同样有效
+var quotaMutex sync.Mutex
+
+// quotaOwner 一次写入需要计入的配额范围：文件拥有者，以及所在的共享根目录
+type quotaOwner struct {
+	Userid int64
+	Pshare int64
+}
+
+// QuotaUsage 配额使用情况
+type QuotaUsage struct {
+	Userid    int64
+	Pshare    int64
+	Used      int64
+	Limit     int64 // 0 为不限制
+	Available int64 // 不限制时为 -1
+}
+
+// IsQuotaError 判断错误是否为配额不足
+func IsQuotaError(err error) bool {
+	var withErr utils.WithError
+	return errors.As(err, &withErr) && withErr.Msg == constant.ErrQuotaExceeded
+}
+
+// shareRoot 从指定文件夹向上查找所属的共享根目录（与 Pshare 的取值一致，取最外层），不在共享文件夹中时返回 0
+func shareRoot(pid int64) int64 {
+	var pshare int64
+	for pid > 0 {
+		folder, err := query.Q.File.Where(query.File.ID.Eq(pid)).First()
+		if err != nil {
+			break
+		}
+		if folder.Share > 0 {
+			pshare = folder.ID
+		}
+		pid = folder.Pid
+	}
+	return pshare
+}
+
+// scopes 返回需要计入的配额记录：会员配额，以及所在共享文件夹的配额
+func (o quotaOwner) scopes() [][2]int64 {
+	scopes := [][2]int64{{o.Userid, 0}}
+	if o.Pshare > 0 {
+		scopes = append(scopes, [2]int64{0, o.Pshare})
+	}
+	return scopes
+}
+
+// shareRootOf 返回文件计入的共享根目录，文件本身是最外层的共享文件夹时为其自身；删除和恢复使用同一范围
+func shareRootOf(file *gorm_gen.File) int64 {
+	if pshare := shareRoot(file.Pid); pshare > 0 {
+		return pshare
+	}
+	if file.Share > 0 {
+		return file.ID
+	}
+	return 0
+}
+
+// ownerOf 返回拥有者为 userid、上级为 pid 的文件所计入的配额范围
+func ownerOf(userid int64, pid int64) quotaOwner {
+	return quotaOwner{Userid: userid, Pshare: shareRoot(pid)}
+}
+
+// collectDescendants 逐层查找文件夹的全部子级
+func collectDescendants(tx *query.Query, root *gorm_gen.File, unscoped bool) ([]*gorm_gen.File, error) {
+	files := []*gorm_gen.File{root}
+	parents := []int64{root.ID}
+	for len(parents) > 0 && root.Type == "folder" {
+		do := tx.File.Where(query.File.Pid.In(parents...))
+		if unscoped {
+			do = tx.File.Unscoped().Where(query.File.Pid.In(parents...), query.File.DeletedAt.IsNotNull())
+		}
+		children, err := do.Find()
+		if err != nil {
+			return nil, err
+		}
+		parents = parents[:0]
+		for _, child := range children {
+			files = append(files, child)
+			if child.Type == "folder" {
+				parents = append(parents, child.ID)
+			}
+		}
+	}
+	return files, nil
+}
+
+// sumFileSize 统计文件大小之和，文件夹不计
+func sumFileSize(files []*gorm_gen.File) int64 {
+	var total int64
+	for _, file := range files {
+		if file.Type != "folder" {
+			total += file.Size
+		}
+	}
+	return total
+}
+
+// loadQuota 读取配额记录；会员配额不存在时按当前文件大小初始化，共享文件夹配额未定义时返回 nil
+func loadQuota(userid int64, pshare int64) (*entity.FileQuota, error) {
+	quota, err := query.Q.FileQuota.Where(query.FileQuota.Userid.Eq(userid), query.FileQuota.Pshare.Eq(pshare)).First()
+	if err == nil {
+		return quota, nil
+	}
+	if pshare > 0 {
+		return nil, nil
+	}
+
+	var used struct{ Total int64 }
+	err = query.Q.File.Select(query.File.Size.Sum().As("total")).
+		Where(query.File.Userid.Eq(userid), query.File.Type.Neq("folder")).
+		Scan(&used)
+	if err != nil {
+		return nil, fmt.Errorf("统计已用空间失败: %v", err)
+	}
+
+	quota = &entity.FileQuota{
+		Userid:    userid,
+		LimitSize: config.QuotaUserDefault,
+		UsedSize:  used.Total,
+	}
+	if err := query.Q.FileQuota.Create(quota); err != nil {
+		return nil, fmt.Errorf("初始化存储配额失败: %v", err)
+	}
+	return quota, nil
+}
+
+// checkQuota 检查写入 delta 字节后是否超出会员或共享文件夹的配额，只用于上传前的预先检查，不预占空间
+func checkQuota(owner quotaOwner, delta int64) error {
+	if delta <= 0 {
+		return nil
+	}
+
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	for _, scope := range owner.scopes() {
+		quota, err := loadQuota(scope[0], scope[1])
+		if err != nil {
+			return err
+		}
+		if quota != nil && quota.LimitSize > 0 && quota.UsedSize+delta > quota.LimitSize {
+			return quotaExceeded(quota)
+		}
+	}
+	return nil
+}
+
+// reserveQuota 写入前预占 delta 字节：以 used_size+delta <= limit_size 为条件更新已用空间，
+// 并发写入合计不会超出上限；写入失败时调用 releaseQuota 归还。delta 不大于 0 时直接调整用量
+func reserveQuota(owner quotaOwner, delta int64) error {
+	if delta <= 0 {
+		addUsage(owner, delta)
+		return nil
+	}
+
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	reserved := []*entity.FileQuota{}
+	for _, scope := range owner.scopes() {
+		quota, err := loadQuota(scope[0], scope[1])
+		if err == nil && quota != nil {
+			err = reserveScope(quota, delta)
+		}
+		if err != nil {
+			for _, q := range reserved {
+				query.Q.FileQuota.Where(query.FileQuota.ID.Eq(q.ID)).UpdateSimple(query.FileQuota.UsedSize.Sub(delta))
+			}
+			return err
+		}
+		if quota != nil {
+			reserved = append(reserved, quota)
+		}
+	}
+	return nil
+}
+
+// reserveScope 在单个配额记录上预占 delta 字节，超出上限时不更新并返回配额不足
+func reserveScope(quota *entity.FileQuota, delta int64) error {
+	info, err := query.Q.FileQuota.Where(
+		query.FileQuota.ID.Eq(quota.ID),
+		field.Or(query.FileQuota.LimitSize.Lte(0), query.FileQuota.UsedSize.Add(delta).LteCol(query.FileQuota.LimitSize)),
+	).UpdateSimple(query.FileQuota.UsedSize.Add(delta))
+	if err != nil {
+		return fmt.Errorf("更新存储用量失败: %v", err)
+	}
+	if info.RowsAffected > 0 {
+		return nil
+	}
+	if current, err := query.Q.FileQuota.Where(query.FileQuota.ID.Eq(quota.ID)).First(); err == nil {
+		quota = current
+	}
+	return quotaExceeded(quota)
+}
+
+// releaseQuota 写入失败时归还 reserveQuota 预占的空间
+func releaseQuota(owner quotaOwner, delta int64) {
+	addUsage(owner, -delta)
+}
+
+func quotaExceeded(quota *entity.FileQuota) error {
+	used, limit := formatSize(quota.UsedSize), formatSize(quota.LimitSize)
+	return utils.WithMap(constant.ErrQuotaExceeded, map[string]any{"used": used, "limit": limit},
+		fmt.Errorf("存储空间不足：已用 %s，上限 %s", used, limit))
+}
+
+// addUsage 按写入或删除的字节数调整已用空间
+func addUsage(owner quotaOwner, delta int64) {
+	if delta == 0 {
+		return
+	}
+
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	if _, err := loadQuota(owner.Userid, 0); err != nil {
+		log.Printf("更新存储用量失败, userid: %d, 错误: %v", owner.Userid, err)
+		return
+	}
+	_, err := query.Q.FileQuota.Where(query.FileQuota.Userid.Eq(owner.Userid), query.FileQuota.Pshare.Eq(0)).
+		UpdateSimple(query.FileQuota.UsedSize.Add(delta))
+	if err != nil {
+		log.Printf("更新存储用量失败, userid: %d, 错误: %v", owner.Userid, err)
+	}
+	if owner.Pshare > 0 {
+		_, err := query.Q.FileQuota.Where(query.FileQuota.Userid.Eq(0), query.FileQuota.Pshare.Eq(owner.Pshare)).
+			UpdateSimple(query.FileQuota.UsedSize.Add(delta))
+		if err != nil {
+			log.Printf("更新共享文件夹用量失败, pshare: %d, 错误: %v", owner.Pshare, err)
+		}
+	}
+}
+
+// GetQuotaUsage 返回会员的配额使用情况；fileID 大于 0 时附带其所在共享文件夹的配额
+func GetQuotaUsage(user *User, fileID int) ([]*QuotaUsage, error) {
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	usages := []*QuotaUsage{}
+	quota, err := loadQuota(int64(user.Userid), 0)
+	if err != nil {
+		return nil, err
+	}
+	usages = append(usages, newQuotaUsage(quota))
+
+	if fileID > 0 {
+		row, err := permissionFind(fileID, user, 0)
+		if err != nil {
+			return nil, err
+		}
+		pshare := shareRootOf(row)
+		if pshare > 0 {
+			quota, err := loadQuota(0, pshare)
+			if err != nil {
+				return nil, err
+			}
+			if quota != nil {
+				usages = append(usages, newQuotaUsage(quota))
+			}
+		}
+	}
+	return usages, nil
+}
+
+// SetQuota 设置会员或共享文件夹的配额上限，仅限管理员操作
+func SetQuota(user *User, userid int64, pshare int64, limit int64) (*QuotaUsage, error) {
+	if !isContain(user.Identity, "admin") {
+		return nil, errors.New("仅限管理员操作")
+	}
+	if (userid > 0) == (pshare > 0) {
+		return nil, errors.New("会员ID与共享文件夹ID必须且只能指定一个")
+	}
+
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	quota, err := loadQuota(userid, pshare)
+	if err != nil {
+		return nil, err
+	}
+	if quota == nil {
+		root, err := query.Q.File.Where(query.File.ID.Eq(pshare), query.File.Share.Gt(0)).First()
+		if err != nil {
+			return nil, errors.New("共享文件夹不存在")
+		}
+		files, err := collectDescendants(query.Q, root, false)
+		if err != nil {
+			return nil, fmt.Errorf("统计已用空间失败: %v", err)
+		}
+		quota = &entity.FileQuota{Pshare: pshare, UsedSize: sumFileSize(files)}
+	}
+
+	quota.LimitSize = limit
+	if err := query.Q.FileQuota.Save(quota); err != nil {
+		return nil, fmt.Errorf("保存存储配额失败: %v", err)
+	}
+	return newQuotaUsage(quota), nil
+}
+
+func newQuotaUsage(quota *entity.FileQuota) *QuotaUsage {
+	usage := &QuotaUsage{
+		Userid:    quota.Userid,
+		Pshare:    quota.Pshare,
+		Used:      quota.UsedSize,
+		Limit:     quota.LimitSize,
+		Available: -1,
+	}
+	if quota.LimitSize > 0 {
+		usage.Available = quota.LimitSize - quota.UsedSize
+		if usage.Available < 0 {
+			usage.Available = 0
+		}
+	}
+	return usage
+}
+
+// formatSize 将字节数格式化为便于阅读的大小
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
+)
+
+func Test_FormatSize(t *testing.T) {
+	cases := map[int64]string{
+		512:              "512B",
+		1536:             "1.5KB",
+		10 * 1024 * 1024: "10.0MB",
+		3 << 30:          "3.0GB",
+	}
+	for size, want := range cases {
+		if got := formatSize(size); got != want {
+			t.Errorf("formatSize(%d) = %s, want %s", size, got, want)
+		}
+	}
+}
+
+func Test_NewQuotaUsage(t *testing.T) {
+	usage := newQuotaUsage(&entity.FileQuota{Userid: 1, UsedSize: 100})
+	if usage.Available != -1 {
+		t.Errorf("unlimited quota should report -1, got %d", usage.Available)
+	}
+	usage = newQuotaUsage(&entity.FileQuota{Userid: 1, UsedSize: 120, LimitSize: 100})
+	if usage.Available != 0 {
+		t.Errorf("over-used quota should report 0, got %d", usage.Available)
+	}
+}
+
+// usedSize 返回配额记录的已用空间，记录不存在时返回 -1
+func usedSize(t *testing.T, userid int64, pshare int64) int64 {
+	t.Helper()
+	quota, err := query.Q.FileQuota.Where(query.FileQuota.Userid.Eq(userid), query.FileQuota.Pshare.Eq(pshare)).First()
+	if err != nil {
+		return -1
+	}
+	return quota.UsedSize
+}
+
+func Test_reserveQuota(t *testing.T) {
+	newTestDB(t)
+	query.Q.FileQuota.Create(&entity.FileQuota{Userid: 1, LimitSize: 100})
+	query.Q.FileQuota.Create(&entity.FileQuota{Pshare: 5, LimitSize: 50})
+	owner := quotaOwner{Userid: 1, Pshare: 5}
+
+	if err := reserveQuota(owner, 40); err != nil {
+		t.Fatal(err)
+	}
+	// 共享文件夹配额不足时，已预占的会员配额需要归还
+	if err := reserveQuota(owner, 20); !IsQuotaError(err) {
+		t.Fatalf("reserveQuota over share limit = %v, want quota error", err)
+	}
+	if used := usedSize(t, 1, 0); used != 40 {
+		t.Errorf("user used = %d, want 40", used)
+	}
+	if used := usedSize(t, 0, 5); used != 40 {
+		t.Errorf("share used = %d, want 40", used)
+	}
+
+	releaseQuota(owner, 40)
+	if used := usedSize(t, 1, 0); used != 0 {
+		t.Errorf("user used after release = %d, want 0", used)
+	}
+	if used := usedSize(t, 0, 5); used != 0 {
+		t.Errorf("share used after release = %d, want 0", used)
+	}
+}
+
+func Test_reserveQuotaConcurrent(t *testing.T) {
+	newTestDB(t)
+	query.Q.FileQuota.Create(&entity.FileQuota{Userid: 1, LimitSize: 100})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := reserveQuota(quotaOwner{Userid: 1}, 10); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 10 {
+		t.Errorf("succeeded = %d, want 10", succeeded)
+	}
+	if used := usedSize(t, 1, 0); used != 100 {
+		t.Errorf("used = %d, want 100", used)
+	}
+}
+
+func Test_createUploadRecordReleasesQuota(t *testing.T) {
+	db := newTestDB(t)
+	useTestStore(t, "test")
+	retention := config.BlobRetentionHours
+	config.BlobRetentionHours = 0
+	defer func() { config.BlobRetentionHours = retention }()
+	query.Q.FileQuota.Create(&entity.FileQuota{Userid: 1, LimitSize: 1000})
+
+	blob, err := putBlob("test", "txt", strings.NewReader("quota content"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 文件内容记录写入失败时，预占的空间和内容引用都要归还
+	if err := db.Migrator().DropTable(&gorm_gen.FileContent{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createUploadRecord(&User{Userid: 1}, 1, 0, "note.txt", "", false, blob); err == nil {
+		t.Fatal("createUploadRecord should fail without file contents table")
+	}
+	if used := usedSize(t, 1, 0); used != 0 {
+		t.Errorf("used = %d, want 0", used)
+	}
+	if n, _ := query.Q.FileBlob.Where(query.FileBlob.ID.Eq(blob.ID)).Count(); n != 0 {
+		t.Error("blob not released")
+	}
+}
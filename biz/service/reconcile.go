@@ -0,0 +1,264 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"gorm.io/gen"
+)
+
+// quarantinePrefix 隔离孤立对象使用的路径前缀，对账时不扫描
+const quarantinePrefix = "quarantine/"
+
+// ReconcileOptions 存储桶与数据库对账参数
+type ReconcileOptions struct {
+	Profile string        // 存储配置ID或云存储名称：aliyun、tencent、qiniu，为空时为默认云存储
+	Prefix  string        // 只对账该前缀下的对象
+	MinAge  time.Duration // 孤立对象的最后修改时间须早于此时长，避免误判正在上传的对象
+	Action  string        // 孤立对象的处理方式：为空只报告，quarantine 移到隔离前缀，delete 删除
+}
+
+// ReconcileItem 对账发现的问题
+type ReconcileItem struct {
+	Kind   string // orphan 存储桶中没有对应记录的对象，missing 记录对应的对象不存在
+	Key    string
+	Size   int64
+	FileID int64  // missing 时为引用该对象的文件ID
+	Action string // 对孤立对象执行的处理：quarantined / deleted
+	Err    error
+}
+
+// ReconcileReport 对账汇总
+type ReconcileReport struct {
+	Objects    int   // 扫描的对象数
+	Orphans    int   // 孤立对象数
+	OrphanSize int64 // 孤立对象总大小
+	Missing    int   // 缺失对象数
+	Failed     int   // 处理失败的孤立对象数
+}
+
+// objectRef 数据库中对对象的引用
+type objectRef struct {
+	FileID   int64
+	Required bool // 未删除文件的最新版本，对象缺失时文件无法下载
+}
+
+// folderNode 文件夹名称和上级ID，用于推导旧数据的对象路径
+type folderNode struct {
+	Name string
+	Pid  int64
+}
+
+// legacyObjectKey 与 GetFilePath 一致，按文件夹层级拼接对象路径，上级文件夹不存在时停止
+func legacyObjectKey(file *gorm_gen.File, folders map[int64]folderNode) string {
+	paths := []string{}
+	for pid := file.Pid; pid > 0; {
+		folder, ok := folders[pid]
+		if !ok {
+			break
+		}
+		paths = append([]string{folder.Name}, paths...)
+		pid = folder.Pid
+	}
+	fileName := file.Name
+	if file.Ext != "" {
+		fileName = fileName + "." + file.Ext
+	}
+	return strings.Join(append(paths, fileName), "/")
+}
+
+// sameProfile 判断内容记录的存储配置是否为 profile，未记录存储配置的内容在阿里云OSS
+func sameProfile(recorded string, profile string) bool {
+	if recorded == "" {
+		recorded = legacyProvider
+	}
+	return recorded == profile
+}
+
+// profileBucket 返回存储配置所在的存储桶，不经过副本的包装，对账和导入只操作该存储桶
+func profileBucket(profile string) (ObjectStore, ObjectLister, error) {
+	store, err := profileStore(profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	store = primaryStore(store)
+	lister, ok := store.(ObjectLister)
+	if !ok {
+		return nil, nil, fmt.Errorf("存储配置 %s 不支持列出对象", profile)
+	}
+	return store, lister, nil
+}
+
+// collectObjectRefs 汇总文件（含回收站中的）各版本内容和已登记内容中，存放在 profile 存储的对象路径
+func collectObjectRefs(profile string) (map[string]*objectRef, error) {
+	folders := map[int64]folderNode{}
+	var rows []*gorm_gen.File
+	err := query.Q.File.Unscoped().Where(query.File.Type.Eq("folder")).FindInBatches(&rows, 1000, func(tx gen.Dao, batch int) error {
+		for _, row := range rows {
+			folders[row.ID] = folderNode{Name: row.Name, Pid: row.Pid}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load folders: %v", err)
+	}
+
+	refs := map[string]*objectRef{}
+	add := func(key string, fileID int64, required bool) {
+		if ref, ok := refs[key]; ok {
+			if required && !ref.Required {
+				ref.FileID, ref.Required = fileID, true
+			}
+			return
+		}
+		refs[key] = &objectRef{FileID: fileID, Required: required}
+	}
+
+	var files []*gorm_gen.File
+	err = query.Q.File.Unscoped().Where(query.File.Type.Neq("folder")).FindInBatches(&files, 100, func(tx gen.Dao, batch int) error {
+		ids := make([]int64, 0, len(files))
+		for _, file := range files {
+			ids = append(ids, file.ID)
+		}
+		contents, err := query.Q.FileContent.Unscoped().Where(query.FileContent.Fid.In(ids...)).Find()
+		if err != nil {
+			return err
+		}
+		latest := map[int64]*gorm_gen.FileContent{}
+		for _, content := range contents {
+			if current, ok := latest[content.Fid]; !ok || content.ID > current.ID {
+				latest[content.Fid] = content
+			}
+		}
+		for _, file := range files {
+			for _, content := range contents {
+				if content.Fid != file.ID {
+					continue
+				}
+				meta := parseContentMeta(content)
+				if !sameProfile(meta.Profile, profile) {
+					continue
+				}
+				key := meta.Key
+				if key == "" {
+					key = legacyObjectKey(file, folders)
+				}
+				required := !file.DeletedAt.Valid && latest[file.ID].ID == content.ID
+				add(key, file.ID, required)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk files: %v", err)
+	}
+
+	// 引用计数为 0 的内容在保留期内仍可恢复，由 gc 负责清理
+	var blobs []*entity.FileBlob
+	err = query.Q.FileBlob.FindInBatches(&blobs, 1000, func(tx gen.Dao, batch int) error {
+		for _, blob := range blobs {
+			if sameProfile(blob.Profile, profile) {
+				add(blob.ObjectKey, 0, false)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blobs: %v", err)
+	}
+	return refs, nil
+}
+
+// isReferenced 判断对象是否被引用，缩略图随原图一起判断
+func isReferenced(key string, refs map[string]*objectRef) bool {
+	if _, ok := refs[key]; ok {
+		return true
+	}
+	if i := strings.LastIndex(key, ".thumb_"); i > 0 {
+		_, ok := refs[key[:i]]
+		return ok
+	}
+	return false
+}
+
+// quarantineObject 将对象复制到隔离前缀后删除原对象
+func quarantineObject(store ObjectStore, key string) error {
+	body, err := store.GetObject(key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if _, err := store.PutObject(body, quarantinePrefix+key, PutOptions{}); err != nil {
+		return err
+	}
+	return store.Delete(key)
+}
+
+// Reconcile 分页扫描存储配置所在的存储桶，与数据库中记录在该存储配置的对象路径比对，报告孤立对象和缺失对象，按需隔离或删除孤立对象
+func Reconcile(opts ReconcileOptions, fn func(ReconcileItem)) (ReconcileReport, error) {
+	var report ReconcileReport
+	if opts.Action != "" && opts.Action != "quarantine" && opts.Action != "delete" {
+		return report, fmt.Errorf("不支持的处理方式: %s", opts.Action)
+	}
+	if opts.Profile == "" {
+		opts.Profile = defaultProvider()
+	}
+	store, lister, err := profileBucket(opts.Profile)
+	if err != nil {
+		return report, err
+	}
+	refs, err := collectObjectRefs(opts.Profile)
+	if err != nil {
+		return report, err
+	}
+
+	seen := map[string]bool{}
+	before := time.Now().Add(-opts.MinAge)
+	marker := ""
+	for {
+		objects, next, err := lister.List(opts.Prefix, marker, 1000)
+		if err != nil {
+			return report, err
+		}
+		for _, obj := range objects {
+			if strings.HasPrefix(obj.Key, quarantinePrefix) || strings.HasSuffix(obj.Key, "/") {
+				continue
+			}
+			report.Objects++
+			seen[obj.Key] = true
+			if isReferenced(obj.Key, refs) || obj.LastModified.After(before) {
+				continue
+			}
+
+			item := ReconcileItem{Kind: "orphan", Key: obj.Key, Size: obj.Size}
+			switch opts.Action {
+			case "quarantine":
+				item.Action, item.Err = "quarantined", quarantineObject(store, obj.Key)
+			case "delete":
+				item.Action, item.Err = "deleted", store.Delete(obj.Key)
+			}
+			if item.Err != nil {
+				report.Failed++
+			}
+			report.Orphans++
+			report.OrphanSize += obj.Size
+			fn(item)
+		}
+		if next == "" {
+			break
+		}
+		marker = next
+	}
+
+	for key, ref := range refs {
+		if ref.Required && !seen[key] && strings.HasPrefix(key, opts.Prefix) {
+			report.Missing++
+			fn(ReconcileItem{Kind: "missing", Key: key, FileID: ref.FileID})
+		}
+	}
+	return report, nil
+}
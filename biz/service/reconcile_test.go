@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/cloudisk/biz/model/gorm_gen"
+)
+
+func Test_legacyObjectKey(t *testing.T) {
+	folders := map[int64]folderNode{
+		1: {Name: "项目"},
+		2: {Name: "设计", Pid: 1},
+	}
+	cases := []struct {
+		file *gorm_gen.File
+		want string
+	}{
+		{&gorm_gen.File{Name: "说明", Ext: "md"}, "说明.md"},
+		{&gorm_gen.File{Pid: 2, Name: "架构图", Ext: "drawio"}, "项目/设计/架构图.drawio"},
+		{&gorm_gen.File{Pid: 1, Name: "Makefile"}, "项目/Makefile"},
+		// 上级文件夹已不存在时只保留已找到的层级
+		{&gorm_gen.File{Pid: 9, Name: "a", Ext: "txt"}, "a.txt"},
+	}
+	for _, c := range cases {
+		if got := legacyObjectKey(c.file, folders); got != c.want {
+			t.Errorf("legacyObjectKey(%s) = %s, want %s", c.file.Name, got, c.want)
+		}
+	}
+}
+
+func Test_isReferenced(t *testing.T) {
+	refs := map[string]*objectRef{
+		"blobs/ab/abcd": {},
+		"项目/设计/架构图.png": {FileID: 3, Required: true},
+	}
+	cases := map[string]bool{
+		"blobs/ab/abcd":               true,
+		"blobs/ab/abcd.thumb_128.jpg": true,
+		"项目/设计/架构图.png.thumb_512.png": true,
+		"项目/设计/旧图.png":                false,
+		"blobs/cd/cdef.thumb_128.jpg": false,
+	}
+	for key, want := range cases {
+		if got := isReferenced(key, refs); got != want {
+			t.Errorf("isReferenced(%s) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func Test_sameProfile(t *testing.T) {
+	cases := []struct {
+		recorded, profile string
+		want              bool
+	}{
+		{"", "aliyun", true},
+		{"", "tencent", false},
+		{"aliyun", "aliyun", true},
+		{"eu-cos", "eu-cos", true},
+		{"eu-cos", "tencent", false},
+	}
+	for _, c := range cases {
+		if got := sameProfile(c.recorded, c.profile); got != c.want {
+			t.Errorf("sameProfile(%q, %q) = %v, want %v", c.recorded, c.profile, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/pkg/config"
+	"gorm.io/gorm/clause"
+)
+
+// replicaLease 领取复制任务后的租约时长，进程中断时任务在租约到期后被重新领取
+const replicaLease = 10 * time.Minute
+
+var (
+	replicaOnce sync.Once
+	replicaKick = make(chan struct{}, 1)
+)
+
+// replicaFailover 读取主存储失败时改读已完成的副本，删除时一并删除副本，写入不登记复制任务；
+// 用于非默认云存储中已有的内容（如修改 CLOUD_PROVIDER 前写入的内容）
+type replicaFailover struct {
+	CloudUploader
+}
+
+// replicatedUploader 写入主存储后登记异步复制到副本存储，读取和删除同 replicaFailover；
+// Upload 按文件夹层级推导对象路径，无法在此登记，由 storagectl replicate 补充复制
+type replicatedUploader struct {
+	replicaFailover
+	primary string
+}
+
+// primaryStore 去掉副本的包装，返回实际的云存储
+func primaryStore(store ObjectStore) ObjectStore {
+	switch s := store.(type) {
+	case *replicatedUploader:
+		return s.CloudUploader
+	case *replicaFailover:
+		return s.CloudUploader
+	}
+	return store
+}
+
+// withReplicas 配置了 REPLICA_PROVIDERS 时为主存储加上复制，并启动复制任务的后台执行
+func withReplicas(primary string, uploader CloudUploader) CloudUploader {
+	if len(replicaProviders(primary)) == 0 {
+		return uploader
+	}
+	replicaOnce.Do(func() {
+		for i := int64(0); i < max(config.ReplicaWorkers, 1); i++ {
+			go replicaWorker(primary)
+		}
+	})
+	return &replicatedUploader{replicaFailover: replicaFailover{CloudUploader: uploader}, primary: primary}
+}
+
+// replicaProviders 返回主存储以外的副本存储
+func replicaProviders(primary string) []string {
+	providers := []string{}
+	for _, provider := range config.ReplicaProviders {
+		if provider != primary {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+func (r *replicatedUploader) ReaderUpload(file io.ReadCloser, objectName string) (int64, error) {
+	size, err := r.CloudUploader.ReaderUpload(file, objectName)
+	if err == nil {
+		enqueueReplicas(r.primary, objectName)
+	}
+	return size, err
+}
+
+func (r *replicatedUploader) PutObject(file io.Reader, objectName string, opts PutOptions) (int64, error) {
+	size, err := r.CloudUploader.PutObject(file, objectName, opts)
+	if err == nil {
+		enqueueReplicas(r.primary, objectName)
+	}
+	return size, err
+}
+
+// GetObject 读取主存储，失败时依次尝试已完成复制的副本
+func (r *replicaFailover) GetObject(objectName string) (io.ReadCloser, error) {
+	body, err := r.CloudUploader.GetObject(objectName)
+	if err == nil {
+		return body, nil
+	}
+	replicas, qerr := query.Q.FileReplica.Where(
+		query.FileReplica.ObjectKey.Eq(objectName),
+		query.FileReplica.Status.Eq("done"),
+	).Find()
+	if qerr != nil || len(replicas) == 0 {
+		return nil, err
+	}
+	stores := []ObjectStore{}
+	for _, replica := range replicas {
+		if store, serr := getObjectStore(replica.Provider); serr == nil {
+			stores = append(stores, store)
+		}
+	}
+	return getWithFailover(objectName, err, stores)
+}
+
+// getWithFailover 主存储读取失败（primaryErr）后依次尝试副本，全部失败时返回主存储的错误
+func getWithFailover(objectName string, primaryErr error, replicas []ObjectStore) (io.ReadCloser, error) {
+	for i, store := range replicas {
+		body, err := store.GetObject(objectName)
+		if err != nil {
+			log.Printf("读取副本失败: %s, 副本 %d, 错误: %v", objectName, i, err)
+			continue
+		}
+		log.Printf("主存储读取失败，已改读副本: %s, 错误: %v", objectName, primaryErr)
+		return body, nil
+	}
+	return nil, primaryErr
+}
+
+// Delete 删除主存储中的对象，并尽力删除各副本
+func (r *replicaFailover) Delete(objectName string) error {
+	err := r.CloudUploader.Delete(objectName)
+	replicas, _ := query.Q.FileReplica.Where(query.FileReplica.ObjectKey.Eq(objectName)).Find()
+	for _, replica := range replicas {
+		if replica.Status == "done" {
+			if store, serr := getObjectStore(replica.Provider); serr == nil {
+				if derr := store.Delete(objectName); derr != nil {
+					log.Printf("删除副本失败: %s, 副本: %s, 错误: %v", objectName, replica.Provider, derr)
+				}
+			}
+		}
+		query.Q.FileReplica.Where(query.FileReplica.ID.Eq(replica.ID)).Delete()
+	}
+	return err
+}
+
+// enqueueReplicas 登记对象到各副本存储的复制任务，已存在的任务重新执行
+func enqueueReplicas(primary string, objectName string) {
+	now := time.Now()
+	for _, provider := range replicaProviders(primary) {
+		err := query.Q.FileReplica.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "object_key"}, {Name: "provider"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "attempts", "error", "next_at", "updated_at"}),
+		}).Create(&entity.FileReplica{ObjectKey: objectName, Provider: provider, Status: "pending", NextAt: now})
+		if err != nil {
+			log.Printf("登记复制任务失败: %s, 副本: %s, 错误: %v", objectName, provider, err)
+		}
+	}
+	select {
+	case replicaKick <- struct{}{}:
+	default:
+	}
+}
+
+// replicaBackoff 第 attempts 次失败后的重试间隔，从 1 分钟开始翻倍，最长 1 小时
+func replicaBackoff(attempts int32) time.Duration {
+	if attempts > 6 {
+		return time.Hour
+	}
+	return min(time.Minute<<max(attempts-1, 0), time.Hour)
+}
+
+// replicaWorker 持续执行到期的复制任务，有新任务或每 30 秒检查一次
+func replicaWorker(primary string) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		for processReplicas(primary, nil) > 0 {
+		}
+		select {
+		case <-replicaKick:
+		case <-ticker.C:
+		}
+	}
+}
+
+// processReplicas 领取并执行一批到期的复制任务，返回执行的数量；fn 在每个任务执行后回调
+func processReplicas(primary string, fn func(*entity.FileReplica)) int {
+	rows, err := query.Q.FileReplica.Where(
+		query.FileReplica.Status.Eq("pending"),
+		query.FileReplica.NextAt.Lte(time.Now()),
+	).Order(query.FileReplica.NextAt).Limit(10).Find()
+	if err != nil {
+		log.Printf("查询复制任务失败: %v", err)
+		return 0
+	}
+	processed := 0
+	for _, row := range rows {
+		// 以租约领取，多个进程或协程不会重复执行同一任务
+		info, err := query.Q.FileReplica.Where(
+			query.FileReplica.ID.Eq(row.ID),
+			query.FileReplica.Status.Eq("pending"),
+			query.FileReplica.NextAt.Eq(row.NextAt),
+		).Update(query.FileReplica.NextAt, time.Now().Add(replicaLease))
+		if err != nil || info.RowsAffected == 0 {
+			continue
+		}
+		runReplica(primary, row)
+		if fn != nil {
+			fn(row)
+		}
+		processed++
+	}
+	return processed
+}
+
+// runReplica 将对象从主存储复制到副本存储并更新任务状态
+func runReplica(primary string, row *entity.FileReplica) {
+	err := func() error {
+		src, err := getObjectStore(primary)
+		if err != nil {
+			return err
+		}
+		dst, err := getObjectStore(row.Provider)
+		if err != nil {
+			return err
+		}
+		_, err = copyObject(src, dst, migrateObject{Key: row.ObjectKey}, nil, false)
+		return err
+	}()
+
+	row.Attempts++
+	row.Error = ""
+	if err == nil {
+		row.Status = "done"
+	} else {
+		row.Error = capText(err.Error(), 1024)
+		if int64(row.Attempts) >= config.ReplicaMaxAttempts {
+			row.Status = "failed"
+			log.Printf("复制失败，已达到最大重试次数: %s, 副本: %s, 错误: %v", row.ObjectKey, row.Provider, err)
+		}
+		row.NextAt = time.Now().Add(replicaBackoff(row.Attempts))
+	}
+	_, uerr := query.Q.FileReplica.Where(query.FileReplica.ID.Eq(row.ID)).Updates(map[string]interface{}{
+		"status":   row.Status,
+		"attempts": row.Attempts,
+		"error":    row.Error,
+		"next_at":  row.NextAt,
+	})
+	if uerr != nil {
+		log.Printf("更新复制任务失败: %s, 错误: %v", row.ObjectKey, uerr)
+	}
+}
+
+// ReplicaSummary 各状态的复制任务数量
+type ReplicaSummary struct {
+	Pending int64
+	Done    int64
+	Failed  int64
+}
+
+// ReplicateAll 为全部文件内容登记尚不存在的复制任务，重置失败的任务，并在当前进程中以 concurrency 个协程执行到期的任务
+func ReplicateAll(concurrency int, fn func(*entity.FileReplica)) (ReplicaSummary, error) {
+	var summary ReplicaSummary
+	primary := defaultProvider()
+	providers := replicaProviders(primary)
+	if len(providers) == 0 {
+		return summary, errors.New("未配置 REPLICA_PROVIDERS")
+	}
+	objects, err := collectMigrateObjects(primary)
+	if err != nil {
+		return summary, err
+	}
+	now := time.Now()
+	for _, obj := range objects {
+		for _, provider := range providers {
+			err := query.Q.FileReplica.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&entity.FileReplica{ObjectKey: obj.Key, Provider: provider, Status: "pending", NextAt: now})
+			if err != nil {
+				return summary, fmt.Errorf("failed to enqueue replica: %v", err)
+			}
+		}
+	}
+	_, err = query.Q.FileReplica.Where(query.FileReplica.Status.Eq("failed")).Updates(map[string]interface{}{
+		"status":   "pending",
+		"attempts": 0,
+		"next_at":  now,
+	})
+	if err != nil {
+		return summary, fmt.Errorf("failed to reset failed replicas: %v", err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < max(concurrency, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for processReplicas(primary, func(row *entity.FileReplica) {
+				mu.Lock()
+				defer mu.Unlock()
+				fn(row)
+			}) > 0 {
+			}
+		}()
+	}
+	wg.Wait()
+
+	count := func(status string) int64 {
+		n, _ := query.Q.FileReplica.Where(query.FileReplica.Status.Eq(status)).Count()
+		return n
+	}
+	summary.Pending, summary.Done, summary.Failed = count("pending"), count("done"), count("failed")
+	return summary, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// memStore 内存中的对象存储，用于测试
+type memStore struct {
+	objects map[string][]byte
+	err     error // 不为空时所有操作返回该错误
+}
+
+func newMemStore() *memStore {
+	return &memStore{objects: map[string][]byte{}}
+}
+
+func (m *memStore) PutObject(file io.Reader, objectName string, opts PutOptions) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return 0, err
+	}
+	m.objects[objectName] = data
+	return int64(len(data)), nil
+}
+
+func (m *memStore) GetObject(objectName string) (io.ReadCloser, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	data, ok := m.objects[objectName]
+	if !ok {
+		return nil, ErrObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memStore) Delete(objectName string) error {
+	if m.err != nil {
+		return m.err
+	}
+	delete(m.objects, objectName)
+	return nil
+}
+
+func Test_getWithFailover(t *testing.T) {
+	primaryErr := errors.New("primary unavailable")
+	broken := &memStore{err: errors.New("replica unavailable")}
+	replica := newMemStore()
+	replica.objects["blobs/ab/abcd"] = []byte("hello")
+
+	body, err := getWithFailover("blobs/ab/abcd", primaryErr, []ObjectStore{broken, replica})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(body)
+	if string(data) != "hello" {
+		t.Errorf("unexpected replica content %q", data)
+	}
+
+	if _, err := getWithFailover("blobs/cd/cdef", primaryErr, []ObjectStore{broken, replica}); err != primaryErr {
+		t.Errorf("expected primary error, got %v", err)
+	}
+}
+
+func Test_copyObject(t *testing.T) {
+	src, dst := newMemStore(), newMemStore()
+	src.objects["docs/a.txt"] = []byte("replicated content")
+
+	size, err := copyObject(src, dst, migrateObject{Key: "docs/a.txt", Size: 18}, nil, true)
+	if err != nil || size != 18 || string(dst.objects["docs/a.txt"]) != "replicated content" {
+		t.Errorf("unexpected copy %d, %v, %q", size, err, dst.objects["docs/a.txt"])
+	}
+	if _, err := copyObject(src, dst, migrateObject{Key: "docs/a.txt", Hash: "0000"}, nil, false); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+}
+
+func Test_replicaBackoff(t *testing.T) {
+	cases := map[int32]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		7:  time.Hour,
+		40: time.Hour,
+	}
+	for attempts, want := range cases {
+		if got := replicaBackoff(attempts); got != want {
+			t.Errorf("replicaBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/common"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"gorm.io/gen"
+	"gorm.io/gorm/clause"
+)
+
+// fulltextDisabled 全文索引不可用时置为 true，之后的搜索直接使用 LIKE 匹配
+var fulltextDisabled atomic.Bool
+
+// snippetWidth 匹配片段在关键词前后各保留的字符数
+const snippetWidth = 60
+
+var pidsPattern = regexp.MustCompile(`\d+`)
+
+// SearchOptions 搜索条件，为空的条件不参与过滤
+type SearchOptions struct {
+	Keyword      string // 匹配文件名和提取的全文
+	Type         string
+	Ext          string
+	Userid       int64  // 所有者
+	Pid          int64  // 只搜索该文件夹下的文件
+	CreatedStart string // 日期格式 2006-01-02，包含当天
+	CreatedEnd   string
+	UpdatedStart string
+	UpdatedEnd   string
+	Page         int
+	PageSize     int
+}
+
+// SearchItem 搜索结果
+type SearchItem struct {
+	File    *common.File
+	Path    string // 所在文件夹路径，如 /项目/文档
+	Snippet string // 匹配片段
+}
+
+// SearchResult 分页的搜索结果
+type SearchResult struct {
+	Total    int64
+	Page     int
+	PageSize int
+	List     []*SearchItem
+}
+
+// Search 按名称、类型、所有者、日期和全文搜索当前会员可查看的文件
+func Search(user *User, opts SearchOptions) (*SearchResult, error) {
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PageSize < 1 || opts.PageSize > 100 {
+		opts.PageSize = 20
+	}
+	opts.Keyword = strings.TrimSpace(opts.Keyword)
+
+	if indexer := getSearchIndexer(); indexer != nil && opts.Keyword != "" {
+		return searchIndex(indexer, user, opts)
+	}
+
+	do, err := searchQuery(user, opts, !fulltextDisabled.Load())
+	if err != nil {
+		return nil, err
+	}
+	total, err := do.Count()
+	if err != nil && opts.Keyword != "" && !fulltextDisabled.Load() {
+		// 没有全文索引时 MATCH 会报错，退回 LIKE 匹配
+		log.Printf("全文搜索不可用，改用 LIKE 匹配: %v", err)
+		fulltextDisabled.Store(true)
+		do, _ = searchQuery(user, opts, false)
+		total, err = do.Count()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("搜索失败: %v", err)
+	}
+
+	files, err := do.Order(query.File.UpdatedAt.Desc()).
+		Offset((opts.Page - 1) * opts.PageSize).Limit(opts.PageSize).Find()
+	if err != nil {
+		return nil, fmt.Errorf("搜索失败: %v", err)
+	}
+
+	paths := folderPaths(files)
+	result := &SearchResult{Total: total, Page: opts.Page, PageSize: opts.PageSize, List: []*SearchItem{}}
+	for _, file := range files {
+		result.List = append(result.List, &SearchItem{
+			File:    toCommonFile(file),
+			Path:    paths[file.ID],
+			Snippet: searchSnippet(file, opts.Keyword),
+		})
+	}
+	return result, nil
+}
+
+// searchUserids 与 permissionFind 一致：临时帐号只能访问自己的文件，其他会员还能访问公开共享（userid 为 0）
+func searchUserids(user *User) []int64 {
+	if isContain(user.Identity, "temp") {
+		return []int64{int64(user.Userid)}
+	}
+	return []int64{0, int64(user.Userid)}
+}
+
+// accessibleShares 返回共享给 userids 且仍处于共享状态的文件夹，与 getShareInfo 一致只认 share 为 1 的文件夹
+func accessibleShares(userids []int64) ([]int64, error) {
+	var shares []int64
+	if err := query.Q.File_User.Where(query.File_User.Userid.In(userids...)).
+		Pluck(query.File_User.FileID, &shares); err != nil {
+		return nil, fmt.Errorf("查询共享文件夹失败: %v", err)
+	}
+	if len(shares) == 0 {
+		return shares, nil
+	}
+	var active []int64
+	if err := query.Q.File.Where(query.File.ID.In(shares...), query.File.Share.Eq(1)).
+		Pluck(query.File.ID, &active); err != nil {
+		return nil, fmt.Errorf("查询共享文件夹失败: %v", err)
+	}
+	return active, nil
+}
+
+// searchQuery 构造搜索条件；查看权限在 SQL 中过滤（与 getPermission 一致：所有者、创建者，或所在共享文件夹共享给了当前会员），
+// 总数和分页只包含有权查看的文件
+func searchQuery(user *User, opts SearchOptions, fulltext bool) (query.IFileDo, error) {
+	userids := searchUserids(user)
+	shares, err := accessibleShares(userids)
+	if err != nil {
+		return nil, err
+	}
+
+	f := query.File
+	scope := query.Q.File.Where(f.Userid.In(userids...)).Or(f.CreatedID.In(userids...))
+	if len(shares) > 0 {
+		scope = scope.Or(f.Pshare.In(shares...)).Or(f.ID.In(shares...))
+	}
+	do := query.Q.File.Where(scope)
+
+	if opts.Keyword != "" {
+		like := "%" + escapeLike(opts.Keyword) + "%"
+		contents := query.Q.FileContent.Select(query.FileContent.Fid)
+		if fulltext {
+			phrase := `"` + strings.ReplaceAll(opts.Keyword, `"`, " ") + `"`
+			contents = contents.Where(gen.Cond(clause.Expr{SQL: "MATCH(text) AGAINST(? IN BOOLEAN MODE)", Vars: []interface{}{phrase}})...)
+		} else {
+			contents = contents.Where(query.FileContent.Text.Like(like))
+		}
+		do = do.Where(query.Q.File.Where(f.Name.Like(like)).Or(query.Q.File.Columns(f.ID).In(contents)))
+	}
+	if opts.Type != "" {
+		do = do.Where(f.Type.Eq(opts.Type))
+	}
+	if opts.Ext != "" {
+		do = do.Where(f.Ext.Eq(strings.TrimPrefix(opts.Ext, ".")))
+	}
+	if opts.Userid > 0 {
+		do = do.Where(f.Userid.Eq(opts.Userid))
+	}
+	if opts.Pid > 0 {
+		root, err := query.Q.File.Where(f.ID.Eq(opts.Pid)).First()
+		if err != nil {
+			return nil, fmt.Errorf("文件夹不存在或已被删除")
+		}
+		descendants, err := collectDescendants(query.Q, root, false)
+		if err != nil {
+			return nil, fmt.Errorf("查询子文件失败: %v", err)
+		}
+		ids := make([]int64, 0, len(descendants))
+		for _, file := range descendants[1:] {
+			ids = append(ids, file.ID)
+		}
+		do = do.Where(f.ID.In(ids...))
+	}
+
+	for _, r := range []struct {
+		value string
+		end   bool
+		field func(time.Time) gen.Condition
+	}{
+		{opts.CreatedStart, false, func(t time.Time) gen.Condition { return f.CreatedAt.Gte(t) }},
+		{opts.CreatedEnd, true, func(t time.Time) gen.Condition { return f.CreatedAt.Lt(t) }},
+		{opts.UpdatedStart, false, func(t time.Time) gen.Condition { return f.UpdatedAt.Gte(t) }},
+		{opts.UpdatedEnd, true, func(t time.Time) gen.Condition { return f.UpdatedAt.Lt(t) }},
+	} {
+		t, err := parseSearchDate(r.value, r.end)
+		if err != nil {
+			return nil, err
+		}
+		if !t.IsZero() {
+			do = do.Where(r.field(t))
+		}
+	}
+	return do, nil
+}
+
+// parseSearchDate 解析 2006-01-02 格式的日期，结束日期包含当天（返回次日零点），为空时返回零值
+func parseSearchDate(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("日期格式错误: %s", value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// folderPaths 按 Pids 中的上级ID拼接每个文件所在的文件夹路径
+func folderPaths(files []*gorm_gen.File) map[int64]string {
+	parents := map[int64][]int64{}
+	ids := []int64{}
+	for _, file := range files {
+		parents[file.ID] = parsePids(file.Pids)
+		ids = append(ids, parents[file.ID]...)
+	}
+
+	names := map[int64]string{}
+	if len(ids) > 0 {
+		folders, _ := query.Q.File.Select(query.File.ID, query.File.Name).Where(query.File.ID.In(ids...)).Find()
+		for _, folder := range folders {
+			names[folder.ID] = folder.Name
+		}
+	}
+
+	paths := map[int64]string{}
+	for _, file := range files {
+		path := ""
+		for _, id := range parents[file.ID] {
+			if name, ok := names[id]; ok {
+				path += "/" + name
+			}
+		}
+		if path == "" {
+			path = "/"
+		}
+		paths[file.ID] = path
+	}
+	return paths
+}
+
+// searchSnippet 返回关键词所在的片段：文件名匹配时为文件名，否则取最新内容中匹配位置的前后文
+func searchSnippet(file *gorm_gen.File, keyword string) string {
+	if keyword == "" {
+		return ""
+	}
+	fullName := file.Name
+	if file.Ext != "" {
+		fullName += "." + file.Ext
+	}
+	if snippet := snippetOf(fullName, keyword); snippet != "" {
+		return snippet
+	}
+	fileContent, err := query.Q.FileContent.Select(query.FileContent.Text).
+		Where(query.FileContent.Fid.Eq(file.ID)).Order(query.FileContent.ID.Desc()).First()
+	if err != nil {
+		return ""
+	}
+	return snippetOf(fileContent.Text, keyword)
+}
+
+// snippetOf 在 text 中不区分大小写地查找 keyword，返回前后各 snippetWidth 个字符，未找到时返回空
+func snippetOf(text string, keyword string) string {
+	lowerRunes := func(s string) []rune {
+		runes := []rune(s)
+		for i, r := range runes {
+			runes[i] = unicode.ToLower(r)
+		}
+		return runes
+	}
+	runes := []rune(text)
+	lower, needle := lowerRunes(text), lowerRunes(keyword)
+	if len(needle) == 0 {
+		return ""
+	}
+
+	index := -1
+	for i := 0; i+len(needle) <= len(lower); i++ {
+		if slices.Equal(lower[i:i+len(needle)], needle) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return ""
+	}
+
+	start, end := max(0, index-snippetWidth), min(len(runes), index+len(needle)+snippetWidth)
+	snippet := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// toCommonFile 将文件记录转换为接口返回的结构
+func toCommonFile(file *gorm_gen.File) *common.File {
+	resp := &common.File{
+		ID:        file.ID,
+		Pid:       file.Pid,
+		Pids:      file.Pids,
+		Cid:       file.Cid,
+		Name:      file.Name,
+		Type:      file.Type,
+		Ext:       file.Ext,
+		Size:      file.Size,
+		Userid:    file.Userid,
+		Share:     file.Share,
+		Pshare:    file.Pshare,
+		CreatedID: file.CreatedID,
+		CreatedAt: file.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt: file.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+	resp.FullName = file.Name
+	if file.Ext != "" {
+		resp.FullName += "." + file.Ext
+	}
+	return resp
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/lang/cjk"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// BleveIndexer 内嵌的 Bleve 索引，适用于单机部署；索引目录同一时间只能由一个进程打开
+type BleveIndexer struct {
+	index bleve.Index
+}
+
+// NewBleveIndexer 打开索引目录，不存在时创建
+func NewBleveIndexer(path string) (*BleveIndexer, error) {
+	if _, err := os.Stat(path); err == nil {
+		index, err := bleve.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("打开 Bleve 索引失败: %v", err)
+		}
+		return &BleveIndexer{index: index}, nil
+	}
+	index, err := bleve.New(path, bleveMapping())
+	if err != nil {
+		return nil, fmt.Errorf("创建 Bleve 索引失败: %v", err)
+	}
+	return &BleveIndexer{index: index}, nil
+}
+
+// bleveMapping 文件名和全文使用 cjk 分词（中文按二元切分），类型、扩展名不分词，其余为数值
+func bleveMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = cjk.AnalyzerName
+	text.Store = false
+	text.IncludeTermVectors = false
+	text.IncludeInAll = false
+
+	term := bleve.NewTextFieldMapping()
+	term.Analyzer = keyword.Name
+	term.Store = false
+	term.IncludeInAll = false
+
+	number := bleve.NewNumericFieldMapping()
+	number.Store = false
+	number.IncludeInAll = false
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("name", text)
+	doc.AddFieldMappingsAt("text", text)
+	doc.AddFieldMappingsAt("type", term)
+	doc.AddFieldMappingsAt("ext", term)
+	for _, field := range []string{"pid", "pids", "pshare", "userid", "created_id", "size", "created_at", "updated_at"} {
+		doc.AddFieldMappingsAt(field, number)
+	}
+
+	m := bleve.NewIndexMapping()
+	m.DefaultMapping = doc
+	return m
+}
+
+// Index 批量写入文档，已存在的文档被覆盖
+func (b *BleveIndexer) Index(docs ...*SearchDocument) error {
+	batch := b.index.NewBatch()
+	for _, doc := range docs {
+		if err := batch.Index(strconv.FormatInt(doc.ID, 10), doc); err != nil {
+			return fmt.Errorf("写入索引失败: %v", err)
+		}
+	}
+	return b.index.Batch(batch)
+}
+
+// Delete 批量删除文档
+func (b *BleveIndexer) Delete(ids ...int64) error {
+	batch := b.index.NewBatch()
+	for _, id := range ids {
+		batch.Delete(strconv.FormatInt(id, 10))
+	}
+	return b.index.Batch(batch)
+}
+
+// Search 按关键词相关度搜索，权限和其他条件作为过滤
+func (b *BleveIndexer) Search(q IndexQuery) ([]SearchHit, int64, error) {
+	req := bleve.NewSearchRequestOptions(bleveQuery(q), max(q.Limit, 1), q.Offset, false)
+	res, err := b.index.Search(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	hits := make([]SearchHit, 0, len(res.Hits))
+	for _, hit := range res.Hits {
+		id, err := strconv.ParseInt(hit.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		hits = append(hits, SearchHit{ID: id})
+	}
+	return hits, int64(res.Total), nil
+}
+
+// Close 关闭索引
+func (b *BleveIndexer) Close() error {
+	return b.index.Close()
+}
+
+// bleveQuery 将搜索条件转换为 Bleve 查询
+func bleveQuery(q IndexQuery) query.Query {
+	var must []query.Query
+	if q.Keyword != "" {
+		name := bleve.NewMatchQuery(q.Keyword)
+		name.SetField("name")
+		name.SetOperator(query.MatchQueryOperatorAnd)
+		name.SetBoost(3)
+		text := bleve.NewMatchQuery(q.Keyword)
+		text.SetField("text")
+		text.SetOperator(query.MatchQueryOperatorAnd)
+		must = append(must, bleve.NewDisjunctionQuery(name, text))
+	}
+
+	// 与 searchQuery 一致：所有者、创建者、共享文件夹内或共享文件夹本身
+	var scope []query.Query
+	for _, id := range q.Userids {
+		scope = append(scope, bleveEqual("userid", id), bleveEqual("created_id", id))
+	}
+	shareIDs := make([]string, 0, len(q.Shares))
+	for _, id := range q.Shares {
+		scope = append(scope, bleveEqual("pshare", id))
+		shareIDs = append(shareIDs, strconv.FormatInt(id, 10))
+	}
+	if len(shareIDs) > 0 {
+		scope = append(scope, bleve.NewDocIDQuery(shareIDs))
+	}
+	if len(scope) > 0 {
+		must = append(must, bleve.NewDisjunctionQuery(scope...))
+	}
+
+	for _, f := range [][2]string{{"type", q.Type}, {"ext", q.Ext}} {
+		if f[1] != "" {
+			term := bleve.NewTermQuery(f[1])
+			term.SetField(f[0])
+			must = append(must, term)
+		}
+	}
+	if q.Userid > 0 {
+		must = append(must, bleveEqual("userid", q.Userid))
+	}
+	if q.Pid > 0 {
+		must = append(must, bleveEqual("pids", q.Pid))
+	}
+	for _, r := range indexDateRanges(q) {
+		if r.start == 0 && r.end == 0 {
+			continue
+		}
+		var min, max *float64
+		if r.start != 0 {
+			v := float64(r.start)
+			min = &v
+		}
+		if r.end != 0 {
+			v := float64(r.end)
+			max = &v
+		}
+		inclusive, exclusive := true, false
+		rq := bleve.NewNumericRangeInclusiveQuery(min, max, &inclusive, &exclusive)
+		rq.SetField(r.field)
+		must = append(must, rq)
+	}
+
+	if len(must) == 0 {
+		return bleve.NewMatchAllQuery()
+	}
+	return bleve.NewConjunctionQuery(must...)
+}
+
+// bleveEqual 数值字段等于 value
+func bleveEqual(field string, value int64) query.Query {
+	v := float64(value)
+	inclusive := true
+	rq := bleve.NewNumericRangeInclusiveQuery(&v, &v, &inclusive, &inclusive)
+	rq.SetField(field)
+	return rq
+}
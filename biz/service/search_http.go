@@ -0,0 +1,448 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// searchHttpClient 外部搜索服务的 HTTP 客户端
+type searchHttpClient struct {
+	baseURL string
+	key     string
+	index   string
+	auth    func(req *http.Request)
+	client  *http.Client
+
+	setupMu sync.Mutex
+	ready   bool
+}
+
+func newSearchHttpClient(baseURL string, key string, index string) (*searchHttpClient, error) {
+	if baseURL == "" {
+		return nil, errors.New("未配置 SEARCH_HTTP_URL")
+	}
+	if _, err := url.Parse(baseURL); err != nil {
+		return nil, fmt.Errorf("SEARCH_HTTP_URL 格式错误: %v", err)
+	}
+	if index == "" {
+		return nil, errors.New("未配置 SEARCH_HTTP_INDEX")
+	}
+	return &searchHttpClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		key:     key,
+		index:   index,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// do 发送请求，body 为 []byte 时原样发送，否则编码为 JSON；状态码不是 2xx 时返回错误，out 不为空时解析响应
+func (c *searchHttpClient) do(method string, path string, contentType string, body any, out any) error {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.key != "" && c.auth != nil {
+		c.auth(req)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &searchHttpError{Status: resp.StatusCode, Body: string(data)}
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("解析搜索服务响应失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// setup 首次写入前初始化索引设置，失败时下次写入重试
+func (c *searchHttpClient) setup(fn func() error) error {
+	c.setupMu.Lock()
+	defer c.setupMu.Unlock()
+	if c.ready {
+		return nil
+	}
+	if err := fn(); err != nil {
+		return fmt.Errorf("初始化搜索索引失败: %v", err)
+	}
+	c.ready = true
+	return nil
+}
+
+// searchHttpError 搜索服务返回的错误响应
+type searchHttpError struct {
+	Status int
+	Body   string
+}
+
+func (e *searchHttpError) Error() string {
+	body := e.Body
+	if len(body) > 512 {
+		body = body[:512]
+	}
+	return fmt.Sprintf("搜索服务返回 %d: %s", e.Status, body)
+}
+
+// MeiliIndexer Meilisearch 索引
+type MeiliIndexer struct {
+	*searchHttpClient
+}
+
+// NewMeiliIndexer 创建 Meilisearch 索引客户端，key 为 API Key
+func NewMeiliIndexer(baseURL string, key string, index string) (*MeiliIndexer, error) {
+	client, err := newSearchHttpClient(baseURL, key, index)
+	if err != nil {
+		return nil, err
+	}
+	client.auth = func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+client.key)
+	}
+	return &MeiliIndexer{client}, nil
+}
+
+func (m *MeiliIndexer) path(suffix string) string {
+	return "/indexes/" + url.PathEscape(m.index) + suffix
+}
+
+// ensureSettings 设置可过滤字段和搜索字段，索引不存在时由 Meilisearch 自动创建
+func (m *MeiliIndexer) ensureSettings() error {
+	return m.setup(func() error {
+		return m.do(http.MethodPatch, m.path("/settings"), "application/json", map[string]any{
+			"searchableAttributes": []string{"name", "text"},
+			"filterableAttributes": []string{"id", "pids", "pshare", "userid", "created_id", "type", "ext", "created_at", "updated_at"},
+		}, nil)
+	})
+}
+
+// Index 批量写入文档，Meilisearch 异步处理写入任务
+func (m *MeiliIndexer) Index(docs ...*SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	if err := m.ensureSettings(); err != nil {
+		return err
+	}
+	return m.do(http.MethodPost, m.path("/documents?primaryKey=id"), "application/json", docs, nil)
+}
+
+// Delete 批量删除文档
+func (m *MeiliIndexer) Delete(ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return m.do(http.MethodPost, m.path("/documents/delete-batch"), "application/json", ids, nil)
+}
+
+// Search 搜索，权限和其他条件转换为 filter 表达式
+func (m *MeiliIndexer) Search(q IndexQuery) ([]SearchHit, int64, error) {
+	var resp struct {
+		Hits []struct {
+			ID int64 `json:"id"`
+		} `json:"hits"`
+		EstimatedTotalHits int64 `json:"estimatedTotalHits"`
+	}
+	err := m.do(http.MethodPost, m.path("/search"), "application/json", map[string]any{
+		"q":                    q.Keyword,
+		"filter":               meiliFilter(q),
+		"offset":               q.Offset,
+		"limit":                max(q.Limit, 1),
+		"attributesToRetrieve": []string{"id"},
+	}, &resp)
+	if err != nil {
+		return nil, 0, err
+	}
+	hits := make([]SearchHit, 0, len(resp.Hits))
+	for _, hit := range resp.Hits {
+		hits = append(hits, SearchHit{ID: hit.ID})
+	}
+	return hits, resp.EstimatedTotalHits, nil
+}
+
+// Close 无需释放资源
+func (m *MeiliIndexer) Close() error {
+	return nil
+}
+
+// meiliFilter 生成 Meilisearch 的 filter 表达式，数组字段 pids 包含任一值即匹配
+func meiliFilter(q IndexQuery) string {
+	list := func(ids []int64) string {
+		items := make([]string, 0, len(ids))
+		for _, id := range ids {
+			items = append(items, strconv.FormatInt(id, 10))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+
+	var filters []string
+	var scope []string
+	if len(q.Userids) > 0 {
+		scope = append(scope, "userid IN "+list(q.Userids), "created_id IN "+list(q.Userids))
+	}
+	if len(q.Shares) > 0 {
+		scope = append(scope, "pshare IN "+list(q.Shares), "id IN "+list(q.Shares))
+	}
+	if len(scope) > 0 {
+		filters = append(filters, "("+strings.Join(scope, " OR ")+")")
+	}
+	if q.Type != "" {
+		filters = append(filters, "type = "+strconv.Quote(q.Type))
+	}
+	if q.Ext != "" {
+		filters = append(filters, "ext = "+strconv.Quote(q.Ext))
+	}
+	if q.Userid > 0 {
+		filters = append(filters, fmt.Sprintf("userid = %d", q.Userid))
+	}
+	if q.Pid > 0 {
+		filters = append(filters, fmt.Sprintf("pids = %d", q.Pid))
+	}
+	for _, r := range indexDateRanges(q) {
+		if r.start != 0 {
+			filters = append(filters, fmt.Sprintf("%s >= %d", r.field, r.start))
+		}
+		if r.end != 0 {
+			filters = append(filters, fmt.Sprintf("%s < %d", r.field, r.end))
+		}
+	}
+	return strings.Join(filters, " AND ")
+}
+
+// ElasticIndexer Elasticsearch 索引
+type ElasticIndexer struct {
+	*searchHttpClient
+}
+
+// NewElasticIndexer 创建 Elasticsearch 索引客户端，key 为 API Key，或 user:password 使用基本认证
+func NewElasticIndexer(baseURL string, key string, index string) (*ElasticIndexer, error) {
+	client, err := newSearchHttpClient(baseURL, key, index)
+	if err != nil {
+		return nil, err
+	}
+	client.auth = func(req *http.Request) {
+		if user, password, ok := strings.Cut(client.key, ":"); ok {
+			req.SetBasicAuth(user, password)
+			return
+		}
+		req.Header.Set("Authorization", "ApiKey "+client.key)
+	}
+	return &ElasticIndexer{client}, nil
+}
+
+// ensureIndex 创建索引和字段映射，索引已存在时忽略
+func (e *ElasticIndexer) ensureIndex() error {
+	return e.setup(func() error {
+		long := map[string]string{"type": "long"}
+		err := e.do(http.MethodPut, "/"+url.PathEscape(e.index), "application/json", map[string]any{
+			"mappings": map[string]any{
+				"properties": map[string]any{
+					"id": long, "pid": long, "pids": long, "pshare": long, "userid": long, "created_id": long,
+					"size": long, "created_at": long, "updated_at": long,
+					"name": map[string]string{"type": "text"},
+					"text": map[string]string{"type": "text"},
+					"type": map[string]string{"type": "keyword"},
+					"ext":  map[string]string{"type": "keyword"},
+				},
+			},
+		}, nil)
+		var httpErr *searchHttpError
+		if errors.As(err, &httpErr) && httpErr.Status == http.StatusBadRequest &&
+			strings.Contains(httpErr.Body, "resource_already_exists_exception") {
+			return nil
+		}
+		return err
+	})
+}
+
+// bulk 发送 _bulk 请求，逐条操作失败时返回第一个错误
+func (e *ElasticIndexer) bulk(lines []any) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, line := range lines {
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+	}
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID     string          `json:"_id"`
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := e.do(http.MethodPost, "/_bulk", "application/x-ndjson", buf.Bytes(), &resp); err != nil {
+		return err
+	}
+	if !resp.Errors {
+		return nil
+	}
+	for _, item := range resp.Items {
+		for action, result := range item {
+			// 删除不存在的文档返回 404，视为成功
+			if result.Status >= 300 && !(action == "delete" && result.Status == http.StatusNotFound) {
+				return fmt.Errorf("%s 文档 %s 失败: %s", action, result.ID, result.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// Index 批量写入文档
+func (e *ElasticIndexer) Index(docs ...*SearchDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	if err := e.ensureIndex(); err != nil {
+		return err
+	}
+	lines := make([]any, 0, len(docs)*2)
+	for _, doc := range docs {
+		lines = append(lines, map[string]any{"index": map[string]any{"_index": e.index, "_id": strconv.FormatInt(doc.ID, 10)}}, doc)
+	}
+	return e.bulk(lines)
+}
+
+// Delete 批量删除文档
+func (e *ElasticIndexer) Delete(ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	lines := make([]any, 0, len(ids))
+	for _, id := range ids {
+		lines = append(lines, map[string]any{"delete": map[string]any{"_index": e.index, "_id": strconv.FormatInt(id, 10)}})
+	}
+	return e.bulk(lines)
+}
+
+// Search 搜索，匹配的全文片段由高亮返回
+func (e *ElasticIndexer) Search(q IndexQuery) ([]SearchHit, int64, error) {
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID        string              `json:"_id"`
+				Highlight map[string][]string `json:"highlight"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	err := e.do(http.MethodPost, "/"+url.PathEscape(e.index)+"/_search", "application/json", elasticQuery(q), &resp)
+	if err != nil {
+		return nil, 0, err
+	}
+	hits := make([]SearchHit, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		id, err := strconv.ParseInt(hit.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		var snippet string
+		if fragments := hit.Highlight["text"]; len(fragments) > 0 {
+			snippet = "…" + strings.Join(strings.Fields(fragments[0]), " ") + "…"
+		}
+		hits = append(hits, SearchHit{ID: id, Snippet: snippet})
+	}
+	return hits, resp.Hits.Total.Value, nil
+}
+
+// Close 无需释放资源
+func (e *ElasticIndexer) Close() error {
+	return nil
+}
+
+// elasticQuery 生成 _search 请求体
+func elasticQuery(q IndexQuery) map[string]any {
+	must, filter := []any{}, []any{}
+	if q.Keyword != "" {
+		must = append(must, map[string]any{
+			"multi_match": map[string]any{"query": q.Keyword, "fields": []string{"name^3", "text"}, "operator": "and"},
+		})
+	}
+
+	var scope []any
+	if len(q.Userids) > 0 {
+		scope = append(scope,
+			map[string]any{"terms": map[string]any{"userid": q.Userids}},
+			map[string]any{"terms": map[string]any{"created_id": q.Userids}})
+	}
+	if len(q.Shares) > 0 {
+		scope = append(scope,
+			map[string]any{"terms": map[string]any{"pshare": q.Shares}},
+			map[string]any{"terms": map[string]any{"id": q.Shares}})
+	}
+	if len(scope) > 0 {
+		filter = append(filter, map[string]any{"bool": map[string]any{"should": scope, "minimum_should_match": 1}})
+	}
+	if q.Type != "" {
+		filter = append(filter, map[string]any{"term": map[string]any{"type": q.Type}})
+	}
+	if q.Ext != "" {
+		filter = append(filter, map[string]any{"term": map[string]any{"ext": q.Ext}})
+	}
+	if q.Userid > 0 {
+		filter = append(filter, map[string]any{"term": map[string]any{"userid": q.Userid}})
+	}
+	if q.Pid > 0 {
+		filter = append(filter, map[string]any{"term": map[string]any{"pids": q.Pid}})
+	}
+	for _, r := range indexDateRanges(q) {
+		bounds := map[string]any{}
+		if r.start != 0 {
+			bounds["gte"] = r.start
+		}
+		if r.end != 0 {
+			bounds["lt"] = r.end
+		}
+		if len(bounds) > 0 {
+			filter = append(filter, map[string]any{"range": map[string]any{r.field: bounds}})
+		}
+	}
+
+	return map[string]any{
+		"from":             q.Offset,
+		"size":             max(q.Limit, 1),
+		"track_total_hits": true,
+		"_source":          false,
+		"query":            map[string]any{"bool": map[string]any{"must": must, "filter": filter}},
+		"highlight": map[string]any{
+			"pre_tags":  []string{""},
+			"post_tags": []string{""},
+			"fields":    map[string]any{"text": map[string]any{"fragment_size": snippetWidth * 2, "number_of_fragments": 1}},
+		},
+	}
+}
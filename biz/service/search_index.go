@@ -0,0 +1,358 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
+	"gorm.io/gen"
+)
+
+// SearchDocument 写入搜索索引的文件文档，权限相关字段用于在索引中预过滤
+type SearchDocument struct {
+	ID        int64   `json:"id"`
+	Pid       int64   `json:"pid"`
+	Pids      []int64 `json:"pids"` // 全部上级文件夹ID，用于按文件夹搜索子级
+	Pshare    int64   `json:"pshare"`
+	Userid    int64   `json:"userid"`
+	CreatedID int64   `json:"created_id"`
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Ext       string  `json:"ext"`
+	Size      int64   `json:"size"`
+	Text      string  `json:"text"`
+	CreatedAt int64   `json:"created_at"` // Unix 时间戳(秒)
+	UpdatedAt int64   `json:"updated_at"`
+}
+
+// IndexQuery 索引搜索条件，为空的条件不参与过滤
+type IndexQuery struct {
+	Keyword      string
+	Type         string
+	Ext          string
+	Userid       int64
+	Pid          int64   // 只搜索该文件夹下的文件（任意层级）
+	Userids      []int64 // 可访问的所有者或创建者
+	Shares       []int64 // 可访问的共享文件夹
+	CreatedStart time.Time
+	CreatedEnd   time.Time // 不包含
+	UpdatedStart time.Time
+	UpdatedEnd   time.Time
+	Offset       int
+	Limit        int
+}
+
+// SearchHit 索引搜索命中的文件，按相关度排序
+type SearchHit struct {
+	ID      int64
+	Snippet string // 匹配片段，为空时由调用方从文件名或内容生成
+}
+
+// SearchIndexer 外部搜索索引，由文件的新增、修改、删除事件同步
+type SearchIndexer interface {
+	Index(docs ...*SearchDocument) error
+	Delete(ids ...int64) error
+	Search(q IndexQuery) ([]SearchHit, int64, error)
+	Close() error
+}
+
+// indexEvent 索引同步事件
+type indexEvent struct {
+	ids    []int64
+	delete bool
+}
+
+var (
+	searchIndexer     SearchIndexer
+	searchIndexerOnce sync.Once
+	indexQueue        chan indexEvent
+	indexQueueOnce    sync.Once
+)
+
+// getSearchIndexer 按 SEARCH_INDEXER 创建搜索索引，未配置或创建失败时返回 nil，搜索退回 MySQL
+func getSearchIndexer() SearchIndexer {
+	searchIndexerOnce.Do(func() {
+		indexer, err := NewSearchIndexer(config.SearchIndexer)
+		if err != nil {
+			log.Printf("创建搜索索引失败，改用 MySQL 搜索: %v", err)
+			return
+		}
+		searchIndexer = indexer
+	})
+	return searchIndexer
+}
+
+// NewSearchIndexer 创建指定类型的搜索索引，kind 为空时返回 nil
+func NewSearchIndexer(kind string) (SearchIndexer, error) {
+	switch strings.ToLower(kind) {
+	case "":
+		return nil, nil
+	case "bleve":
+		return NewBleveIndexer(config.SearchBlevePath)
+	case "meilisearch":
+		return NewMeiliIndexer(config.SearchHttpUrl, config.SearchHttpKey, config.SearchHttpIndex)
+	case "elasticsearch":
+		return NewElasticIndexer(config.SearchHttpUrl, config.SearchHttpKey, config.SearchHttpIndex)
+	}
+	return nil, fmt.Errorf("不支持的搜索索引: %s", kind)
+}
+
+// indexFiles 将文件加入索引同步队列，由后台协程按顺序写入，未配置索引时不处理
+func indexFiles(ids ...int64) {
+	pushIndexEvent(indexEvent{ids: ids})
+}
+
+// unindexFiles 将文件从索引中删除
+func unindexFiles(ids ...int64) {
+	pushIndexEvent(indexEvent{ids: ids, delete: true})
+}
+
+// pushIndexEvent 单个协程顺序处理，保证同一文件的新增和删除不会乱序
+func pushIndexEvent(event indexEvent) {
+	if len(event.ids) == 0 || getSearchIndexer() == nil {
+		return
+	}
+	indexQueueOnce.Do(func() {
+		indexQueue = make(chan indexEvent, 1000)
+		go func() {
+			for event := range indexQueue {
+				var err error
+				if event.delete {
+					err = getSearchIndexer().Delete(event.ids...)
+				} else {
+					err = syncIndex(getSearchIndexer(), event.ids)
+				}
+				if err != nil {
+					log.Printf("同步搜索索引失败, 文件ID: %v, 错误: %v", event.ids, err)
+				}
+			}
+		}()
+	})
+	select {
+	case indexQueue <- event:
+	default:
+		log.Printf("搜索索引队列已满，跳过文件ID: %v，可稍后执行 storagectl reindex", event.ids)
+	}
+}
+
+// syncIndex 读取文件的最新记录写入索引，已删除的文件从索引中移除
+func syncIndex(indexer SearchIndexer, ids []int64) error {
+	files, err := query.Q.File.Where(query.File.ID.In(ids...)).Find()
+	if err != nil {
+		return fmt.Errorf("查询文件失败: %v", err)
+	}
+	docs, err := searchDocuments(files)
+	if err != nil {
+		return err
+	}
+
+	found := map[int64]bool{}
+	for _, doc := range docs {
+		found[doc.ID] = true
+	}
+	var missing []int64
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		if err := indexer.Delete(missing...); err != nil {
+			return err
+		}
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+	return indexer.Index(docs...)
+}
+
+// searchDocuments 为文件生成索引文档，全文取最新内容的 Text
+func searchDocuments(files []*gorm_gen.File) ([]*SearchDocument, error) {
+	ids := make([]int64, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.ID)
+	}
+	texts := map[int64]string{}
+	if len(ids) > 0 {
+		// 先按文件取最新内容的 ID，只读取这些记录的 Text，避免加载全部历史版本
+		var latest []struct{ ID int64 }
+		err := query.Q.FileContent.Select(query.FileContent.ID.Max().As("id")).
+			Where(query.FileContent.Fid.In(ids...)).Group(query.FileContent.Fid).Scan(&latest)
+		if err != nil {
+			return nil, fmt.Errorf("查询文件内容失败: %v", err)
+		}
+		contentIDs := make([]int64, 0, len(latest))
+		for _, row := range latest {
+			contentIDs = append(contentIDs, row.ID)
+		}
+		if len(contentIDs) > 0 {
+			contents, err := query.Q.FileContent.Select(query.FileContent.Fid, query.FileContent.Text).
+				Where(query.FileContent.ID.In(contentIDs...)).Find()
+			if err != nil {
+				return nil, fmt.Errorf("查询文件内容失败: %v", err)
+			}
+			for _, fileContent := range contents {
+				texts[fileContent.Fid] = fileContent.Text
+			}
+		}
+	}
+
+	docs := make([]*SearchDocument, 0, len(files))
+	for _, file := range files {
+		docs = append(docs, newSearchDocument(file, texts[file.ID]))
+	}
+	return docs, nil
+}
+
+// newSearchDocument 将文件记录转换为索引文档
+func newSearchDocument(file *gorm_gen.File, text string) *SearchDocument {
+	name := file.Name
+	if file.Ext != "" {
+		name += "." + file.Ext
+	}
+	return &SearchDocument{
+		ID:        file.ID,
+		Pid:       file.Pid,
+		Pids:      parsePids(file.Pids),
+		Pshare:    file.Pshare,
+		Userid:    file.Userid,
+		CreatedID: file.CreatedID,
+		Name:      name,
+		Type:      file.Type,
+		Ext:       strings.ToLower(file.Ext),
+		Size:      file.Size,
+		Text:      text,
+		CreatedAt: file.CreatedAt.Unix(),
+		UpdatedAt: file.UpdatedAt.Unix(),
+	}
+}
+
+// parsePids 解析 Pids 中的上级文件夹ID
+func parsePids(pids string) []int64 {
+	ids := []int64{}
+	for _, match := range pidsPattern.FindAllString(pids, -1) {
+		id, _ := strconv.ParseInt(match, 10, 64)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// ReindexAll 重建搜索索引，每批写入后回调 fn 报告已处理的数量
+func ReindexAll(indexer SearchIndexer, fn func(count int)) error {
+	var files []*gorm_gen.File
+	count := 0
+	return query.Q.File.FindInBatches(&files, 100, func(tx gen.Dao, batch int) error {
+		docs, err := searchDocuments(files)
+		if err != nil {
+			return err
+		}
+		if err := indexer.Index(docs...); err != nil {
+			return err
+		}
+		count += len(docs)
+		fn(count)
+		return nil
+	})
+}
+
+// searchIndex 使用外部索引搜索，命中的文件再从数据库读取并经 getPermission 确认
+func searchIndex(indexer SearchIndexer, user *User, opts SearchOptions) (*SearchResult, error) {
+	userids := searchUserids(user)
+	shares, err := accessibleShares(userids)
+	if err != nil {
+		return nil, err
+	}
+
+	q := IndexQuery{
+		Keyword: opts.Keyword,
+		Type:    opts.Type,
+		Ext:     strings.ToLower(strings.TrimPrefix(opts.Ext, ".")),
+		Userid:  opts.Userid,
+		Pid:     opts.Pid,
+		Userids: userids,
+		Shares:  shares,
+		Offset:  (opts.Page - 1) * opts.PageSize,
+		Limit:   opts.PageSize,
+	}
+	for _, r := range []struct {
+		value  string
+		end    bool
+		target *time.Time
+	}{
+		{opts.CreatedStart, false, &q.CreatedStart},
+		{opts.CreatedEnd, true, &q.CreatedEnd},
+		{opts.UpdatedStart, false, &q.UpdatedStart},
+		{opts.UpdatedEnd, true, &q.UpdatedEnd},
+	} {
+		if *r.target, err = parseSearchDate(r.value, r.end); err != nil {
+			return nil, err
+		}
+	}
+
+	hits, total, err := indexer.Search(q)
+	if err != nil {
+		return nil, fmt.Errorf("搜索失败: %v", err)
+	}
+	ids := make([]int64, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	files := []*gorm_gen.File{}
+	if len(ids) > 0 {
+		if files, err = query.Q.File.Where(query.File.ID.In(ids...)).Find(); err != nil {
+			return nil, fmt.Errorf("搜索失败: %v", err)
+		}
+	}
+	byID := make(map[int64]*gorm_gen.File, len(files))
+	for _, file := range files {
+		byID[file.ID] = file
+	}
+
+	paths := folderPaths(files)
+	result := &SearchResult{Total: total, Page: opts.Page, PageSize: opts.PageSize, List: []*SearchItem{}}
+	for _, hit := range hits {
+		// 索引尚未同步删除的文件在数据库中已不存在
+		file, ok := byID[hit.ID]
+		if !ok || getPermission(file, userids) < 0 {
+			continue
+		}
+		snippet := hit.Snippet
+		if snippet == "" {
+			snippet = searchSnippet(file, opts.Keyword)
+		}
+		result.List = append(result.List, &SearchItem{
+			File:    toCommonFile(file),
+			Path:    paths[file.ID],
+			Snippet: snippet,
+		})
+	}
+	return result, nil
+}
+
+// indexDateRange 日期过滤条件，start 包含、end 不包含，为 0 时不限制
+type indexDateRange struct {
+	field string
+	start int64
+	end   int64
+}
+
+// indexDateRanges 将搜索条件中的日期转换为时间戳
+func indexDateRanges(q IndexQuery) []indexDateRange {
+	unix := func(t time.Time) int64 {
+		if t.IsZero() {
+			return 0
+		}
+		return t.Unix()
+	}
+	return []indexDateRange{
+		{"created_at", unix(q.CreatedStart), unix(q.CreatedEnd)},
+		{"updated_at", unix(q.UpdatedStart), unix(q.UpdatedEnd)},
+	}
+}
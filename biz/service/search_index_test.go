@@ -0,0 +1,221 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSearchDocuments() []*SearchDocument {
+	return []*SearchDocument{
+		{ID: 1, Userid: 1, CreatedID: 1, Name: "项目", Type: "folder", CreatedAt: 100, UpdatedAt: 100},
+		{ID: 2, Pid: 1, Pids: []int64{1}, Userid: 1, CreatedID: 1, Name: "存储设计.docx", Type: "word", Ext: "docx",
+			Text: "本文档介绍网盘的存储设计，包括去重与配额。", CreatedAt: 200, UpdatedAt: 200},
+		{ID: 3, Userid: 2, CreatedID: 2, Name: "会议纪要.txt", Type: "txt", Ext: "txt",
+			Text: "讨论存储设计的评审意见", CreatedAt: 300, UpdatedAt: 300},
+		{ID: 4, Pid: 9, Pids: []int64{9}, Pshare: 9, Userid: 3, CreatedID: 3, Name: "共享设计稿.pdf", Type: "pdf", Ext: "pdf",
+			CreatedAt: 400, UpdatedAt: 400},
+	}
+}
+
+func hitIDs(hits []SearchHit) []int64 {
+	ids := []int64{}
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	return ids
+}
+
+func Test_BleveIndexer(t *testing.T) {
+	indexer, err := NewBleveIndexer(filepath.Join(t.TempDir(), "search.bleve"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer indexer.Close()
+	if err := indexer.Index(testSearchDocuments()...); err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(q IndexQuery) []int64 {
+		t.Helper()
+		q.Limit = 10
+		hits, _, err := indexer.Search(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hitIDs(hits)
+	}
+	equal := func(got []int64, want ...int64) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		seen := map[int64]bool{}
+		for _, id := range got {
+			seen[id] = true
+		}
+		for _, id := range want {
+			if !seen[id] {
+				return false
+			}
+		}
+		return true
+	}
+
+	// 只能搜索到会员1的文件和共享文件夹9中的文件
+	scope := IndexQuery{Keyword: "设计", Userids: []int64{0, 1}, Shares: []int64{9}}
+	if got := search(scope); !equal(got, 2, 4) {
+		t.Errorf("unexpected permission filtered hits %v", got)
+	}
+	q := scope
+	q.Keyword = "去重"
+	if got := search(q); !equal(got, 2) {
+		t.Errorf("full text should match content, got %v", got)
+	}
+	q = scope
+	q.Type = "pdf"
+	if got := search(q); !equal(got, 4) {
+		t.Errorf("type filter failed, got %v", got)
+	}
+	q = scope
+	q.Pid = 1
+	if got := search(q); !equal(got, 2) {
+		t.Errorf("pid filter failed, got %v", got)
+	}
+	q = scope
+	q.CreatedStart, q.CreatedEnd = time.Unix(300, 0), time.Unix(500, 0)
+	if got := search(q); !equal(got, 4) {
+		t.Errorf("date filter failed, got %v", got)
+	}
+
+	if err := indexer.Delete(2); err != nil {
+		t.Fatal(err)
+	}
+	if got := search(scope); !equal(got, 4) {
+		t.Errorf("deleted document still found, got %v", got)
+	}
+}
+
+func Test_MeiliIndexer(t *testing.T) {
+	var settings, documents, search map[string]any
+	var deleted []int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "PATCH /indexes/files/settings":
+			json.Unmarshal(body, &settings)
+		case "POST /indexes/files/documents":
+			var docs []map[string]any
+			json.Unmarshal(body, &docs)
+			documents = docs[0]
+		case "POST /indexes/files/documents/delete-batch":
+			json.Unmarshal(body, &deleted)
+		case "POST /indexes/files/search":
+			json.Unmarshal(body, &search)
+			w.Write([]byte(`{"hits":[{"id":2},{"id":4}],"estimatedTotalHits":2}`))
+			return
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"taskUid":1}`))
+	}))
+	defer server.Close()
+
+	indexer, err := NewMeiliIndexer(server.URL, "secret", "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.Index(testSearchDocuments()[1]); err != nil {
+		t.Fatal(err)
+	}
+	if settings == nil || documents["name"] != "存储设计.docx" {
+		t.Errorf("unexpected settings %v or document %v", settings, documents)
+	}
+	if err := indexer.Delete(2, 3); err != nil || len(deleted) != 2 {
+		t.Errorf("unexpected delete %v, %v", deleted, err)
+	}
+
+	hits, total, err := indexer.Search(IndexQuery{
+		Keyword: "设计", Type: "word", Pid: 1, Userids: []int64{0, 1}, Shares: []int64{9},
+		CreatedStart: time.Unix(100, 0), Limit: 20,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(hitIDs(hits)) != 2 || hits[1].ID != 4 {
+		t.Errorf("unexpected hits %v, total %d", hits, total)
+	}
+	want := `(userid IN [0, 1] OR created_id IN [0, 1] OR pshare IN [9] OR id IN [9]) AND type = "word" AND pids = 1 AND created_at >= 100`
+	if search["filter"] != want || search["q"] != "设计" {
+		t.Errorf("unexpected search request %v", search)
+	}
+}
+
+func Test_ElasticIndexer(t *testing.T) {
+	var created bool
+	var bulk []string
+	var search map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "ApiKey secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "PUT /files":
+			if created {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":{"type":"resource_already_exists_exception"}}`))
+				return
+			}
+			created = true
+			w.Write([]byte(`{"acknowledged":true}`))
+		case "POST /_bulk":
+			bulk = strings.Split(strings.TrimSpace(string(body)), "\n")
+			w.Write([]byte(`{"errors":true,"items":[{"delete":{"_id":"3","status":404}}]}`))
+		case "POST /files/_search":
+			json.Unmarshal(body, &search)
+			w.Write([]byte(`{"hits":{"total":{"value":1},"hits":[{"_id":"2","highlight":{"text":["网盘的存储设计"]}}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	indexer, err := NewElasticIndexer(server.URL, "secret", "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := indexer.Index(testSearchDocuments()[1:3]...); err != nil {
+		t.Fatal(err)
+	}
+	if !created || len(bulk) != 4 || !strings.Contains(bulk[0], `"_id":"2"`) {
+		t.Errorf("unexpected bulk index %v", bulk)
+	}
+	// 删除不存在的文档不视为失败
+	if err := indexer.Delete(3); err != nil || len(bulk) != 1 {
+		t.Errorf("unexpected bulk delete %v, %v", bulk, err)
+	}
+
+	hits, total, err := indexer.Search(IndexQuery{Keyword: "设计", Userids: []int64{0, 1}, Limit: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(hits) != 1 || hits[0].ID != 2 || hits[0].Snippet != "…网盘的存储设计…" {
+		t.Errorf("unexpected hits %v, total %d", hits, total)
+	}
+	filter := search["query"].(map[string]any)["bool"].(map[string]any)["filter"].([]any)
+	if len(filter) != 1 || search["size"] != float64(20) {
+		t.Errorf("unexpected search request %v", search)
+	}
+}
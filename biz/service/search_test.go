@@ -0,0 +1,74 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/gorm_gen"
+)
+
+func Test_SnippetOf(t *testing.T) {
+	text := "第一章 概述。本文档介绍 CloudDisk 的存储设计，包括去重与配额。"
+	if got := snippetOf(text, "clouddisk"); got != text {
+		t.Errorf("unexpected snippet %q", got)
+	}
+	if got := snippetOf(text, "不存在"); got != "" {
+		t.Errorf("missing keyword should return empty snippet, got %q", got)
+	}
+
+	long := ""
+	for i := 0; i < 100; i++ {
+		long += "一"
+	}
+	got := snippetOf(long+"关键词"+long, "关键词")
+	if []rune(got)[0] != '…' || len([]rune(got)) != 1+snippetWidth+3+snippetWidth+1 {
+		t.Errorf("unexpected long snippet %q", got)
+	}
+}
+
+func Test_EscapeLike(t *testing.T) {
+	if got := escapeLike(`50%_a\b`); got != `50\%\_a\\b` {
+		t.Errorf("unexpected escape %q", got)
+	}
+}
+
+func Test_SearchSharedSubfolder(t *testing.T) {
+	newTestDB(t)
+	useTestStore(t, "test")
+	owner := &User{Userid: 1}
+	shared := createTestFile(t, &gorm_gen.File{Name: "项目", Type: "folder", Share: 1, Userid: 1, CreatedID: 1})
+	query.Q.File_User.Create(&gorm_gen.File_User{FileID: shared.ID, Userid: 2, Permission: 0})
+
+	// 上传到共享文件夹下新建的子文件夹
+	userid, pid, err := prepareUploadFolder(owner, int(shared.ID), "设计/架构图.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := putBlob("test", "txt", strings.NewReader("架构说明"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := createUploadRecord(owner, userid, pid, "架构图.txt", "设计/架构图.txt", false, blob); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Search(&User{Userid: 2}, SearchOptions{Keyword: "架构"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 1 || len(result.List) != 1 {
+		t.Fatalf("share member found %d files, want 1", result.Total)
+	}
+	if path := result.List[0].Path; path != "/项目/设计" {
+		t.Errorf("path = %q, want /项目/设计", path)
+	}
+
+	result, err = Search(&User{Userid: 3}, SearchOptions{Keyword: "架构"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 0 {
+		t.Errorf("non-member found %d files, want 0", result.Total)
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cloudisk/pkg/config"
+)
+
+// 服务端加密方式
+const (
+	SSEAES256 = "aes256" // 云存储托管密钥（SSE-OSS、SSE-COS）
+	SSEKMS    = "kms"    // KMS 托管密钥（SSE-KMS）
+	SSESM4    = "sm4"    // 国密 SM4，仅 OSS 支持
+)
+
+// sseProbeKey 自检时写入的探测对象
+const sseProbeKey = ".cloudisk/sse-check"
+
+// ServerSideEncryption 服务端加密设置，Mode 为空时不指定，由存储桶的默认加密决定
+type ServerSideEncryption struct {
+	Mode  string // aes256、kms、sm4
+	KeyID string // KMS 密钥ID，为空时使用云存储默认的 KMS 密钥
+}
+
+// sseModeNames 各云存储返回的加密方式（小写）与统一名称的对应关系
+var sseModeNames = map[string]string{
+	"":        "",
+	"aes256":  SSEAES256,
+	"kms":     SSEKMS,
+	"cos/kms": SSEKMS,
+	"sm4":     SSESM4,
+}
+
+// newSSE 按配置创建服务端加密设置，无法识别的加密方式原样转为小写，在自检时报告
+func newSSE(mode string, keyID string) ServerSideEncryption {
+	return ServerSideEncryption{Mode: sseMode(mode), KeyID: strings.TrimSpace(keyID)}
+}
+
+// sseMode 将各云存储返回的加密方式统一为小写名称
+func sseMode(raw string) string {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if name, ok := sseModeNames[raw]; ok {
+		return name
+	}
+	return raw
+}
+
+func (s ServerSideEncryption) String() string {
+	if s.Mode == "" {
+		return "未加密"
+	}
+	if s.KeyID != "" {
+		return s.Mode + "(" + s.KeyID + ")"
+	}
+	return s.Mode
+}
+
+// ossHeaders 返回 OSS 的 x-oss-server-side-encryption 和 x-oss-server-side-encryption-key-id 取值，未设置时为 nil
+func (s ServerSideEncryption) ossHeaders() (*string, *string) {
+	var mode, keyID *string
+	switch s.Mode {
+	case SSEAES256:
+		mode = ptr("AES256")
+	case SSEKMS:
+		mode = ptr("KMS")
+		if s.KeyID != "" {
+			keyID = ptr(s.KeyID)
+		}
+	case SSESM4:
+		mode = ptr("SM4")
+	}
+	return mode, keyID
+}
+
+// cosHeader 返回 COS 上传和复制请求的加密请求头，未设置时为 nil
+func (s ServerSideEncryption) cosHeader() *http.Header {
+	header := http.Header{}
+	switch s.Mode {
+	case SSEAES256:
+		header.Set("x-cos-server-side-encryption", "AES256")
+	case SSEKMS:
+		header.Set("x-cos-server-side-encryption", "cos/kms")
+		if s.KeyID != "" {
+			header.Set("x-cos-server-side-encryption-cos-kms-key-id", s.KeyID)
+		}
+	default:
+		return nil
+	}
+	return &header
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+// EncryptionChecker 能查询存储桶默认加密的云存储，自检时以探测对象验证实际生效的加密方式
+type EncryptionChecker interface {
+	// BucketEncryption 返回存储桶的默认加密，未配置时 Mode 为空
+	BucketEncryption() (ServerSideEncryption, error)
+	// ObjectEncryption 返回对象实际的加密方式
+	ObjectEncryption(objectName string) (ServerSideEncryption, error)
+}
+
+// SSECheck 单个云存储的服务端加密自检结果
+type SSECheck struct {
+	Store         string
+	Configured    ServerSideEncryption
+	BucketDefault ServerSideEncryption
+	Applied       ServerSideEncryption
+}
+
+// Problems 比较配置、存储桶默认加密和探测对象实际的加密方式，返回需要处理的问题
+func (c SSECheck) Problems() []string {
+	problems := []string{}
+	if _, ok := sseModeNames[c.Configured.Mode]; !ok {
+		problems = append(problems, fmt.Sprintf("不支持的加密方式: %s", c.Configured.Mode))
+	}
+	expected := c.Configured
+	if expected.Mode == "" {
+		expected = c.BucketDefault
+	}
+	if c.Applied.Mode != expected.Mode || (expected.KeyID != "" && c.Applied.KeyID != expected.KeyID) {
+		problems = append(problems, fmt.Sprintf("上传的对象实际为 %s，应为 %s", c.Applied, expected))
+	}
+	if c.Configured.Mode != "" && c.BucketDefault.Mode != "" && c.Configured != c.BucketDefault {
+		problems = append(problems, fmt.Sprintf("配置的加密方式 %s 与存储桶默认加密 %s 不一致，以请求指定的为准", c.Configured, c.BucketDefault))
+	}
+	return problems
+}
+
+// sseTarget 参与自检的云存储
+type sseTarget struct {
+	name  string
+	store ObjectStore
+	sse   ServerSideEncryption
+}
+
+// sseTargets 返回写入新内容的云存储及其服务端加密设置：默认云存储、副本云存储和各存储配置
+func sseTargets() []sseTarget {
+	targets := []sseTarget{}
+	for _, provider := range append([]string{defaultProvider()}, replicaProviders(defaultProvider())...) {
+		store, err := getObjectStore(provider)
+		if err != nil || getStorageRouting().profile(provider) != nil {
+			continue
+		}
+		targets = append(targets, sseTarget{name: provider, store: store, sse: providerSSE(provider)})
+	}
+	for _, profile := range getStorageRouting().Profiles {
+		store, err := profileStore(profile.ID)
+		if err != nil {
+			continue
+		}
+		targets = append(targets, sseTarget{name: profile.ID, store: primaryStore(store), sse: newSSE(profile.SSE, profile.SSEKeyID)})
+	}
+	return targets
+}
+
+// providerSSE 返回环境变量中默认云存储的服务端加密设置
+func providerSSE(provider string) ServerSideEncryption {
+	switch provider {
+	case "aliyun":
+		return newSSE(config.OssSSE, config.OssSSEKeyID)
+	case "tencent":
+		return newSSE(config.CosSSE, config.CosSSEKeyID)
+	}
+	return ServerSideEncryption{}
+}
+
+// CheckServerSideEncryption 启动时自检服务端加密：未配置任何服务端加密时跳过，
+// 否则读取各存储桶的默认加密，并上传探测对象核对实际生效的加密方式，问题记录到日志
+func CheckServerSideEncryption() []SSECheck {
+	targets := sseTargets()
+	configured := false
+	for _, target := range targets {
+		configured = configured || target.sse.Mode != ""
+	}
+	if !configured {
+		return nil
+	}
+
+	checks := []SSECheck{}
+	for _, target := range targets {
+		check, err := checkSSE(target)
+		if err != nil {
+			log.Printf("服务端加密自检失败: %s, 错误: %v", target.name, err)
+			continue
+		}
+		checks = append(checks, check)
+		problems := check.Problems()
+		if len(problems) == 0 {
+			log.Printf("服务端加密自检通过: %s, 加密方式: %s", target.name, check.Applied)
+			continue
+		}
+		for _, problem := range problems {
+			log.Printf("服务端加密自检: %s, %s", target.name, problem)
+		}
+	}
+	return checks
+}
+
+// checkSSE 查询存储桶默认加密，上传并删除探测对象，返回自检结果
+func checkSSE(target sseTarget) (SSECheck, error) {
+	check := SSECheck{Store: target.name, Configured: target.sse}
+	checker, ok := target.store.(EncryptionChecker)
+	if !ok {
+		if target.sse.Mode != "" {
+			return check, fmt.Errorf("该云存储不支持按请求指定服务端加密")
+		}
+		return check, fmt.Errorf("该云存储不支持查询服务端加密")
+	}
+	var err error
+	if check.BucketDefault, err = checker.BucketEncryption(); err != nil {
+		return check, err
+	}
+	if _, err := target.store.PutObject(bytes.NewReader([]byte("sse-check")), sseProbeKey, PutOptions{}); err != nil {
+		return check, err
+	}
+	defer target.store.Delete(sseProbeKey)
+	if check.Applied, err = checker.ObjectEncryption(sseProbeKey); err != nil {
+		return check, err
+	}
+	return check, nil
+}
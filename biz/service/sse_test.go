@@ -0,0 +1,69 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_sseMode(t *testing.T) {
+	cases := map[string]string{
+		"":         "",
+		"AES256":   SSEAES256,
+		" KMS ":    SSEKMS,
+		"cos/kms":  SSEKMS,
+		"SM4":      SSESM4,
+		"aws:kms2": "aws:kms2",
+	}
+	for raw, want := range cases {
+		if got := sseMode(raw); got != want {
+			t.Errorf("sseMode(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func Test_ServerSideEncryption_headers(t *testing.T) {
+	mode, keyID := newSSE("kms", "key-1").ossHeaders()
+	if mode == nil || *mode != "KMS" || keyID == nil || *keyID != "key-1" {
+		t.Errorf("ossHeaders kms = %v, %v", mode, keyID)
+	}
+	if mode, keyID := (ServerSideEncryption{}).ossHeaders(); mode != nil || keyID != nil {
+		t.Errorf("ossHeaders empty = %v, %v", mode, keyID)
+	}
+
+	header := newSSE("kms", "key-1").cosHeader()
+	want := http.Header{}
+	want.Set("x-cos-server-side-encryption", "cos/kms")
+	want.Set("x-cos-server-side-encryption-cos-kms-key-id", "key-1")
+	if header == nil || header.Get("x-cos-server-side-encryption") != "cos/kms" || header.Get("x-cos-server-side-encryption-cos-kms-key-id") != "key-1" {
+		t.Errorf("cosHeader kms = %v, want %v", header, want)
+	}
+	if header := newSSE("aes256", "").cosHeader(); header == nil || header.Get("x-cos-server-side-encryption") != "AES256" {
+		t.Errorf("cosHeader aes256 = %v", header)
+	}
+	if header := newSSE("sm4", "").cosHeader(); header != nil {
+		t.Errorf("cosHeader sm4 = %v, want nil", header)
+	}
+}
+
+func Test_SSECheck_Problems(t *testing.T) {
+	aes := ServerSideEncryption{Mode: SSEAES256}
+	kms := ServerSideEncryption{Mode: SSEKMS, KeyID: "key-1"}
+	cases := []struct {
+		name  string
+		check SSECheck
+		want  int
+	}{
+		{"一致", SSECheck{Configured: aes, Applied: aes}, 0},
+		{"按存储桶默认", SSECheck{BucketDefault: kms, Applied: kms}, 0},
+		{"均未加密", SSECheck{}, 0},
+		{"未生效", SSECheck{Configured: aes}, 1},
+		{"密钥不符", SSECheck{Configured: kms, Applied: ServerSideEncryption{Mode: SSEKMS, KeyID: "key-2"}}, 1},
+		{"与默认加密不一致", SSECheck{Configured: aes, BucketDefault: kms, Applied: aes}, 1},
+		{"不支持的加密方式", SSECheck{Configured: ServerSideEncryption{Mode: "sse-c"}}, 2},
+	}
+	for _, c := range cases {
+		if got := c.check.Problems(); len(got) != c.want {
+			t.Errorf("%s: Problems() = %v, want %d", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qiniu/go-sdk/v7/storage"
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// fakeBucket 本地模拟的存储桶，按键排序列出对象
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeBucket() *fakeBucket {
+	return &fakeBucket{objects: map[string][]byte{}}
+}
+
+func (b *fakeBucket) put(key string, data []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.objects[key] = data
+}
+
+func (b *fakeBucket) get(key string) ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[key]
+	return data, ok
+}
+
+func (b *fakeBucket) remove(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.objects[key]
+	delete(b.objects, key)
+	return ok
+}
+
+// list 返回 prefix 下键大于 marker 的前 limit 个对象，以及是否还有更多
+func (b *fakeBucket) list(prefix, marker string, limit int) ([]string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keys := []string{}
+	for key := range b.objects {
+		if strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		return keys[:limit], true
+	}
+	return keys, false
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+type s3Contents struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified string
+	StorageClass string
+}
+
+type s3ListResult struct {
+	XMLName               xml.Name `xml:"ListBucketResult"`
+	IsTruncated           bool
+	NextMarker            string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	Contents              []s3Contents
+}
+
+// newS3Fake 模拟 OSS 和 COS 的对象接口：pathPrefix 为对象路径前的存储桶路径，crcHeader 为返回 CRC64 的响应头，
+// storageClass 为列出对象时返回的存储类型
+func newS3Fake(bucket *fakeBucket, pathPrefix string, crcHeader string, storageClass string) *httptest.Server {
+	writeError := func(w http.ResponseWriter, r *http.Request, status int, code string) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(status)
+		if r.Method != http.MethodHead {
+			fmt.Fprintf(w, "<Error><Code>%s</Code><Message>%s</Message><RequestId>fake</RequestId></Error>", code, code)
+		}
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, pathPrefix), "/")
+		if key == "" && r.Method == http.MethodGet {
+			q := r.URL.Query()
+			marker := q.Get("marker")
+			if q.Get("list-type") == "2" {
+				marker = q.Get("continuation-token")
+			}
+			limit, _ := strconv.Atoi(q.Get("max-keys"))
+			keys, truncated := bucket.list(q.Get("prefix"), marker, limit)
+			result := s3ListResult{IsTruncated: truncated}
+			for _, k := range keys {
+				data, _ := bucket.get(k)
+				result.Contents = append(result.Contents, s3Contents{
+					Key:          k,
+					Size:         int64(len(data)),
+					ETag:         `"` + md5Hex(data) + `"`,
+					LastModified: time.Now().UTC().Format("2006-01-02T15:04:05.000Z"),
+					StorageClass: storageClass,
+				})
+			}
+			if truncated && q.Get("list-type") == "2" {
+				result.NextContinuationToken = keys[len(keys)-1]
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			xml.NewEncoder(w).Encode(result)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			sum := md5.Sum(data)
+			if digest := r.Header.Get("Content-MD5"); digest != "" && digest != base64.StdEncoding.EncodeToString(sum[:]) {
+				writeError(w, r, http.StatusBadRequest, "InvalidDigest")
+				return
+			}
+			bucket.put(key, data)
+			w.Header().Set("ETag", `"`+md5Hex(data)+`"`)
+			w.Header().Set(crcHeader, strconv.FormatUint(crc64.Checksum(data, crc64.MakeTable(crc64.ECMA)), 10))
+		case http.MethodGet, http.MethodHead:
+			data, ok := bucket.get(key)
+			if !ok {
+				writeError(w, r, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			w.Header().Set("ETag", `"`+md5Hex(data)+`"`)
+			http.ServeContent(w, r, key, time.Now(), bytes.NewReader(data))
+		case http.MethodDelete:
+			bucket.remove(key)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, r, http.StatusMethodNotAllowed, "MethodNotAllowed")
+		}
+	}))
+}
+
+// qetag 计算七牛的文件哈希（不超过 4MB 的文件）
+func qetag(data []byte) string {
+	sum := sha1.Sum(data)
+	return base64.URLEncoding.EncodeToString(append([]byte{0x16}, sum[:]...))
+}
+
+// newQiniuFake 模拟七牛的区域查询、表单上传、资源管理、列举和下载接口
+func newQiniuFake(bucket *fakeBucket, bucketName string) *httptest.Server {
+	var server *httptest.Server
+	writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	// entryKey 解析 EncodedEntry 中的对象路径
+	entryKey := func(encoded string) string {
+		entry, _ := base64.URLEncoding.DecodeString(encoded)
+		return strings.TrimPrefix(string(entry), bucketName+":")
+	}
+	noSuchFile := map[string]string{"error": "no such file or directory"}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.TrimPrefix(server.URL, "http://")
+		w.Header().Set("X-Reqid", "fake") // SDK 以此判断响应来自七牛
+		switch {
+		case r.URL.Path == "/v4/query":
+			hosts := map[string][]string{"domains": {host}}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"hosts": []map[string]interface{}{{
+				"region": "fake", "ttl": 86400,
+				"io": hosts, "io_src": hosts, "up": hosts, "rs": hosts, "rsf": hosts, "api": hosts, "uc": hosts,
+			}}})
+		case r.URL.Path == "/" && r.Method == http.MethodPost:
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			data, _ := io.ReadAll(file)
+			key := r.FormValue("key")
+			bucket.put(key, data)
+			writeJSON(w, http.StatusOK, map[string]string{"key": key, "hash": qetag(data)})
+		case strings.HasPrefix(r.URL.Path, "/stat/"):
+			data, ok := bucket.get(entryKey(strings.TrimPrefix(r.URL.Path, "/stat/")))
+			if !ok {
+				writeJSON(w, qiniuNoSuchFile, noSuchFile)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"fsize": len(data), "hash": qetag(data), "putTime": time.Now().UnixNano() / 100})
+		case strings.HasPrefix(r.URL.Path, "/delete/"):
+			if !bucket.remove(entryKey(strings.TrimPrefix(r.URL.Path, "/delete/"))) {
+				writeJSON(w, qiniuNoSuchFile, noSuchFile)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{})
+		case r.URL.Path == "/list":
+			q := r.URL.Query()
+			limit, _ := strconv.Atoi(q.Get("limit"))
+			keys, more := bucket.list(q.Get("prefix"), q.Get("marker"), limit)
+			items := []map[string]interface{}{}
+			for _, k := range keys {
+				data, _ := bucket.get(k)
+				items = append(items, map[string]interface{}{"key": k, "fsize": len(data), "hash": qetag(data), "putTime": time.Now().UnixNano() / 100, "type": 1})
+			}
+			result := map[string]interface{}{"items": items}
+			if more {
+				result["marker"] = keys[len(keys)-1]
+			}
+			writeJSON(w, http.StatusOK, result)
+		case r.Method == http.MethodGet:
+			data, ok := bucket.get(strings.TrimPrefix(r.URL.Path, "/"))
+			if !ok {
+				writeJSON(w, http.StatusNotFound, noSuchFile)
+				return
+			}
+			http.ServeContent(w, r, r.URL.Path, time.Now(), bytes.NewReader(data))
+		default:
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown api " + r.URL.Path})
+		}
+	}))
+	return server
+}
+
+// contractStore 各云存储共同实现的对象读写和列举接口
+type contractStore interface {
+	ObjectStore
+	ObjectLister
+	ObjectRangeReader
+}
+
+// testStoreContract 校验云存储实现 ObjectStore 和 ObjectLister 约定的语义，列出的对象均为 storageClass 类型
+func testStoreContract(t *testing.T, store contractStore, verifiesMD5 bool, storageClass string) {
+	data := []byte("hello, 云盘")
+	sum := md5.Sum(data)
+
+	size, err := store.PutObject(bytes.NewReader(data), "docs/a.txt", PutOptions{ContentMD5: base64.StdEncoding.EncodeToString(sum[:])})
+	if err != nil || size != int64(len(data)) {
+		t.Fatalf("PutObject = %d, %v, want %d", size, err, len(data))
+	}
+	body, err := store.GetObject("docs/a.txt")
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	got, _ := io.ReadAll(body)
+	body.Close()
+	if !bytes.Equal(got, data) {
+		t.Errorf("GetObject = %q, want %q", got, data)
+	}
+
+	for _, r := range [][2]int64{{2, 3}, {7, -1}} {
+		want := data[r[0]:]
+		if r[1] >= 0 {
+			want = want[:r[1]]
+		}
+		body, err := store.GetObjectRange("docs/a.txt", r[0], r[1])
+		if err != nil {
+			t.Fatalf("GetObjectRange(%d, %d): %v", r[0], r[1], err)
+		}
+		got, _ := io.ReadAll(body)
+		body.Close()
+		if !bytes.Equal(got, want) {
+			t.Errorf("GetObjectRange(%d, %d) = %q, want %q", r[0], r[1], got, want)
+		}
+	}
+	if _, err := store.GetObjectRange("docs/missing.txt", 0, 1); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("GetObjectRange missing = %v, want ErrObjectNotFound", err)
+	}
+
+	// 覆盖写入
+	if size, err := store.PutObject(strings.NewReader("v2"), "docs/a.txt", PutOptions{}); err != nil || size != 2 {
+		t.Errorf("PutObject overwrite = %d, %v", size, err)
+	}
+
+	if verifiesMD5 {
+		wrong := md5.Sum([]byte("other"))
+		if _, err := store.PutObject(bytes.NewReader(data), "docs/bad.txt", PutOptions{ContentMD5: base64.StdEncoding.EncodeToString(wrong[:])}); err == nil {
+			t.Error("PutObject with wrong Content-MD5 should fail")
+		}
+	}
+
+	if _, err := store.GetObject("docs/missing.txt"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("GetObject missing = %v, want ErrObjectNotFound", err)
+	}
+
+	for _, key := range []string{"docs/b.txt", "docs/c.txt", "other/d.txt"} {
+		if _, err := store.PutObject(strings.NewReader(key), key, PutOptions{}); err != nil {
+			t.Fatalf("PutObject %s: %v", key, err)
+		}
+	}
+	keys := []string{}
+	marker := ""
+	for page := 0; ; page++ {
+		objects, next, err := store.List("docs/", marker, 2)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(objects) > 2 {
+			t.Errorf("List returned %d objects, limit 2", len(objects))
+		}
+		for _, obj := range objects {
+			keys = append(keys, obj.Key)
+			if obj.Size == 0 || obj.ETag == "" || strings.Contains(obj.ETag, `"`) || obj.StorageClass != storageClass {
+				t.Errorf("List object %+v", obj)
+			}
+		}
+		if next == "" {
+			break
+		}
+		if page > 3 {
+			t.Fatal("List does not terminate")
+		}
+		marker = next
+	}
+	if strings.Join(keys, ",") != "docs/a.txt,docs/b.txt,docs/c.txt" {
+		t.Errorf("List keys = %v", keys)
+	}
+
+	if err := store.Delete("docs/a.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.GetObject("docs/a.txt"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("GetObject deleted = %v, want ErrObjectNotFound", err)
+	}
+	if err := store.Delete("docs/a.txt"); err != nil {
+		t.Errorf("Delete missing = %v, want nil", err)
+	}
+}
+
+func Test_StoreContract_aliyun(t *testing.T) {
+	bucket := newFakeBucket()
+	server := newS3Fake(bucket, "/bucket", "x-oss-hash-crc64ecma", "Standard")
+	defer server.Close()
+	testStoreContract(t, &OssUploader{
+		Region:          "cn-hangzhou",
+		Bucket:          "bucket",
+		Endpoint:        server.URL,
+		AccessKeyId:     "ak",
+		AccessKeySecret: "sk",
+	}, true, StorageStandard)
+}
+
+func Test_StoreContract_tencent(t *testing.T) {
+	bucket := newFakeBucket()
+	server := newS3Fake(bucket, "", "x-cos-hash-crc64ecma", "STANDARD_IA")
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	testStoreContract(t, &CosUploader{client: cos.NewClient(&cos.BaseURL{BucketURL: u}, &http.Client{
+		Transport: &cos.AuthorizationTransport{SecretID: "ak", SecretKey: "sk"},
+	})}, true, StorageIA)
+}
+
+func Test_StoreContract_qiniu(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir()) // 区域查询结果的缓存文件
+	bucket := newFakeBucket()
+	server := newQiniuFake(bucket, "bucket")
+	defer server.Close()
+	storage.SetUcHosts(server.URL) // 资源管理接口按区域查询结果选择域名，不使用 Zone 中的配置
+	host := server.URL
+	testStoreContract(t, &QiniuCommoner{
+		accessKey:  "ak",
+		secretKey:  "sk",
+		bucketName: "bucket",
+		endpoint:   server.URL,
+		config: &storage.Config{
+			Zone: &storage.Region{SrcUpHosts: []string{host}, RsHost: host, RsfHost: host, ApiHost: host, IovipHost: host},
+		},
+	}, false, StorageIA)
+}
+
+func Test_storageClassName(t *testing.T) {
+	cases := map[string]string{
+		"":                    StorageStandard,
+		"Standard":            StorageStandard,
+		"STANDARD_IA":         StorageIA,
+		"MAZ_STANDARD_IA":     StorageIA,
+		"Archive":             StorageArchive,
+		"ColdArchive":         StorageColdArchive,
+		"DEEP_ARCHIVE":        StorageDeepArchive,
+		"DeepColdArchive":     StorageDeepArchive,
+		"INTELLIGENT_TIERING": "intelligent_tiering",
+	}
+	for raw, want := range cases {
+		if got := storageClassName(raw); got != want {
+			t.Errorf("storageClassName(%q) = %s, want %s", raw, got, want)
+		}
+	}
+	if got := qiniuStorageClass(3); got != StorageDeepArchive {
+		t.Errorf("qiniuStorageClass(3) = %s", got)
+	}
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// StorageProfile 存储配置：云存储类型、存储桶、地域和访问密钥
+type StorageProfile struct {
+	ID              string `yaml:"id"`
+	Provider        string `yaml:"provider"` // aliyun、tencent、qiniu
+	Bucket          string `yaml:"bucket"`
+	Region          string `yaml:"region"`   // 七牛为存储区域，如 z0
+	Endpoint        string `yaml:"endpoint"` // OSS 的 Endpoint 或七牛的下载域名
+	AccessKeyID     string `yaml:"access_key_id"`
+	AccessKeySecret string `yaml:"access_key_secret"`
+	Encrypt         bool   `yaml:"encrypt"`    // 写入该存储的新内容在上传前加密
+	SSE             string `yaml:"sse"`        // 服务端加密：aes256、kms，OSS 另支持 sm4，七牛不支持
+	SSEKeyID        string `yaml:"sse_key_id"` // SSE 为 kms 时使用的 KMS 密钥ID
+}
+
+// StorageRule 存储路由规则，同一规则中的条件需全部满足，未设置的条件不限制
+type StorageRule struct {
+	Profile string   `yaml:"profile"` // 匹配时使用的存储配置ID
+	Pshare  []int64  `yaml:"pshare"`  // 所属共享文件夹ID
+	Userid  []int64  `yaml:"userid"`  // 拥有者ID
+	Types   []string `yaml:"types"`   // getFileType 返回的文件类型
+}
+
+// StorageRouting 存储配置和路由规则，规则按顺序匹配
+type StorageRouting struct {
+	Profiles []StorageProfile `yaml:"profiles"`
+	Rules    []StorageRule    `yaml:"rules"`
+	Default  string           `yaml:"default"` // 没有规则匹配时使用的存储配置，为空时使用 CLOUD_PROVIDER 对应的默认存储
+}
+
+var (
+	storageRouting     *StorageRouting
+	storageRoutingOnce sync.Once
+	profileStores      = map[string]ObjectStore{}
+	profileStoresMu    sync.Mutex
+)
+
+// getStorageRouting 读取 STORAGE_PROFILES_FILE，引用不存在的存储配置的规则被忽略
+func getStorageRouting() *StorageRouting {
+	storageRoutingOnce.Do(func() {
+		storageRouting = &StorageRouting{}
+		if config.StorageProfilesFile == "" {
+			return
+		}
+		data, err := os.ReadFile(config.StorageProfilesFile)
+		if err != nil {
+			log.Printf("读取存储配置文件失败: %s, 错误: %v", config.StorageProfilesFile, err)
+			return
+		}
+		var routing StorageRouting
+		if err := yaml.Unmarshal(data, &routing); err != nil {
+			log.Printf("解析存储配置文件失败: %s, 错误: %v", config.StorageProfilesFile, err)
+			return
+		}
+		routing.normalize()
+		storageRouting = &routing
+	})
+	return storageRouting
+}
+
+// normalize 统一文件类型的大小写，去掉引用不存在的存储配置的规则
+func (r *StorageRouting) normalize() {
+	rules := r.Rules[:0]
+	for _, rule := range r.Rules {
+		if r.profile(rule.Profile) == nil {
+			log.Printf("存储路由规则引用的存储配置不存在: %s", rule.Profile)
+			continue
+		}
+		for i, t := range rule.Types {
+			rule.Types[i] = strings.ToLower(strings.TrimSpace(t))
+		}
+		rules = append(rules, rule)
+	}
+	r.Rules = rules
+	if r.Default != "" && r.profile(r.Default) == nil {
+		log.Printf("默认存储配置不存在: %s", r.Default)
+		r.Default = ""
+	}
+}
+
+// profile 按 ID 查找存储配置
+func (r *StorageRouting) profile(id string) *StorageProfile {
+	for i := range r.Profiles {
+		if r.Profiles[i].ID == id {
+			return &r.Profiles[i]
+		}
+	}
+	return nil
+}
+
+// route 返回第一个匹配的规则对应的存储配置ID，没有匹配时返回默认存储配置
+func (r *StorageRouting) route(userid int64, pshare int64, fileType string) string {
+	for _, rule := range r.Rules {
+		if len(rule.Pshare) > 0 && !IsContainInt(rule.Pshare, pshare) {
+			continue
+		}
+		if len(rule.Userid) > 0 && !IsContainInt(rule.Userid, userid) {
+			continue
+		}
+		if len(rule.Types) > 0 && !isContain(rule.Types, strings.ToLower(fileType)) {
+			continue
+		}
+		return rule.Profile
+	}
+	return r.Default
+}
+
+// routeProfile 为拥有者 userid 在 pid 文件夹中的文件 filename 选择存储配置，没有匹配时返回默认云存储的名称
+func routeProfile(userid int64, pid int64, filename string) string {
+	routing := getStorageRouting()
+	if len(routing.Rules) == 0 && routing.Default == "" {
+		return defaultProvider()
+	}
+	return routeOr(routing.route(userid, shareRoot(pid), getFileType(filename)))
+}
+
+// routeFile 为已有文件的新内容选择存储配置
+func routeFile(file *gorm_gen.File) string {
+	routing := getStorageRouting()
+	if len(routing.Rules) == 0 && routing.Default == "" {
+		return defaultProvider()
+	}
+	return routeOr(routing.route(file.Userid, shareRoot(file.Pid), file.Type))
+}
+
+// routeOr 未匹配到存储配置时使用默认云存储，内容记录其名称，修改 CLOUD_PROVIDER 后仍从原云存储读取
+func routeOr(profile string) string {
+	if profile == "" {
+		return defaultProvider()
+	}
+	return profile
+}
+
+// profileStore 返回内容所在的云存储：先按存储配置ID查找，其次按云存储名称，空字符串为此前写入阿里云OSS的内容
+func profileStore(id string) (ObjectStore, error) {
+	if id == "" {
+		id = legacyProvider
+	}
+	if id == defaultProvider() && getStorageRouting().profile(id) == nil {
+		return getCloudUploader(), nil
+	}
+	profileStoresMu.Lock()
+	defer profileStoresMu.Unlock()
+	if store, ok := profileStores[id]; ok {
+		return store, nil
+	}
+	profile := getStorageRouting().profile(id)
+	if profile == nil {
+		if _, err := getObjectStore(id); err != nil {
+			return nil, fmt.Errorf("存储配置不存在: %s", id)
+		}
+		// 非默认云存储中的内容只读取和删除，不登记复制任务
+		store := &replicaFailover{CloudUploader: providerUploader(id)}
+		profileStores[id] = store
+		return store, nil
+	}
+	store, err := newProfileStore(profile)
+	if err != nil {
+		return nil, err
+	}
+	profileStores[id] = store
+	return store, nil
+}
+
+// newProfileStore 按存储配置创建云存储客户端
+func newProfileStore(p *StorageProfile) (ObjectStore, error) {
+	switch p.Provider {
+	case "aliyun":
+		return &OssUploader{
+			Region:          p.Region,
+			Bucket:          p.Bucket,
+			Endpoint:        p.Endpoint,
+			AccessKeyId:     p.AccessKeyID,
+			AccessKeySecret: p.AccessKeySecret,
+			SSE:             newSSE(p.SSE, p.SSEKeyID),
+		}, nil
+	case "tencent":
+		return &CosUploader{
+			client: newCosClient(p.Bucket, p.Region, p.AccessKeyID, p.AccessKeySecret),
+			sse:    newSSE(p.SSE, p.SSEKeyID),
+		}, nil
+	case "qiniu":
+		return &QiniuCommoner{
+			accessKey:  p.AccessKeyID,
+			secretKey:  p.AccessKeySecret,
+			bucketName: p.Bucket,
+			endpoint:   p.Endpoint,
+			zone:       p.Region,
+		}, nil
+	}
+	return nil, fmt.Errorf("存储配置 %s 的云存储类型不支持: %s", p.ID, p.Provider)
+}
+
+// ListObjects 管理员分页浏览存储配置或云存储中 prefix 下的对象，profile 为空时为默认云存储
+func ListObjects(user *User, profile string, prefix string, marker string, limit int) ([]ObjectInfo, string, error) {
+	if !isContain(user.Identity, "admin") {
+		return nil, "", errors.New("仅限管理员操作")
+	}
+	if profile == "" {
+		profile = defaultProvider()
+	}
+	store, err := profileStore(profile)
+	if err != nil {
+		return nil, "", err
+	}
+	lister, ok := store.(ObjectLister)
+	if !ok {
+		return nil, "", fmt.Errorf("存储配置 %s 不支持列出对象", profile)
+	}
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	return lister.List(prefix, marker, limit)
+}
+
+// openObject 读取存储配置 profile 中的对象，调用方负责关闭
+func openObject(profile string, key string) (io.ReadCloser, error) {
+	store, err := profileStore(profile)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetObject(key)
+}
+
+// ReadContent 读取文件内容记录对应的全部内容，见 ReadContentRange
+func ReadContent(meta ContentMeta) ([]byte, error) {
+	return ReadContentRange(meta, 0, -1)
+}
+
+// ReadContentRange 读取内容中从 offset 开始的 length 字节（小于 0 时读到末尾），加密内容解密、压缩内容解压后返回，并记录下载时间；
+// 优先读取本地缓存，未缓存时先填充下载缓存；对象已归档时发起恢复并返回 ErrObjectRestoring
+func ReadContentRange(meta ContentMeta, offset int64, length int64) ([]byte, error) {
+	if data, ok := readCachedRange(meta, offset, length); ok {
+		recordAccess(meta)
+		return data, nil
+	}
+	if err := fillCache(meta); err == nil {
+		if data, ok := readCachedRange(meta, offset, length); ok {
+			return data, nil
+		}
+	} else if errors.Is(err, ErrObjectRestoring) {
+		return nil, err
+	} else if !errors.Is(err, errNotCacheable) {
+		log.Printf("填充本地缓存失败: %s, 错误: %v", meta.Key, err)
+	}
+	return readContent(meta, func() (io.ReadCloser, error) {
+		return openContentRange(meta, offset, length)
+	})
+}
+
+// ReadEncodedContent 读取压缩内容解压前的数据，用于客户端接受该压缩方式时原样返回，加密内容解密后返回
+func ReadEncodedContent(meta ContentMeta) ([]byte, error) {
+	return readContent(meta, func() (io.ReadCloser, error) {
+		return openPayload(meta)
+	})
+}
+
+// readContent 通过 open 读取全部内容，见 openRestored
+func readContent(meta ContentMeta, open func() (io.ReadCloser, error)) ([]byte, error) {
+	body, err := openRestored(meta, open)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// openRestored 通过 open 读取内容并记录下载时间，对象已归档时发起恢复，恢复完成后重新读取
+func openRestored(meta ContentMeta, open func() (io.ReadCloser, error)) (io.ReadCloser, error) {
+	if err := checkRestore(meta); err != nil {
+		return nil, err
+	}
+	body, err := open()
+	if errors.Is(err, ErrObjectArchived) {
+		// 未记录存储类型的归档对象，例如由存储桶的生命周期规则转换
+		if err := requestRestore(meta, StorageArchive); err != nil {
+			return nil, err
+		}
+		body, err = open()
+	}
+	if err != nil {
+		return nil, err
+	}
+	recordAccess(meta)
+	return body, nil
+}
+
+// ErrRangeNotSatisfiable 请求的范围超出内容大小
+var ErrRangeNotSatisfiable = errors.New("请求的范围无效")
+
+// ParseByteRange 解析 Range 请求头中的单个字节范围，返回 offset 和 length；
+// 没有 Range、不是 bytes 单位或包含多个范围时返回 length 为 -1，表示读取全部内容
+func ParseByteRange(header string, size int64) (int64, int64, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") || size <= 0 {
+		return 0, -1, nil
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, -1, nil
+	}
+	if first == "" {
+		// bytes=-n 表示最后 n 字节
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, ErrRangeNotSatisfiable
+		}
+		n = min(n, size)
+		return size - n, n, nil
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, ErrRangeNotSatisfiable
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, 0, ErrRangeNotSatisfiable
+		}
+		end = min(end, size-1)
+	}
+	return start, end - start + 1, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import "testing"
+
+func Test_StorageRouting_route(t *testing.T) {
+	routing := &StorageRouting{
+		Profiles: []StorageProfile{{ID: "eu"}, {ID: "media"}, {ID: "cn"}},
+		Rules: []StorageRule{
+			{Profile: "eu", Pshare: []int64{12}},
+			{Profile: "media", Userid: []int64{3}, Types: []string{"Video", " image"}},
+			{Profile: "missing", Userid: []int64{4}},
+		},
+		Default: "cn",
+	}
+	routing.normalize()
+	if len(routing.Rules) != 2 {
+		t.Fatalf("normalize 后规则数 = %d, want 2", len(routing.Rules))
+	}
+
+	cases := []struct {
+		userid   int64
+		pshare   int64
+		fileType string
+		want     string
+	}{
+		{1, 12, "document", "eu"},
+		// 先匹配的规则优先
+		{3, 12, "video", "eu"},
+		{3, 0, "VIDEO", "media"},
+		{3, 0, "image", "media"},
+		// 同一规则的条件需全部满足
+		{3, 0, "document", "cn"},
+		{1, 0, "video", "cn"},
+		// 引用不存在的存储配置的规则已被忽略
+		{4, 0, "document", "cn"},
+	}
+	for _, c := range cases {
+		if got := routing.route(c.userid, c.pshare, c.fileType); got != c.want {
+			t.Errorf("route(%d, %d, %s) = %s, want %s", c.userid, c.pshare, c.fileType, got, c.want)
+		}
+	}
+
+	empty := &StorageRouting{Default: "missing"}
+	empty.normalize()
+	if got := empty.route(1, 0, "document"); got != "" {
+		t.Errorf("默认存储配置不存在时 route = %s, want 空", got)
+	}
+}
+
+func Test_contentProvider(t *testing.T) {
+	cases := map[string]ContentMeta{
+		legacyProvider: {},
+		"tencent":      {Profile: "tencent"},
+		"eu":           {Profile: "eu"},
+	}
+	for want, meta := range cases {
+		if got := contentProvider(meta); got != want {
+			t.Errorf("contentProvider(%+v) = %s, want %s", meta, got, want)
+		}
+	}
+}
@@ -8,12 +8,16 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/cloudisk/pkg/config"
 	"github.com/tencentyun/cos-go-sdk-v5"
 )
 
 type CosUploader struct {
 	client *cos.Client
+	sse    ServerSideEncryption // 上传和复制时指定的服务端加密
 }
 
 type CosDownloader struct {
@@ -30,11 +34,13 @@ type CosLister struct {
 
 type CosCopier struct {
 	client *cos.Client
+	sse    ServerSideEncryption
 }
 
 func NewCosUploader() *CosUploader {
 	return &CosUploader{
 		client: NewCosClient(),
+		sse:    newSSE(config.CosSSE, config.CosSSEKeyID),
 	}
 }
 
@@ -59,34 +65,42 @@ func NewCosLister() *CosLister {
 func NewCosCopier() *CosCopier {
 	return &CosCopier{
 		client: NewCosClient(),
+		sse:    newSSE(config.CosSSE, config.CosSSEKeyID),
 	}
 }
 
 func NewCosClient() *cos.Client {
 	// 创建一个通用的 COS 客户端
-	u, _ := url.Parse(fmt.Sprintf("https://%s.cos.%s.myqcloud.com", os.Getenv("COS_BUCKET"), os.Getenv("COS_REGION")))
+	return newCosClient(os.Getenv("COS_BUCKET"), os.Getenv("COS_REGION"), os.Getenv("SECRETID"), os.Getenv("SECRETKEY"))
+}
+
+// newCosClient 按指定的存储桶、地域和密钥创建 COS 客户端
+func newCosClient(bucket, region, secretID, secretKey string) *cos.Client {
+	u, _ := url.Parse(fmt.Sprintf("https://%s.cos.%s.myqcloud.com", bucket, region))
 	b := &cos.BaseURL{BucketURL: u}
 
 	client := cos.NewClient(b, &http.Client{
 		Transport: &cos.AuthorizationTransport{
-			SecretID:  os.Getenv("SECRETID"),
-			SecretKey: os.Getenv("SECRETKEY"),
+			SecretID:  secretID,
+			SecretKey: secretKey,
 		},
 	})
 
 	return client
 }
 
-// Upload 上传文件到腾讯云 COS
-func (u *CosUploader) Upload(fileData multipart.File, objectName string) (int64, error) {
+// Upload 上传文件到腾讯云 COS，对象路径与阿里云一致按文件夹层级拼接
+func (u *CosUploader) Upload(fileData multipart.File, objectName string, pid int64) (int64, error) {
+	fullPath := uploadPath(objectName, pid)
+
 	// 上传文件流
-	_, err := u.client.Object.Put(context.Background(), objectName, fileData, nil)
+	_, err := u.client.Object.Put(context.Background(), fullPath, fileData, u.putOptions(""))
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("failed to upload object: %v", err)
 	}
 
 	// 获取文件信息
-	objInfo, err := u.client.Object.Head(context.Background(), objectName, nil)
+	objInfo, err := u.client.Object.Head(context.Background(), fullPath, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to retrieve object info: %v", err)
 	}
@@ -98,9 +112,9 @@ func (u *CosUploader) Upload(fileData multipart.File, objectName string) (int64,
 // ReaderUpload 使用io.ReadCloser上传文件到腾讯云COS
 func (u *CosUploader) ReaderUpload(file io.ReadCloser, objectName string) (int64, error) {
 	// 上传文件流
-	_, err := u.client.Object.Put(context.Background(), objectName, file, nil)
+	_, err := u.client.Object.Put(context.Background(), objectName, file, u.putOptions(""))
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("failed to upload object: %v", err)
 	}
 
 	// 获取文件信息
@@ -112,6 +126,140 @@ func (u *CosUploader) ReaderUpload(file io.ReadCloser, objectName string) (int64
 	return objInfo.Response.ContentLength, nil
 }
 
+// PutObject 上传对象，Content-MD5 由 COS 校验，CRC64 与 COS 返回值比对
+func (u *CosUploader) PutObject(file io.Reader, objectName string, opts PutOptions) (int64, error) {
+	resp, err := u.client.Object.Put(context.Background(), objectName, file, u.putOptions(opts.ContentMD5))
+	if err != nil {
+		return 0, fmt.Errorf("failed to upload object: %v", err)
+	}
+	if crc := resp.Header.Get("x-cos-hash-crc64ecma"); opts.CRC64 != "" && crc != "" && crc != opts.CRC64 {
+		return 0, fmt.Errorf("crc64 mismatch: local %s, cos %s", opts.CRC64, crc)
+	}
+
+	objInfo, err := u.client.Object.Head(context.Background(), objectName, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve object info: %v", err)
+	}
+	return objInfo.Response.ContentLength, nil
+}
+
+// putOptions 返回上传请求的校验值和服务端加密请求头，都未设置时为 nil
+func (u *CosUploader) putOptions(contentMD5 string) *cos.ObjectPutOptions {
+	header := u.sse.cosHeader()
+	if contentMD5 == "" && header == nil {
+		return nil
+	}
+	return &cos.ObjectPutOptions{
+		ObjectPutHeaderOptions: &cos.ObjectPutHeaderOptions{ContentMD5: contentMD5, XOptionHeader: header},
+	}
+}
+
+// GetObject 以流的方式读取对象内容，调用方负责关闭
+func (u *CosUploader) GetObject(objectName string) (io.ReadCloser, error) {
+	return u.getObject(objectName, nil)
+}
+
+// GetObjectRange 以流的方式读取对象的一段内容，调用方负责关闭
+func (u *CosUploader) GetObjectRange(objectName string, offset int64, length int64) (io.ReadCloser, error) {
+	return u.getObject(objectName, &cos.ObjectGetOptions{Range: httpRange(offset, length)})
+}
+
+func (u *CosUploader) getObject(objectName string, opt *cos.ObjectGetOptions) (io.ReadCloser, error) {
+	resp, err := u.client.Object.Get(context.Background(), objectName, opt)
+	if err != nil {
+		if cos.IsNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, objectName)
+		}
+		if cerr, ok := cos.IsCOSError(err); ok && cerr.Code == "InvalidObjectState" {
+			return nil, fmt.Errorf("%w: %s", ErrObjectArchived, objectName)
+		}
+		return nil, fmt.Errorf("failed to download object: %v", err)
+	}
+	return resp.Body, nil
+}
+
+// cosStorageClasses 统一的存储类型名称与 COS 存储类型的对应关系，COS 没有冷归档
+var cosStorageClasses = map[string]string{
+	StorageStandard:    "STANDARD",
+	StorageIA:          "STANDARD_IA",
+	StorageArchive:     "ARCHIVE",
+	StorageDeepArchive: "DEEP_ARCHIVE",
+}
+
+// SetStorageClass 通过复制到自身转换对象的存储类型
+func (u *CosUploader) SetStorageClass(objectName string, class string) error {
+	storageClass, ok := cosStorageClasses[class]
+	if !ok {
+		return fmt.Errorf("COS 不支持的存储类型: %s", class)
+	}
+	source := fmt.Sprintf("%s/%s", u.client.BaseURL.BucketURL.Host, objectName)
+	_, _, err := u.client.Object.Copy(context.Background(), objectName, source, &cos.ObjectCopyOptions{
+		ObjectCopyHeaderOptions: &cos.ObjectCopyHeaderOptions{
+			XCosMetadataDirective: "Copy",
+			XCosStorageClass:      storageClass,
+			XOptionHeader:         u.sse.cosHeader(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change storage class: %v", err)
+	}
+	return nil
+}
+
+// Restore 发起归档对象的恢复
+func (u *CosUploader) Restore(objectName string, days int) error {
+	_, err := u.client.Object.PostRestore(context.Background(), objectName, &cos.ObjectRestoreOptions{
+		Days: days,
+		Tier: &cos.CASJobParameters{Tier: "Standard"},
+	})
+	if cerr, ok := cos.IsCOSError(err); err != nil && !(ok && cerr.Code == "RestoreAlreadyInProgress") {
+		return fmt.Errorf("failed to restore object: %v", err)
+	}
+	return nil
+}
+
+// Restored 按 x-cos-restore 响应头判断恢复是否完成
+func (u *CosUploader) Restored(objectName string) (bool, error) {
+	resp, err := u.client.Object.Head(context.Background(), objectName, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve object info: %v", err)
+	}
+	return restoreDone(resp.Header.Get("x-cos-restore")), nil
+}
+
+// BucketEncryption 返回存储桶的默认加密，未配置时 Mode 为空
+func (u *CosUploader) BucketEncryption() (ServerSideEncryption, error) {
+	result, _, err := u.client.Bucket.GetEncryption(context.Background())
+	if cos.IsNotFoundError(err) {
+		return ServerSideEncryption{}, nil
+	}
+	if err != nil {
+		return ServerSideEncryption{}, fmt.Errorf("failed to get bucket encryption: %v", err)
+	}
+	if result.Rule == nil {
+		return ServerSideEncryption{}, nil
+	}
+	return newSSE(result.Rule.SSEAlgorithm, result.Rule.KMSMasterKeyID), nil
+}
+
+// ObjectEncryption 按 x-cos-server-side-encryption 响应头返回对象的加密方式
+func (u *CosUploader) ObjectEncryption(objectName string) (ServerSideEncryption, error) {
+	resp, err := u.client.Object.Head(context.Background(), objectName, nil)
+	if err != nil {
+		return ServerSideEncryption{}, fmt.Errorf("failed to retrieve object info: %v", err)
+	}
+	return newSSE(resp.Header.Get("x-cos-server-side-encryption"), resp.Header.Get("x-cos-server-side-encryption-cos-kms-key-id")), nil
+}
+
+// Delete 删除对象，对象不存在时不报错
+func (u *CosUploader) Delete(objectName string) error {
+	_, err := u.client.Object.Delete(context.Background(), objectName)
+	if err != nil && !cos.IsNotFoundError(err) {
+		return fmt.Errorf("failed to delete object: %v", err)
+	}
+	return nil
+}
+
 // Download 从 COS 下载文件
 func (d *CosDownloader) DownloadFile(objectName string) ([]byte, error) {
 	resp, err := d.client.Object.Get(context.Background(), objectName, nil)
@@ -191,53 +339,56 @@ func (d *CosDeleter) Delete(objectName string) error {
 	return nil
 }
 
-// List 获取 COS 文件列表，格式化输出文件信息
-/*
-	func (l *CosLister) List(prefix, marker string, maxKeys int) ([]model.FileInfo, string, error) {
-		if prefix == "" {
-			prefix = "" // 默认 Prefix 为 *，返回所有对象
-		}
-		if maxKeys == 0 {
-			maxKeys = 1000 // 默认 MaxKeys 为 1000
-		}
+// List 获取 COS 文件列表，marker 为上一页返回的起点，没有下一页时返回空字符串
+func (l *CosLister) List(prefix, marker string, maxKeys int) ([]ObjectInfo, string, error) {
+	if maxKeys == 0 {
+		maxKeys = 1000 // 默认 MaxKeys 为 1000
+	}
 
-		opt := &cos.BucketGetOptions{
-			Prefix:  prefix,
-			Marker:  marker,
-			MaxKeys: maxKeys,
-		}
+	opt := &cos.BucketGetOptions{
+		Prefix:  prefix,
+		Marker:  marker,
+		MaxKeys: maxKeys,
+	}
 
-		v, _, err := l.client.Bucket.Get(context.Background(), opt)
+	v, _, err := l.client.Bucket.Get(context.Background(), opt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list objects: %v", err)
+	}
+
+	fileList := make([]ObjectInfo, 0, len(v.Contents))
+	for _, content := range v.Contents {
+		// COS 返回 ISO 8601 格式的时间，例如 2019-05-24T10:56:40.000Z
+		parsedTime, err := time.Parse(time.RFC3339, content.LastModified)
 		if err != nil {
-			return nil, "", err
+			return nil, "", fmt.Errorf("failed to parse LastModified: %v", err)
 		}
 
-		var fileList []model.FileInfo
-		for _, content := range v.Contents {
-			// 解析 LastModified 字段
-			// 与阿里云返回的格式不一样
-			parsedTime, err := time.Parse(time.RFC3339, content.LastModified)
-			if err != nil {
-				return nil, "", fmt.Errorf("failed to parse LastModified: %v", err)
-			}
+		fileList = append(fileList, ObjectInfo{
+			Key:          content.Key,
+			Size:         content.Size,
+			ETag:         strings.Trim(content.ETag, `"`),
+			LastModified: parsedTime,
+			StorageClass: storageClassName(content.StorageClass),
+		})
+	}
 
-			fileList = append(fileList, model.FileInfo{
-				Key:           content.Key,
-				ContentLength: content.Size,
-				ETag:          content.ETag,
-				LastModified:  parsedTime,
-			})
+	// 返回 NextMarker 作为下一次查询的起点，未设置分隔符时 COS 可能不返回 NextMarker，以最后一个对象代替
+	var nextMarker string
+	if v.IsTruncated {
+		nextMarker = v.NextMarker
+		if nextMarker == "" && len(v.Contents) > 0 {
+			nextMarker = v.Contents[len(v.Contents)-1].Key
 		}
+	}
 
-		// 返回 NextMarker 作为下一次查询的起点
-		var nextMarker string
-		if v.IsTruncated {
-			nextMarker = v.NextMarker
-		}
+	return fileList, nextMarker, nil
+}
 
-		return fileList, nextMarker, nil
-	}
-*/
+// List 实现 ObjectLister 接口
+func (u *CosUploader) List(prefix, marker string, maxKeys int) ([]ObjectInfo, string, error) {
+	return (&CosLister{client: u.client}).List(prefix, marker, maxKeys)
+}
 
 func (c *CosCopier) CopyFile(srcBucket, srcObject, destBucket, destObject, srcRegion, destRegion string) error {
 	// 使用提供的 region 信息创建源客户端和目标客户端
@@ -268,7 +419,11 @@ func (c *CosCopier) CopyFile(srcBucket, srcObject, destBucket, destObject, srcRe
 	sourceFileURL := fmt.Sprintf("%s/%s", srcURL.Host, srcObject)
 
 	// 调用 COS 的 Copy 方法从源桶拷贝到目标桶
-	_, _, err := destClient.Object.Copy(context.Background(), destObject, sourceFileURL, nil)
+	var options *cos.ObjectCopyOptions
+	if header := c.sse.cosHeader(); header != nil {
+		options = &cos.ObjectCopyOptions{ObjectCopyHeaderOptions: &cos.ObjectCopyHeaderOptions{XOptionHeader: header}}
+	}
+	_, _, err := destClient.Object.Copy(context.Background(), destObject, sourceFileURL, options)
 	if err != nil {
 		return fmt.Errorf("failed to copy file: %v", err)
 	}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/pkg/config"
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// thumbnailMaxPixels 原图的最大像素数，超出时不生成缩略图，避免解码占用过多内存
+const thumbnailMaxPixels = 50 * 1000 * 1000
+
+// ErrThumbnailUnsupported 文件不是可生成缩略图的图片
+var ErrThumbnailUnsupported = errors.New("该文件不支持生成缩略图")
+
+// Thumbnail 缩略图信息，内容在 Load 时读取或生成
+type Thumbnail struct {
+	Key         string // 缩略图对象路径
+	Source      string // 原图对象路径
+	Profile     string // 原图所在的存储配置
+	Size        int
+	source      ContentMeta // 原图的存储元数据
+	ContentType string
+	ETag        string
+}
+
+// thumbnailFormat 返回缩略图的格式和扩展名：jpeg、png、webp（无损编码）
+func thumbnailFormat() (string, string) {
+	switch strings.ToLower(config.ThumbnailFormat) {
+	case "png":
+		return "image/png", "png"
+	case "webp":
+		return "image/webp", "webp"
+	}
+	return "image/jpeg", "jpg"
+}
+
+// thumbnailExts 所有支持的缩略图扩展名，删除时包括修改 THUMBNAIL_FORMAT 前生成的缩略图
+var thumbnailExts = []string{"jpg", "png", "webp"}
+
+// thumbnailKey 返回缩略图在存储桶中的路径，与原图存放在一起
+func thumbnailKey(key string, size int) string {
+	_, ext := thumbnailFormat()
+	return thumbnailKeyExt(key, size, ext)
+}
+
+// thumbnailKeyExt 返回指定格式的缩略图路径
+func thumbnailKeyExt(key string, size int, ext string) string {
+	return fmt.Sprintf("%s.thumb_%d.%s", key, size, ext)
+}
+
+// thumbnailSize 校验请求的尺寸，只允许配置中的尺寸，为 0 时使用最小的尺寸
+func thumbnailSize(size int) (int, error) {
+	if len(config.ThumbnailSizes) == 0 {
+		return 0, errors.New("未配置缩略图尺寸")
+	}
+	smallest := config.ThumbnailSizes[0]
+	for _, s := range config.ThumbnailSizes {
+		if size > 0 && int64(size) == s {
+			return size, nil
+		}
+		if s < smallest {
+			smallest = s
+		}
+	}
+	if size > 0 {
+		return 0, fmt.Errorf("不支持的缩略图尺寸: %d", size)
+	}
+	return int(smallest), nil
+}
+
+// GetThumbnail 返回文件最新内容的缩略图信息，需要查看权限；缩略图路径由内容路径决定，保存新版本后自动指向新的缩略图
+func GetThumbnail(user *User, fileID int64, size int) (*Thumbnail, error) {
+	file, err := permissionFind(int(fileID), user, 0)
+	if err != nil {
+		return nil, err
+	}
+	if file.Type != "picture" || strings.ToLower(file.Ext) == "svg" {
+		return nil, ErrThumbnailUnsupported
+	}
+	size, err = thumbnailSize(size)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := GetContentMeta(file)
+	version := meta.Hash
+	if version == "" {
+		version = fmt.Sprintf("%d-%d", file.ID, file.UpdatedAt.Unix())
+	}
+	contentType, _ := thumbnailFormat()
+	return &Thumbnail{
+		Key:         thumbnailKey(meta.Key, size),
+		Source:      meta.Key,
+		Profile:     meta.Profile,
+		Size:        size,
+		source:      meta,
+		ContentType: contentType,
+		ETag:        fmt.Sprintf(`"%s-%d"`, version, size),
+	}, nil
+}
+
+// Load 读取缩略图，不存在时由原图生成并保存到存储桶；加密的原图每次生成，不保存明文缩略图
+func (t *Thumbnail) Load() ([]byte, error) {
+	if t.source.Envelope == nil {
+		if body, err := openObject(t.Profile, t.Key); err == nil {
+			defer body.Close()
+			return io.ReadAll(body)
+		}
+	}
+	return generateThumbnail(t.source, t.Size)
+}
+
+// generateThumbnail 下载原图生成指定尺寸的缩略图，未加密时与原图保存在同一存储中
+func generateThumbnail(source ContentMeta, size int) ([]byte, error) {
+	uploader, err := profileStore(source.Profile)
+	if err != nil {
+		return nil, err
+	}
+	body, err := openContent(source)
+	if err != nil {
+		return nil, fmt.Errorf("读取原图失败: %v", err)
+	}
+	defer body.Close()
+	raw, err := readThumbnailSource(body)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := makeThumbnail(raw, size)
+	if err != nil {
+		return nil, err
+	}
+	if source.Envelope != nil {
+		return data, nil
+	}
+	if _, err := uploader.PutObject(bytes.NewReader(data), thumbnailKey(source.Key, size), PutOptions{}); err != nil {
+		// 保存失败不影响本次返回，下次请求时重新生成
+		log.Printf("保存缩略图失败: %s, 错误: %v", source.Key, err)
+	}
+	return data, nil
+}
+
+// readThumbnailSource 先按文件头读取图片尺寸，不支持的格式或超出像素上限时不再读取剩余内容
+func readThumbnailSource(body io.Reader) ([]byte, error) {
+	var head bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(body, &head))
+	if err != nil {
+		return nil, ErrThumbnailUnsupported
+	}
+	if cfg.Width*cfg.Height > thumbnailMaxPixels {
+		return nil, fmt.Errorf("图片尺寸过大: %dx%d", cfg.Width, cfg.Height)
+	}
+	raw, err := io.ReadAll(io.MultiReader(&head, body))
+	if err != nil {
+		return nil, fmt.Errorf("读取原图失败: %v", err)
+	}
+	return raw, nil
+}
+
+// makeThumbnail 将图片等比缩放到边长不超过 size，小图不放大
+func makeThumbnail(raw []byte, size int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrThumbnailUnsupported
+	}
+	if cfg.Width*cfg.Height > thumbnailMaxPixels {
+		return nil, fmt.Errorf("图片尺寸过大: %dx%d", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrThumbnailUnsupported
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+
+	contentType, _ := thumbnailFormat()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if contentType == "image/jpeg" {
+		// jpeg 不支持透明，透明区域以白色填充
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/png":
+		err = png.Encode(&buf, dst)
+	case "image/webp":
+		err = nativewebp.Encode(&buf, dst, nil)
+	default:
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("生成缩略图失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// deleteThumbnails 删除内容对应的全部尺寸的缩略图
+func deleteThumbnails(profile string, key string) {
+	uploader, err := profileStore(profile)
+	if err != nil {
+		log.Printf("删除缩略图失败: %s, 错误: %v", key, err)
+		return
+	}
+	for _, size := range config.ThumbnailSizes {
+		for _, ext := range thumbnailExts {
+			if err := uploader.Delete(thumbnailKeyExt(key, int(size), ext)); err != nil {
+				log.Printf("删除缩略图失败: %s, 错误: %v", key, err)
+			}
+		}
+	}
+}
+
+// refreshThumbnails 保存新版本后处理缩略图：旧版本不是去重内容时删除其缩略图（去重内容的缩略图随内容一起清理），
+// 开启上传时生成则在后台生成新版本的缩略图
+func refreshThumbnails(fileType string, old ContentMeta, blob *entity.FileBlob) {
+	if fileType != "picture" {
+		return
+	}
+	key := blob.ObjectKey
+	if old.Key != "" && old.Key != key && old.Hash == "" {
+		go deleteThumbnails(old.Profile, old.Key)
+	}
+	if config.ThumbnailOnUpload && blob.Cipher == "" {
+		go func() {
+			for _, size := range config.ThumbnailSizes {
+				if _, err := generateThumbnail(blobMeta(blob), int(size)); err != nil {
+					log.Printf("生成缩略图失败: %s, 错误: %v", key, err)
+					return
+				}
+			}
+		}()
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+
+	"github.com/cloudisk/pkg/config"
+)
+
+func Test_MakeThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		src.Set(x, 100, color.RGBA{R: 255, A: 255})
+	}
+	var raw bytes.Buffer
+	if err := png.Encode(&raw, src); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := makeThumbnail(raw.Bytes(), 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Width != 128 || cfg.Height != 64 {
+		t.Errorf("unexpected thumbnail size %dx%d", cfg.Width, cfg.Height)
+	}
+
+	// 小图不放大
+	data, _ = makeThumbnail(raw.Bytes(), 512)
+	cfg, _, _ = image.DecodeConfig(bytes.NewReader(data))
+	if cfg.Width != 400 || cfg.Height != 200 {
+		t.Errorf("small image should keep its size, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	if _, err := makeThumbnail([]byte("not an image"), 128); err != ErrThumbnailUnsupported {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	defer func(format string) { config.ThumbnailFormat = format }(config.ThumbnailFormat)
+	config.ThumbnailFormat = "webp"
+	data, err = makeThumbnail(raw.Bytes(), 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || format != "webp" || cfg.Width != 128 || cfg.Height != 64 {
+		t.Errorf("unexpected webp thumbnail %s %dx%d %v", format, cfg.Width, cfg.Height, err)
+	}
+}
+
+func Test_ThumbnailSize(t *testing.T) {
+	if size, err := thumbnailSize(0); err != nil || size != 128 {
+		t.Errorf("default size should be the smallest, got %d %v", size, err)
+	}
+	if _, err := thumbnailSize(300); err == nil {
+		t.Errorf("unconfigured size should be rejected")
+	}
+	if key := thumbnailKey("blobs/ab/abc", 512); key != "blobs/ab/abc.thumb_512.jpg" {
+		t.Errorf("unexpected thumbnail key %s", key)
+	}
+}
+
+// errReader 读取即返回错误，用于确认没有读取文件头之后的内容
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("read past header")
+}
+
+func Test_readThumbnailSource(t *testing.T) {
+	// 65535x65535 的 GIF 文件头，超出像素上限时不应读取后续内容
+	header := []byte("GIF89a\xff\xff\xff\xff\x00\x00\x00")
+	if _, err := readThumbnailSource(io.MultiReader(bytes.NewReader(header), errReader{})); err == nil || err == ErrThumbnailUnsupported {
+		t.Errorf("oversize image should be rejected by size, got %v", err)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	var raw bytes.Buffer
+	png.Encode(&raw, src)
+	data, err := readThumbnailSource(bytes.NewReader(raw.Bytes()))
+	if err != nil || !bytes.Equal(data, raw.Bytes()) {
+		t.Errorf("readThumbnailSource should return the whole image, err=%v", err)
+	}
+}
+
+func Test_deleteThumbnails(t *testing.T) {
+	store := useTestStore(t, "test")
+	for _, ext := range thumbnailExts {
+		store.objects[thumbnailKeyExt("blobs/ab/abc", 128, ext)] = []byte("thumb")
+	}
+	store.objects["blobs/ab/abc"] = []byte("image")
+	deleteThumbnails("test", "blobs/ab/abc")
+	if len(store.objects) != 1 {
+		t.Errorf("thumbnails in every format should be deleted, left %d objects", len(store.objects))
+	}
+}
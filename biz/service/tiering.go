@@ -0,0 +1,353 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudisk/biz/dal/query"
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/pkg/config"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gen"
+	"gorm.io/gorm/clause"
+)
+
+// ErrObjectRestoring 归档对象正在恢复，恢复完成后才能下载
+var ErrObjectRestoring = errors.New("文件已归档，正在恢复中，请稍后再试")
+
+// tierClassRank 各存储类型由热到冷的顺序，转换规则只会把对象转为更冷的类型
+var tierClassRank = map[string]int{
+	StorageStandard:    0,
+	StorageIA:          1,
+	StorageArchive:     2,
+	StorageColdArchive: 3,
+	StorageDeepArchive: 4,
+}
+
+// TierRule 存储类型转换规则，所列条件需全部满足
+type TierRule struct {
+	Class    string   `yaml:"class"`     // 转换后的存储类型：ia、archive、cold_archive、deep_archive
+	IdleDays int64    `yaml:"idle_days"` // 超过该天数未下载，0 为不限制
+	Types    []string `yaml:"types"`     // 引用该对象的文件均属于这些 getFileType 分组
+	Profiles []string `yaml:"profiles"`  // 对象所在的存储配置或云存储
+}
+
+// Tiering 存储类型转换配置，按顺序匹配规则，先匹配的优先
+type Tiering struct {
+	Rules []TierRule `yaml:"rules"`
+}
+
+var (
+	tiering     *Tiering
+	tieringOnce sync.Once
+)
+
+// getTiering 读取 TIERING_FILE 中的转换规则，未配置时没有规则
+func getTiering() *Tiering {
+	tieringOnce.Do(func() {
+		tiering = &Tiering{}
+		defer tiering.normalize()
+		if config.TieringFile == "" {
+			return
+		}
+		data, err := os.ReadFile(config.TieringFile)
+		if err != nil {
+			log.Printf("读取存储类型转换规则失败: %s, 错误: %v", config.TieringFile, err)
+			return
+		}
+		if err := yaml.Unmarshal(data, tiering); err != nil {
+			log.Printf("解析存储类型转换规则失败: %s, 错误: %v", config.TieringFile, err)
+		}
+	})
+	return tiering
+}
+
+// normalize 统一类型名称的大小写，忽略目标存储类型无效或没有任何条件的规则
+func (t *Tiering) normalize() {
+	rules := []TierRule{}
+	for _, rule := range t.Rules {
+		rule.Class = storageClassName(strings.TrimSpace(rule.Class))
+		if tierClassRank[rule.Class] == 0 {
+			log.Printf("忽略存储类型转换规则，存储类型无效: %s", rule.Class)
+			continue
+		}
+		if rule.IdleDays <= 0 && len(rule.Types) == 0 && len(rule.Profiles) == 0 {
+			log.Printf("忽略存储类型转换规则，没有任何条件: %s", rule.Class)
+			continue
+		}
+		for i, fileType := range rule.Types {
+			rule.Types[i] = strings.ToLower(strings.TrimSpace(fileType))
+		}
+		rules = append(rules, rule)
+	}
+	t.Rules = rules
+}
+
+// target 返回对象应转换到的存储类型，没有匹配的规则时返回空字符串
+func (t *Tiering) target(profile string, fileTypes []string, idle time.Duration) string {
+	for _, rule := range t.Rules {
+		if rule.IdleDays > 0 && idle < time.Duration(rule.IdleDays)*24*time.Hour {
+			continue
+		}
+		if len(rule.Profiles) > 0 && !isContain(rule.Profiles, profile) {
+			continue
+		}
+		if len(rule.Types) > 0 && !allContained(rule.Types, fileTypes) {
+			continue
+		}
+		return rule.Class
+	}
+	return ""
+}
+
+// allContained 判断 items 中的每一项是否都在 set 中
+func allContained(set []string, items []string) bool {
+	for _, item := range items {
+		if !isContain(set, strings.ToLower(item)) {
+			return false
+		}
+	}
+	return true
+}
+
+// storeTierer 返回存储配置对应的 ObjectTierer，副本复制的包装只转换主存储中的对象
+func storeTierer(profile string) (ObjectTierer, error) {
+	store, err := profileStore(profile)
+	if err != nil {
+		return nil, err
+	}
+	store = primaryStore(store)
+	tierer, ok := store.(ObjectTierer)
+	if !ok {
+		return nil, fmt.Errorf("存储配置 %s 不支持转换存储类型", profile)
+	}
+	return tierer, nil
+}
+
+// findTier 返回对象的存储类型记录，没有记录时返回 nil
+func findTier(profile string, key string) *entity.FileTier {
+	tier, err := query.Q.FileTier.Where(query.FileTier.Profile.Eq(profile), query.FileTier.ObjectKey.Eq(key)).First()
+	if err != nil {
+		return nil
+	}
+	return tier
+}
+
+// saveTier 写入对象的存储类型记录，已有记录时只更新 columns 列
+func saveTier(tier *entity.FileTier, columns ...string) error {
+	return query.Q.FileTier.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "profile"}, {Name: "object_key"}},
+		DoUpdates: clause.AssignmentColumns(append(columns, "updated_at")),
+	}).Create(tier)
+}
+
+// recordAccess 记录对象的下载时间，供按未访问天数转换存储类型
+func recordAccess(meta ContentMeta) {
+	tier := &entity.FileTier{
+		Profile:      contentProvider(meta),
+		ObjectKey:    meta.Key,
+		StorageClass: StorageStandard,
+		AccessedAt:   time.Now(),
+	}
+	if err := saveTier(tier, "accessed_at"); err != nil {
+		log.Printf("记录下载时间失败: %s, 错误: %v", meta.Key, err)
+	}
+}
+
+// checkRestore 读取已归档的对象前确认恢复状态：未恢复时发起恢复，恢复中时返回 ErrObjectRestoring
+func checkRestore(meta ContentMeta) error {
+	tier := findTier(contentProvider(meta), meta.Key)
+	if tier == nil || !needsRestore(tier.StorageClass) {
+		return nil
+	}
+	if tier.RestoreStatus == "restored" && tier.RestoreExpireAt != nil && time.Now().Before(*tier.RestoreExpireAt) {
+		return nil
+	}
+	return requestRestore(meta, tier.StorageClass)
+}
+
+// requestRestore 为归档对象发起恢复，已在恢复中时查询是否完成，完成后返回 nil
+func requestRestore(meta ContentMeta, class string) error {
+	profile := contentProvider(meta)
+	tierer, err := storeTierer(profile)
+	if err != nil {
+		return err
+	}
+	tier := findTier(profile, meta.Key)
+	if tier != nil && tier.RestoreStatus == "restoring" {
+		restored, err := tierer.Restored(meta.Key)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			return ErrObjectRestoring
+		}
+		tier.RestoreStatus = "restored"
+		expireAt := time.Now().Add(time.Duration(config.TieringRestoreDays) * 24 * time.Hour)
+		tier.RestoreExpireAt = &expireAt
+		if err := saveTier(tier, "restore_status", "restore_expire_at"); err != nil {
+			log.Printf("更新恢复状态失败: %s, 错误: %v", meta.Key, err)
+		}
+		log.Printf("归档对象已恢复: %s", meta.Key)
+		return nil
+	}
+	if err := tierer.Restore(meta.Key, int(config.TieringRestoreDays)); err != nil {
+		return err
+	}
+	log.Printf("已发起归档对象的恢复: %s", meta.Key)
+	if tier == nil {
+		tier = &entity.FileTier{Profile: profile, ObjectKey: meta.Key, StorageClass: class, AccessedAt: time.Now()}
+	}
+	tier.RestoreStatus = "restoring"
+	if err := saveTier(tier, "storage_class", "restore_status"); err != nil {
+		log.Printf("更新恢复状态失败: %s, 错误: %v", meta.Key, err)
+	}
+	return ErrObjectRestoring
+}
+
+// IsRestoring 判断文件内容对应的归档对象是否正在恢复，恢复已完成时顺带更新记录
+func IsRestoring(fileContent *gorm_gen.FileContent) bool {
+	meta := parseContentMeta(fileContent)
+	if meta.Key == "" {
+		file, err := query.Q.File.Unscoped().Where(query.File.ID.Eq(fileContent.Fid)).First()
+		if err != nil {
+			return false
+		}
+		meta.Key = GetFilePath(file)
+	}
+	tier := findTier(contentProvider(meta), meta.Key)
+	if tier == nil || tier.RestoreStatus != "restoring" {
+		return false
+	}
+	return errors.Is(requestRestore(meta, tier.StorageClass), ErrObjectRestoring)
+}
+
+// tierObject 参与存储类型转换的对象及引用它的文件
+type tierObject struct {
+	Profile  string
+	Key      string
+	Types    []string
+	LastUsed time.Time // 引用该对象的内容最近一次写入时间
+}
+
+// TierResult 单个对象的转换结果
+type TierResult struct {
+	Profile string
+	Key     string
+	From    string
+	To      string
+	Err     error
+}
+
+// TierSummary 存储类型转换的汇总
+type TierSummary struct {
+	Transitioned int
+	Skipped      int
+	Failed       int
+}
+
+// collectTierObjects 遍历全部文件（含回收站中的）的各个版本，按存储配置和对象路径汇总
+func collectTierObjects() ([]*tierObject, error) {
+	var objects []*tierObject
+	byKey := map[string]*tierObject{}
+	var files []*gorm_gen.File
+	err := query.Q.File.Unscoped().Where(query.File.Type.Neq("folder")).FindInBatches(&files, 100, func(tx gen.Dao, batch int) error {
+		ids := make([]int64, 0, len(files))
+		byID := map[int64]*gorm_gen.File{}
+		for _, file := range files {
+			ids = append(ids, file.ID)
+			byID[file.ID] = file
+		}
+		contents, err := query.Q.FileContent.Unscoped().Where(query.FileContent.Fid.In(ids...)).Find()
+		if err != nil {
+			return err
+		}
+		for _, content := range contents {
+			meta := parseContentMeta(content)
+			file := byID[content.Fid]
+			if meta.Key == "" {
+				meta.Key = GetFilePath(file)
+			}
+			id := contentProvider(meta) + "\x00" + meta.Key
+			obj, ok := byKey[id]
+			if !ok {
+				obj = &tierObject{Profile: contentProvider(meta), Key: meta.Key}
+				byKey[id] = obj
+				objects = append(objects, obj)
+			}
+			if !isContain(obj.Types, file.Type) {
+				obj.Types = append(obj.Types, file.Type)
+			}
+			if content.UpdatedAt.After(obj.LastUsed) {
+				obj.LastUsed = content.UpdatedAt
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk files: %v", err)
+	}
+	return objects, nil
+}
+
+// TierObjects 按 TIERING_FILE 的规则转换对象的存储类型，dryRun 时只报告不转换
+func TierObjects(dryRun bool, fn func(TierResult)) (TierSummary, error) {
+	var summary TierSummary
+	rules := getTiering()
+	if len(rules.Rules) == 0 {
+		return summary, errors.New("未配置存储类型转换规则，请设置 TIERING_FILE")
+	}
+	objects, err := collectTierObjects()
+	if err != nil {
+		return summary, err
+	}
+	now := time.Now()
+	for _, obj := range objects {
+		tier := findTier(obj.Profile, obj.Key)
+		if tier == nil {
+			tier = &entity.FileTier{Profile: obj.Profile, ObjectKey: obj.Key, StorageClass: StorageStandard, AccessedAt: obj.LastUsed}
+		}
+		lastUsed := obj.LastUsed
+		if tier.AccessedAt.After(lastUsed) {
+			lastUsed = tier.AccessedAt
+		}
+		to := rules.target(obj.Profile, obj.Types, now.Sub(lastUsed))
+		if to == "" || tierClassRank[to] <= tierClassRank[tier.StorageClass] {
+			summary.Skipped++
+			continue
+		}
+		result := TierResult{Profile: obj.Profile, Key: obj.Key, From: tier.StorageClass, To: to}
+		if !dryRun {
+			result.Err = transitionObject(tier, to)
+		}
+		if result.Err != nil {
+			summary.Failed++
+		} else {
+			summary.Transitioned++
+		}
+		if fn != nil {
+			fn(result)
+		}
+	}
+	return summary, nil
+}
+
+// transitionObject 转换对象的存储类型并记录
+func transitionObject(tier *entity.FileTier, class string) error {
+	tierer, err := storeTierer(tier.Profile)
+	if err != nil {
+		return err
+	}
+	if err := tierer.SetStorageClass(tier.ObjectKey, class); err != nil {
+		return err
+	}
+	tier.StorageClass = class
+	tier.RestoreStatus = ""
+	return saveTier(tier, "storage_class", "restore_status")
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Tiering_target(t *testing.T) {
+	tiering := &Tiering{Rules: []TierRule{
+		{Class: "Archive", Types: []string{" Archive", "media"}, IdleDays: 30},
+		{Class: "archive", IdleDays: 365, Profiles: []string{"cn-oss"}},
+		{Class: "ia", IdleDays: 180},
+		{Class: "standard", IdleDays: 1},
+		{Class: "ia"},
+	}}
+	tiering.normalize()
+	if len(tiering.Rules) != 3 {
+		t.Fatalf("normalize 后规则数 = %d, want 3", len(tiering.Rules))
+	}
+
+	day := 24 * time.Hour
+	cases := []struct {
+		profile string
+		types   []string
+		idle    time.Duration
+		want    string
+	}{
+		{"aliyun", []string{"media"}, 31 * day, StorageArchive},
+		// 引用对象的文件需全部属于规则中的类型
+		{"aliyun", []string{"media", "document"}, 31 * day, ""},
+		{"aliyun", []string{"media", "document"}, 200 * day, StorageIA},
+		{"aliyun", []string{"media"}, 10 * day, ""},
+		// 先匹配的规则优先
+		{"cn-oss", []string{"document"}, 400 * day, StorageArchive},
+		{"aliyun", []string{"document"}, 400 * day, StorageIA},
+		{"cn-oss", []string{"document"}, 200 * day, StorageIA},
+	}
+	for _, c := range cases {
+		if got := tiering.target(c.profile, c.types, c.idle); got != c.want {
+			t.Errorf("target(%s, %v, %v) = %q, want %q", c.profile, c.types, c.idle, got, c.want)
+		}
+	}
+}
+
+func Test_restoreDone(t *testing.T) {
+	cases := map[string]bool{
+		"":                       false,
+		`ongoing-request="true"`: false,
+		`ongoing-request="false", expiry-date="Sun, 16 Apr 2017 08:12:33 GMT"`: true,
+	}
+	for header, want := range cases {
+		if got := restoreDone(header); got != want {
+			t.Errorf("restoreDone(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/cloudisk/biz/dal/query"
 	"github.com/cloudwego/hertz/pkg/app/client"
 	"github.com/cloudwego/hertz/pkg/protocol"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
@@ -74,3 +76,24 @@ func GetUserInfo(token []byte) (*User, error) {
 	// fmt.Printf("%v\n", string(res.Body()))
 	return &u.User, nil
 }
+
+// userByID 从 DooTask 会员表读取会员身份，供没有登录凭证的后台任务重新校验权限；会员不存在或已禁用时返回错误
+func userByID(userid int64) (*User, error) {
+	var row struct {
+		Userid   int
+		Identity string
+	}
+	err := query.Q.File.UnderlyingDB().Table("pre_users").Select("userid", "identity").
+		Where("userid = ? AND disable_at IS NULL", userid).Take(&row).Error
+	if err != nil {
+		return nil, fmt.Errorf("会员不存在或已禁用: %v", err)
+	}
+	// DooTask 的身份以逗号分隔存储，如 ,admin,
+	user := &User{Userid: row.Userid, Identity: []string{}}
+	for _, identity := range strings.Split(row.Identity, ",") {
+		if identity != "" {
+			user.Identity = append(user.Identity, identity)
+		}
+	}
+	return user, nil
+}
@@ -19,6 +19,7 @@ package main
 import (
 	_ "github.com/cloudisk/biz/dal"
 	"github.com/cloudisk/biz/dal/mysql"
+	"github.com/cloudisk/biz/model/entity"
 	"gorm.io/gen"
 )
 
@@ -39,6 +40,13 @@ func main() {
 		g.GenerateModelAs("pre_files", "File"),
 		g.GenerateModelAs("pre_file_users", "File_User"),
 		g.GenerateModelAs("pre_file_contents", "FileContent"),
+		// 本服务自有的表以 biz/model/entity 中的结构体为准（AutoMigrate 依赖其索引和长度），只生成查询代码
+		entity.FileBlob{},
+		entity.FileQuota{},
+		entity.FileMigration{},
+		entity.FileReplica{},
+		entity.FileTier{},
+		entity.FileJob{},
 	)
 
 	// Generate the code
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/cloudisk/biz/service"
+)
+
+// runExtract 为存量文件补充提取全文
+func runExtract(args []string) error {
+	fs := flag.NewFlagSet("extract", flag.ExitOnError)
+	id := fs.Int64("id", 0, "只提取指定文件内容记录ID，为 0 时补充提取全部尚无文本的文件")
+	fs.Parse(args)
+
+	if *id > 0 {
+		if err := service.ExtractContent(*id); err != nil {
+			return err
+		}
+		fmt.Printf("提取完成: 内容ID %d\n", *id)
+		return nil
+	}
+
+	var total, failed int
+	err := service.ExtractFiles(func(fileID int64, err error) {
+		total++
+		if err != nil {
+			failed++
+			fmt.Printf("error    文件ID %d: %v\n", fileID, err)
+			return
+		}
+		fmt.Printf("ok       文件ID %d\n", fileID)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("提取完成: 共 %d 个文件, %d 个失败\n", total, failed)
+	return nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cloudisk/biz/dal/mysql"
+)
+
+// runFulltext 为 pre_file_contents.text 创建 ngram 全文索引；大表上耗时较长且会锁表，应在低峰期执行，完成后重启服务
+func runFulltext(args []string) error {
+	created, err := mysql.CreateFulltextIndex()
+	if err != nil {
+		return fmt.Errorf("创建全文索引失败: %v", err)
+	}
+	if !created {
+		fmt.Println("全文索引已存在")
+		return nil
+	}
+	fmt.Println("已创建全文索引，重启服务后搜索改用全文匹配")
+	return nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cloudisk/biz/service"
+)
+
+// runGC 清理超过保留期且已无引用的内容
+func runGC(args []string) error {
+	purged, err := service.PurgeBlobs()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("清理完成: 共删除 %d 个对象\n", purged)
+	return nil
+}
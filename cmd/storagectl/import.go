@@ -0,0 +1,37 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/cloudisk/biz/model/gorm_gen"
+	"github.com/cloudisk/biz/service"
+)
+
+// runImport 将存储桶中已有的对象按目录结构导入为文件夹和文件，不复制对象；重复执行只导入新增的对象
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	profile := fs.String("profile", "", "导入该存储配置或云存储中的对象，默认为 CLOUD_PROVIDER 对应的云存储")
+	prefix := fs.String("prefix", "", "导入该前缀下的对象，如 projects/2020/")
+	userid := fs.Int64("userid", 0, "导入后文件的拥有者")
+	pid := fs.Int64("pid", 0, "导入到的文件夹ID，0 为根目录")
+	fs.Parse(args)
+	if *userid <= 0 {
+		fs.Usage()
+		return errors.New("需要指定 -userid")
+	}
+
+	result, err := service.ImportPrefix(*profile, *prefix, *userid, *pid, func(key string, file *gorm_gen.File) {
+		fmt.Printf("imported %s -> id=%d size=%d\n", key, file.ID, file.Size)
+	})
+	fmt.Printf("导入完成: 新建文件夹 %d 个, 文件 %d 个（%d 字节）, 跳过 %d 个\n",
+		result.Folders, result.Files, result.Size, result.Skipped)
+	if err != nil {
+		return err
+	}
+	if result.Files > 0 {
+		fmt.Println("请执行 storagectl extract 和 storagectl reindex 为导入的文件补充全文和搜索索引")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/cloudisk/biz/service"
+)
+
+// runJobs 列出后台任务，重新排队重试耗尽的任务或取消任务
+func runJobs(args []string) error {
+	fs := flag.NewFlagSet("jobs", flag.ExitOnError)
+	status := fs.String("status", "", "按状态筛选：pending、running、done、dead、canceled，为空时列出全部")
+	limit := fs.Int("limit", 50, "最多列出的任务数")
+	retry := fs.Bool("retry", false, "将 dead 任务重新排队，配合 -id 只重试指定任务")
+	cancel := fs.Bool("cancel", false, "取消 -id 指定的任务")
+	id := fs.Int64("id", 0, "任务ID")
+	fs.Parse(args)
+
+	switch {
+	case *retry:
+		count, err := service.RetryDeadJobs(*id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已重新排队 %d 个任务\n", count)
+		return nil
+	case *cancel:
+		if *id == 0 {
+			fs.Usage()
+			return fmt.Errorf("需要指定 -id")
+		}
+		job, err := service.CancelJobByID(*id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已取消任务 %d (%s)\n", job.ID, job.Type)
+		return nil
+	}
+
+	jobs, err := service.ListJobs(*status, *limit)
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		fmt.Printf("%-6d %-14s %-9s %d/%d %s %s\n", job.ID, job.Type, job.Status, job.Attempts, job.MaxAttempts,
+			job.UpdatedAt.Format("2006-01-02 15:04:05"), job.Error)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	_ "github.com/cloudisk/biz/dal"
+)
+
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"verify", "重新计算云端对象的校验值并与记录比对", runVerify},
+	{"gc", "清理超过保留期且已无引用的内容", runGC},
+	{"extract", "为存量文件补充提取全文", runExtract},
+	{"reindex", "重建搜索索引", runReindex},
+	{"fulltext", "为提取的全文创建 MySQL ngram 全文索引", runFulltext},
+	{"migrate", "将文件内容迁移到另一个云存储", runMigrate},
+	{"reconcile", "核对存储桶与数据库，报告孤立对象和缺失对象", runReconcile},
+	{"import", "将存储桶中已有的对象导入为文件夹和文件", runImport},
+	{"replicate", "为存量文件补充复制到副本云存储", runReplicate},
+	{"tier", "按规则将久未下载或指定类型的对象转为低频或归档存储", runTier},
+	{"jobs", "查看、重试或取消后台任务", runJobs},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "用法: storagectl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
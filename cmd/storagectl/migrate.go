@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/cloudisk/biz/service"
+)
+
+// runMigrate 将文件内容从一个云存储复制到另一个云存储，全部完成后切换文件内容记录；
+// 进度记录在 pre_file_migrations，中断后重新执行即可从断点继续
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := fs.String("from", "", "源存储：aliyun、tencent、qiniu")
+	to := fs.String("to", "", "目标存储：aliyun、tencent、qiniu")
+	concurrency := fs.Int("concurrency", 4, "并发迁移的对象数")
+	qps := fs.Float64("qps", 0, "每秒最多迁移的对象数，0 表示不限")
+	bps := fs.Int64("bps", 0, "每秒最多传输的字节数，0 表示不限")
+	verify := fs.Bool("verify", false, "写入后重新下载目标对象比对校验值")
+	noSwitch := fs.Bool("no-switch", false, "迁移完成后不切换文件内容记录")
+	queue := fs.Bool("queue", false, "登记为后台任务由服务进程执行，失败时自动重试")
+	fs.Parse(args)
+	if *from == "" || *to == "" {
+		fs.Usage()
+		return fmt.Errorf("需要指定 -from 和 -to")
+	}
+
+	opts := service.MigrateOptions{
+		Source:      *from,
+		Target:      *to,
+		Concurrency: *concurrency,
+		QPS:         *qps,
+		BPS:         *bps,
+		Verify:      *verify,
+	}
+	if *queue {
+		job, err := service.EnqueueMigrate(opts, !*noSwitch)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("已登记迁移任务 %d，执行 storagectl jobs 查看进度\n", job.ID)
+		return nil
+	}
+	var total, skipped int
+	failed, err := service.MigrateObjects(opts, func(r service.MigrateResult) {
+		total++
+		switch {
+		case r.Status == "skipped":
+			skipped++
+		case r.Err != nil:
+			fmt.Printf("%-8s %s: %v\n", r.Status, r.Key, r.Err)
+		default:
+			fmt.Printf("%-8s %s size=%d\n", r.Status, r.Key, r.Size)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("迁移完成: 共 %d 个对象, 已跳过 %d 个, 失败 %d 个\n", total, skipped, failed)
+	if failed > 0 {
+		return fmt.Errorf("存在迁移失败的对象，重新执行以重试，全部完成后才会切换")
+	}
+	if *noSwitch {
+		return nil
+	}
+
+	updated, err := service.SwitchProvider(*from, *to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已切换 %d 条文件内容记录，请将 CLOUD_PROVIDER 设置为 %s 后重启服务\n", updated, *to)
+	return nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/cloudisk/biz/service"
+)
+
+// runReconcile 核对存储桶与数据库，输出孤立对象和缺失对象；默认只报告，-quarantine、-delete 处理孤立对象
+func runReconcile(args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	profile := fs.String("profile", "", "存储配置ID或云存储名称：aliyun、tencent、qiniu，默认为 CLOUD_PROVIDER 对应的云存储")
+	prefix := fs.String("prefix", "", "只核对该前缀下的对象")
+	minAge := fs.Duration("min-age", 24*time.Hour, "只处理最后修改时间早于此时长的孤立对象")
+	quarantine := fs.Bool("quarantine", false, "将孤立对象移到 quarantine/ 前缀下")
+	remove := fs.Bool("delete", false, "删除孤立对象")
+	fs.Parse(args)
+	if *quarantine && *remove {
+		return errors.New("-quarantine 与 -delete 不能同时使用")
+	}
+
+	opts := service.ReconcileOptions{Profile: *profile, Prefix: *prefix, MinAge: *minAge}
+	if *quarantine {
+		opts.Action = "quarantine"
+	} else if *remove {
+		opts.Action = "delete"
+	}
+
+	report, err := service.Reconcile(opts, func(item service.ReconcileItem) {
+		switch {
+		case item.Kind == "missing":
+			fmt.Printf("%-8s %s file_id=%d\n", item.Kind, item.Key, item.FileID)
+		case item.Err != nil:
+			fmt.Printf("%-8s %s size=%d %s失败: %v\n", item.Kind, item.Key, item.Size, item.Action, item.Err)
+		default:
+			fmt.Printf("%-8s %s size=%d %s\n", item.Kind, item.Key, item.Size, item.Action)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("核对完成: 共扫描 %d 个对象, 孤立对象 %d 个（%d 字节）, 缺失对象 %d 个\n",
+		report.Objects, report.Orphans, report.OrphanSize, report.Missing)
+	if report.Failed > 0 {
+		return fmt.Errorf("%d 个孤立对象处理失败", report.Failed)
+	}
+	return nil
+}
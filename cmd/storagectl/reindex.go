@@ -0,0 +1,31 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudisk/biz/service"
+	"github.com/cloudisk/pkg/config"
+)
+
+// runReindex 将全部文件重新写入 SEARCH_INDEXER 配置的搜索索引；
+// Bleve 索引目录同一时间只能由一个进程打开，需先停止服务
+func runReindex(args []string) error {
+	if config.SearchIndexer == "" {
+		return errors.New("未配置 SEARCH_INDEXER")
+	}
+	indexer, err := service.NewSearchIndexer(config.SearchIndexer)
+	if err != nil {
+		return err
+	}
+	defer indexer.Close()
+
+	err = service.ReindexAll(indexer, func(count int) {
+		fmt.Printf("已写入 %d 个文件\n", count)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("重建索引完成")
+	return nil
+}
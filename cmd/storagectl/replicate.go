@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/cloudisk/biz/model/entity"
+	"github.com/cloudisk/biz/service"
+)
+
+// runReplicate 为存量文件内容补充复制到 REPLICA_PROVIDERS，并重新执行失败的复制任务
+func runReplicate(args []string) error {
+	fs := flag.NewFlagSet("replicate", flag.ExitOnError)
+	concurrency := fs.Int("concurrency", 4, "并发复制的对象数")
+	fs.Parse(args)
+
+	summary, err := service.ReplicateAll(*concurrency, func(row *entity.FileReplica) {
+		if row.Error != "" {
+			fmt.Printf("%-8s %s -> %s: %s\n", row.Status, row.ObjectKey, row.Provider, row.Error)
+			return
+		}
+		fmt.Printf("%-8s %s -> %s\n", row.Status, row.ObjectKey, row.Provider)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("复制完成: 已完成 %d 个, 待重试 %d 个, 失败 %d 个\n", summary.Done, summary.Pending, summary.Failed)
+	if summary.Failed > 0 {
+		return fmt.Errorf("存在复制失败的对象")
+	}
+	return nil
+}
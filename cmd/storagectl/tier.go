@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/cloudisk/biz/service"
+)
+
+// runTier 按 TIERING_FILE 的规则将对象转换为低频或归档存储
+func runTier(args []string) error {
+	fs := flag.NewFlagSet("tier", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "只列出需要转换的对象，不转换")
+	fs.Parse(args)
+
+	summary, err := service.TierObjects(*dryRun, func(result service.TierResult) {
+		if result.Err != nil {
+			fmt.Printf("失败 %s:%s %s -> %s: %v\n", result.Profile, result.Key, result.From, result.To, result.Err)
+			return
+		}
+		fmt.Printf("转换 %s:%s %s -> %s\n", result.Profile, result.Key, result.From, result.To)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("转换完成: 已转换 %d 个, 跳过 %d 个, 失败 %d 个\n", summary.Transitioned, summary.Skipped, summary.Failed)
+	if summary.Failed > 0 {
+		return fmt.Errorf("存在转换失败的对象")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/cloudisk/biz/service"
+)
+
+// runVerify 校验单个文件或全部已登记内容
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	id := fs.Int64("id", 0, "只校验指定文件ID的最新内容，为 0 时校验全部已登记内容")
+	fs.Parse(args)
+
+	var total, failed int
+	report := func(r service.VerifyResult) {
+		total++
+		if r.Status != "ok" {
+			failed++
+		}
+		if r.Err != nil {
+			fmt.Printf("%-8s %s: %v\n", r.Status, r.Key, r.Err)
+			return
+		}
+		fmt.Printf("%-8s %s size=%d expected=%s actual=%s\n", r.Status, r.Key, r.Size, r.Expected, r.Actual)
+	}
+
+	if *id > 0 {
+		result, err := service.VerifyFile(*id)
+		if err != nil {
+			return err
+		}
+		report(result)
+	} else if err := service.VerifyBlobs(report); err != nil {
+		return err
+	}
+
+	fmt.Printf("校验完成: 共 %d 个对象, %d 个异常\n", total, failed)
+	if failed > 0 {
+		return fmt.Errorf("verify failed")
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+// idl/storage.thrift
+namespace go storage
+include "common.thrift"
+// struct File {
+//   1: i32 id;
+//   2: i32 pid;
+//   3: string pids;
+//   4: i32 cid;
+//   5: string name;
+//   6: string type;
+//   7: string ext;
+//   8: i32 size;
+//   9: i32 userid;
+//   10: i32 share;
+//   11: i32 pshare;
+//   12: i32 created_id;
+//   13: string created_at;
+//   14: string updated_at;
+//   15: i32 deleted_at;
+//   16: string full_name;
+//   17: i32 overwrite;
+// }
+
+// struct FileContent {
+//   1: i32 fid;
+//   2: string content;
+//   3: string text;
+//   4: i32 size;
+//   5: i32 userid;
+//   6: string updated_at;
+//   7: string created_at;
+//   8: i32 id;
+// }
+struct UploadReq {
+    1: string Pid (api.query="pid"); 
+    2: string Cover (api.query="cover");
+    3: string WebkitRelativePath (api.query="webkitRelativePath");
+    4: string Hash (api.query="hash");
+}
+
+struct UploadResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<common.File> data;
+}
+
+struct IoUploadReq {
+    1: string Pid (api.query="pid"); 
+    2: string Cover (api.query="cover");
+    3: string WebkitRelativePath (api.query="webkitRelativePath");
+    4: i32 FileId (api.query="id");
+    5: string Hash (api.query="hash");
+}
+
+struct IoUploadResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<common.File> data;
+    4: i64 job_id;
+}
+
+struct OfficeUploadReq {
+    1: i32 Id (api.query="id", api.json="id"); 
+    2: i32 Status (api.query="status", api.json="status");
+    3: string Key (api.query="key", api.json="key");
+    4: string Url (api.query="url", api.json="url");
+}
+
+struct OfficeUploadResp {
+    1: i32 error;
+}
+
+struct SaveReq {
+    1: i32 Id (api.json="id"); 
+    2: string Content (api.json="content");
+}
+
+struct SaveResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<common.FileContent> data;
+}
+
+struct DownloadReq {
+    1: i32 FileId (api.query="id");
+    2: bool Inline (api.query="inline");
+}
+
+struct DownloadResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<common.File> data;
+    4: i64 job_id;
+}
+
+struct DownloadOfficeReq {
+    1: string Key (api.query="key");
+}
+
+struct DownloadOfficeResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<common.File> data;
+}
+
+struct RemoveReq {
+    1: i32 FileId (api.query="id");
+}
+
+struct RemoveResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<common.File> data;
+}
+
+struct InstantUploadReq {
+    1: string Pid (api.query="pid"); 
+    2: string Cover (api.query="cover");
+    3: string WebkitRelativePath (api.query="webkitRelativePath");
+    4: string Name (api.query="name");
+    5: string Hash (api.query="hash");
+    6: i64 Size (api.query="size");
+}
+
+struct InstantUploadResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<common.File> data;
+}
+
+struct DeleteReq {
+    1: i32 FileId (api.query="id");
+}
+
+struct DeleteResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<common.File> data;
+}
+
+struct FileStatus {
+    1: i32 id;
+    2: string status;
+}
+
+struct StatusReq {
+    1: list<i32> FileIds (api.query="ids");
+}
+
+struct StatusResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<FileStatus> data;
+}
+
+struct QuotaInfo {
+    1: i64 userid;
+    2: i64 pshare;
+    3: i64 used;
+    4: i64 limit;
+    5: i64 available;
+}
+
+struct QuotaReq {
+    1: i32 FileId (api.query="id");
+}
+
+struct QuotaResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<QuotaInfo> data;
+}
+
+struct SetQuotaReq {
+    1: i64 Userid (api.json="userid");
+    2: i64 Pshare (api.json="pshare");
+    3: i64 Limit (api.json="limit");
+}
+
+struct SetQuotaResp {
+    1: i8 ret;
+    2: string msg;
+    3: QuotaInfo data;
+}
+
+struct RestoreReq {
+    1: i32 FileId (api.query="id");
+}
+
+struct RestoreResp {
+    1: i8 ret;
+    2: string msg;
+}
+
+struct ThumbnailReq {
+    1: i32 FileId (api.query="id");
+    2: i32 Size (api.query="size");
+}
+
+struct SearchReq {
+    1: string Keyword (api.query="keyword");
+    2: string Type (api.query="type");
+    3: string Ext (api.query="ext");
+    4: i64 Userid (api.query="userid");
+    5: i64 Pid (api.query="pid");
+    6: string CreatedStart (api.query="created_start");
+    7: string CreatedEnd (api.query="created_end");
+    8: string UpdatedStart (api.query="updated_start");
+    9: string UpdatedEnd (api.query="updated_end");
+    10: i32 Page (api.query="page");
+    11: i32 PageSize (api.query="pagesize");
+}
+
+struct SearchItem {
+    1: common.File file;
+    2: string path;
+    3: string snippet;
+}
+
+struct SearchData {
+    1: i64 total;
+    2: i32 page;
+    3: i32 pagesize;
+    4: list<SearchItem> list;
+}
+
+struct SearchResp {
+    1: i8 ret;
+    2: string msg;
+    3: SearchData data;
+}
+
+struct ExportReq {
+    1: i32 FileId (api.query="id", api.form="id");
+    2: string Format (api.query="format", api.form="format");
+    3: bool Save (api.query="save", api.form="save");
+    4: bool Inline (api.query="inline");
+}
+
+struct ExportResp {
+    1: i8 ret;
+    2: string msg;
+    3: list<common.File> data;
+}
+
+struct ImportReq {
+    1: string Prefix (api.json="prefix");
+    2: i64 Userid (api.json="userid");
+    3: i64 Pid (api.json="pid");
+    4: string Profile (api.json="profile");
+}
+
+struct ImportInfo {
+    1: i64 folders;
+    2: i64 files;
+    3: i64 skipped;
+    4: i64 size;
+}
+
+struct ImportResp {
+    1: i8 ret;
+    2: string msg;
+    3: ImportInfo data;
+}
+
+struct ListObjectsReq {
+    1: string Profile (api.query="profile");
+    2: string Prefix (api.query="prefix");
+    3: string Marker (api.query="marker");
+    4: i32 Limit (api.query="limit");
+}
+
+struct ObjectItem {
+    1: string key;
+    2: i64 size;
+    3: string etag;
+    4: string last_modified;
+    5: string storage_class;
+}
+
+struct ListObjectsData {
+    1: list<ObjectItem> list;
+    2: string next_marker;
+}
+
+struct ListObjectsResp {
+    1: i8 ret;
+    2: string msg;
+    3: ListObjectsData data;
+}
+
+struct JobReq {
+    1: i64 Id (api.query="id", api.json="id");
+}
+
+struct JobInfo {
+    1: i64 id;
+    2: string type;
+    3: string status;
+    4: i32 attempts;
+    5: i32 max_attempts;
+    6: string error;
+    7: string result;
+    8: string next_at;
+    9: string created_at;
+    10: string updated_at;
+}
+
+struct JobResp {
+    1: i8 ret;
+    2: string msg;
+    3: JobInfo data;
+}
+
+service StorageService {
+    UploadResp upload(1: UploadReq request) (api.post="/api/file/content/upload");
+    IoUploadResp io_upload(1: IoUploadReq request) (api.post="/api/file/content/io_upload");
+    OfficeUploadResp office_upload(1: OfficeUploadReq request) (api.get="/api/file/content/office", api.post="/api/file/content/office");
+    SaveResp save(1: SaveReq request) (api.post="/api/file/content/save");
+    DownloadResp download(1: DownloadReq request) (api.get="/api/file/content/download");
+    DownloadResp downloading(1: DownloadReq request) (api.get="/api/file/content/downloading");
+    DownloadResp downloading_office(1: DownloadOfficeReq request) (api.get="/api/file/content/downloading_office");
+    RemoveResp remove(1: RemoveReq request) (api.delete="/api/file/content/remove");
+    StatusResp status(1: StatusReq request) (api.get="/api/file/content/status");
+    InstantUploadResp instant_upload(1: InstantUploadReq request) (api.post="/api/file/content/instant_upload");
+    DeleteResp delete(1: DeleteReq request) (api.delete="/api/file/content/delete");
+    QuotaResp quota(1: QuotaReq request) (api.get="/api/file/content/quota");
+    SetQuotaResp set_quota(1: SetQuotaReq request) (api.post="/api/file/content/quota");
+    RestoreResp restore(1: RestoreReq request) (api.post="/api/file/content/restore");
+    DownloadResp thumbnail(1: ThumbnailReq request) (api.get="/api/file/content/thumbnail");
+    SearchResp search(1: SearchReq request) (api.get="/api/file/search");
+    ExportResp export(1: ExportReq request) (api.get="/api/file/content/export", api.post="/api/file/content/export");
+    ImportResp import_bucket(1: ImportReq request) (api.post="/api/file/content/import");
+    ListObjectsResp list_objects(1: ListObjectsReq request) (api.get="/api/file/content/objects");
+    JobResp job(1: JobReq request) (api.get="/api/file/content/job");
+    JobResp cancel_job(1: JobReq request) (api.post="/api/file/content/job/cancel");
+}
@@ -3,26 +3,22 @@
 package main
 
 import (
-	"os"
-
 	_ "github.com/cloudisk/biz/dal"
-	"github.com/cloudisk/biz/router"
+	"github.com/cloudisk/biz/service"
 	"github.com/cloudwego/hertz/pkg/app/server"
 )
 
 func main() {
 	h := server.Default(server.WithMaxRequestBodySize(100 * 1024 * 1024))
 
-	// 从环境变量中获取云服务提供商
-	provider := os.Getenv("CLOUD_PROVIDER")
-	cloudProvider := router.CloudFactory(provider)
-	if cloudProvider != nil {
-		cloudProvider.Register(h)
-	} else {
-		// 默认使用阿里云
-		cloudProvider = router.CloudFactory("aliyun")
-		cloudProvider.Register(h)
-	}
+	// 各云存储共用同一组路由，按文件内容记录的存储配置选择云存储
+	register(h)
+
+	// 配置了服务端加密时，核对存储桶的默认加密和实际生效的加密方式
+	go service.CheckServerSideEncryption()
+
+	// 执行离线保存、上传、全文提取和迁移等后台任务，包括重启前未完成的任务
+	service.StartJobWorkers()
 
 	h.Spin()
 }
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 
 	"golang.org/x/text/language"
 )
@@ -23,4 +25,127 @@ var (
 	OssAccessKeyId     = os.Getenv("OSS_ACCESS_KEY_ID")     // 从环境变量读取 AccessKeyId
 	OssAccessKeySecret = os.Getenv("OSS_ACCESS_KEY_SECRET") // 从环境变量读取 AccessKeySecret
 
+	QuotaUserDefault   = getEnvInt64("QUOTA_USER_DEFAULT", 0)     // 会员默认存储配额(B)，0为不限制
+	BlobRetentionHours = getEnvInt64("BLOB_RETENTION_HOURS", 720) // 无引用内容的保留时长(小时)，期间可恢复已删除的文件；0为立即删除，删除后无法恢复
+
+	PolicyFile         = os.Getenv("POLICY_FILE")                    // 上传策略配置文件(yaml)，其中的设置覆盖环境变量
+	FolderMaxItems     = getEnvInt64("POLICY_FOLDER_MAX_ITEMS", 300) // 每个文件夹最多的文件或文件夹数，0为不限制
+	FolderMaxDepth     = getEnvInt64("POLICY_FOLDER_MAX_DEPTH", 0)   // 文件夹最大层级，0为不限制
+	FileMaxSize        = getEnvInt64("POLICY_FILE_MAX_SIZE", 0)      // 单个文件最大大小(B)，0为不限制
+	FileMaxSizeByType  = getEnvInt64Map("POLICY_FILE_MAX_SIZE_")     // 按文件类型的最大大小(B)，如 POLICY_FILE_MAX_SIZE_MEDIA
+	FileAllowedExts    = getEnvList("POLICY_FILE_ALLOWED_EXTS")      // 允许的扩展名，逗号分隔，为空时不限制
+	FileBlockedExts    = getEnvList("POLICY_FILE_BLOCKED_EXTS")      // 禁止的扩展名，逗号分隔
+	MimeRejectMismatch = getEnvBool("POLICY_MIME_REJECT_MISMATCH")   // 文件内容与扩展名不符时拒绝上传，否则只记录日志
+
+	ThumbnailSizes    = getEnvInt64List("THUMBNAIL_SIZES", []int64{128, 512}) // 缩略图边长(px)，逗号分隔
+	ThumbnailFormat   = getEnv("THUMBNAIL_FORMAT", "jpeg")                    // 缩略图格式：jpeg、png、webp
+	ThumbnailOnUpload = getEnvBool("THUMBNAIL_ON_UPLOAD")                     // 上传时生成缩略图，否则在首次请求时生成
+
+	ExtractMaxFileSize = getEnvInt64("EXTRACT_MAX_FILE_SIZE", 50<<20) // 参与全文提取的最大文件大小(B)
+	ExtractMaxText     = getEnvInt64("EXTRACT_MAX_TEXT", 1<<20)       // 提取文本的最大长度(B)，超出部分截断
+
+	SearchIndexer   = os.Getenv("SEARCH_INDEXER")                        // 外部搜索索引：bleve、meilisearch、elasticsearch，为空时使用 MySQL
+	SearchBlevePath = getEnv("SEARCH_BLEVE_PATH", "./data/search.bleve") // Bleve 索引目录
+	SearchHttpUrl   = os.Getenv("SEARCH_HTTP_URL")                       // Meilisearch/Elasticsearch 地址
+	SearchHttpKey   = os.Getenv("SEARCH_HTTP_KEY")                       // Meilisearch/Elasticsearch 访问密钥
+	SearchHttpIndex = getEnv("SEARCH_HTTP_INDEX", "cloudisk_files")      // Meilisearch/Elasticsearch 索引名
+
+	ExportPdfUrl   = os.Getenv("EXPORT_PDF_URL")            // Gotenberg 地址，用于将导出的 HTML 转为 PDF，为空时不支持导出 PDF
+	ExportMaxSize  = getEnvInt64("EXPORT_MAX_SIZE", 10<<20) // 可导出的最大文档大小(B)
+	ExportFontFile = os.Getenv("EXPORT_FONT_FILE")          // 导出 PNG 使用的字体文件(ttf/otf)，需包含中文字形，为空时使用内置英文字体
+	ExportPngScale = getEnvInt64("EXPORT_PNG_SCALE", 2)     // 导出 PNG 的缩放倍数
+
+	CloudProvider       = getEnv("CLOUD_PROVIDER", "aliyun") // 新内容写入的默认云存储：aliyun、tencent、qiniu
+	StorageProfilesFile = os.Getenv("STORAGE_PROFILES_FILE") // 存储配置和路由规则文件(yaml)，为空时全部使用 CLOUD_PROVIDER 对应的默认存储
+
+	ReplicaProviders   = getEnvList("REPLICA_PROVIDERS")        // 异步复制的副本云存储，逗号分隔，如 tencent,qiniu，为空时不复制
+	ReplicaWorkers     = getEnvInt64("REPLICA_WORKERS", 2)      // 复制的并发数
+	ReplicaMaxAttempts = getEnvInt64("REPLICA_MAX_ATTEMPTS", 8) // 复制失败的最大重试次数，超过后标记为失败
+
+	TieringFile        = os.Getenv("TIERING_FILE")              // 存储类型转换规则文件(yaml)，为空时不转换
+	TieringRestoreDays = getEnvInt64("TIERING_RESTORE_DAYS", 3) // 归档对象恢复后可读取的天数
+
+	EncryptionEnabled    = getEnvBool("ENCRYPTION_ENABLED")    // 新内容上传前加密(AES-256-GCM)，也可按存储配置的 encrypt 开启
+	EncryptionMasterKeys = os.Getenv("ENCRYPTION_MASTER_KEYS") // 主密钥，逗号分隔的 id:base64(32字节)，第一个用于加密新内容
+
+	LocalDownloadDir   = os.Getenv("LOCAL_DOWNLOAD_DIR")              // 离线文件和下载缓存的本地目录
+	LocalCacheMaxBytes = getEnvInt64("LOCAL_CACHE_MAX_BYTES", 10<<30) // 本地目录的空间预算(B)，超出时按 LRU 淘汰下载缓存，0为不缓存下载
+
+	CompressionCodec   = strings.ToLower(os.Getenv("COMPRESSION_CODEC")) // 新内容上传前的压缩方式：zstd、gzip，为空时不压缩
+	CompressionTypes   = getEnvList("COMPRESSION_TYPES")                 // 压缩的文件类型，逗号分隔，为空时为 document,code,txt,drawio,mind
+	CompressionMinSize = getEnvInt64("COMPRESSION_MIN_SIZE", 1024)       // 参与压缩的最小文件大小(B)
+
+	OssSSE      = os.Getenv("OSS_SSE")        // 阿里云OSS的服务端加密：aes256、kms、sm4，为空时按存储桶的默认加密
+	OssSSEKeyID = os.Getenv("OSS_SSE_KEY_ID") // OSS_SSE 为 kms 时使用的 KMS 密钥ID
+	CosSSE      = os.Getenv("COS_SSE")        // 腾讯云COS的服务端加密：aes256、kms，为空时按存储桶的默认加密
+	CosSSEKeyID = os.Getenv("COS_SSE_KEY_ID") // COS_SSE 为 kms 时使用的 KMS 密钥ID
+
+	JobWorkers     = getEnvInt64("JOB_WORKERS", 2)      // 后台任务（离线保存、上传、全文提取、迁移）的并发数
+	JobMaxAttempts = getEnvInt64("JOB_MAX_ATTEMPTS", 5) // 后台任务的最大执行次数，超过后标记为 dead
 )
+
+// getEnv 读取字符串类型的环境变量，未设置时返回默认值
+func getEnv(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// getEnvInt64 读取整数类型的环境变量，未设置或格式错误时返回默认值
+func getEnvInt64(key string, def int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getEnvBool 读取布尔类型的环境变量，未设置或格式错误时返回 false
+func getEnvBool(key string) bool {
+	value, _ := strconv.ParseBool(os.Getenv(key))
+	return value
+}
+
+// getEnvList 读取逗号分隔的环境变量，统一转为小写
+func getEnvList(key string) []string {
+	items := []string{}
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// getEnvInt64List 读取逗号分隔的整数环境变量，未设置或格式错误时返回默认值
+func getEnvInt64List(key string, def []int64) []int64 {
+	items := getEnvList(key)
+	if len(items) == 0 {
+		return def
+	}
+	values := make([]int64, 0, len(items))
+	for _, item := range items {
+		n, err := strconv.ParseInt(item, 10, 64)
+		if err != nil || n <= 0 {
+			return def
+		}
+		values = append(values, n)
+	}
+	return values
+}
+
+// getEnvInt64Map 读取指定前缀的整数环境变量，以去掉前缀后的小写名称为键
+func getEnvInt64Map(prefix string) map[string]int64 {
+	values := map[string]int64{}
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			values[strings.ToLower(strings.TrimPrefix(key, prefix))] = n
+		}
+	}
+	return values
+}
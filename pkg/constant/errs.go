@@ -23,3 +23,15 @@ var (
 var (
 	ErrCmdTimeout = "ErrCmdTimeout" //命令执行超时
 )
+
+var (
+	// 存储
+	ErrQuotaExceeded = "ErrQuotaExceeded" //存储空间不足：已用 {{.used}}，上限 {{.limit}}
+
+	// 上传策略
+	ErrPolicyFolderItems = "ErrPolicyFolderItems" //每个文件夹里最多只能创建{{.limit}}个文件或文件夹
+	ErrPolicyFolderDepth = "ErrPolicyFolderDepth" //文件夹层级不能超过{{.limit}}层
+	ErrPolicyFileSize    = "ErrPolicyFileSize"    //文件大小不能超过{{.limit}}
+	ErrPolicyFileExt     = "ErrPolicyFileExt"     //不允许上传 {{.ext}} 类型的文件
+	ErrPolicyFileMime    = "ErrPolicyFileMime"    //文件内容（{{.mime}}）与扩展名 {{.ext}} 不符
+)